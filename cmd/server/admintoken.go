@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"hermit/internal/config"
+	"hermit/internal/service"
+)
+
+const adminTokenUsage = "usage: hermit-server admin-token rotate"
+
+// runAdminTokenCommand implements the admin-token subcommand. rotate prints
+// a new admin token and revokes the previous one, which also recovers
+// access when the admin token is lost.
+func runAdminTokenCommand(ctx context.Context, svc *service.Service, args []string) error {
+	if len(args) != 1 || args[0] != "rotate" {
+		return fmt.Errorf("%s", adminTokenUsage)
+	}
+	rawToken, _, err := svc.RotateAdminToken(ctx, "cli")
+	if err != nil {
+		return err
+	}
+	log.Printf("[auth] admin token rotated from the command line")
+	fmt.Println(rawToken)
+	return nil
+}
+
+// setupAdminToken returns the token the authenticator accepts as the static
+// admin credential. In bootstrap mode ADMIN_TOKEN is stored hashed as a
+// one-time token instead, and ignored once it has been exchanged or has
+// expired.
+func setupAdminToken(ctx context.Context, svc *service.Service, cfg config.Config) string {
+	if cfg.AdminTokenMode != config.AdminTokenBootstrap {
+		if cfg.AdminToken == config.DefaultAdminToken {
+			log.Printf("warning: ADMIN_TOKEN is the development default; set ADMIN_TOKEN_MODE=bootstrap or change it")
+		}
+		return cfg.AdminToken
+	}
+	expiresAt, err := svc.BootstrapAdminToken(ctx, cfg.AdminToken, cfg.AdminBootstrapTTL)
+	if err != nil {
+		log.Fatalf("bootstrap admin token: %v", err)
+	}
+	if expiresAt != nil {
+		log.Printf("[auth] bootstrap admin token active until %s; exchange it with POST /api/internal/admin-token/rotate", expiresAt.Format(time.RFC3339))
+	} else if cfg.AdminToken != "" {
+		log.Printf("[auth] bootstrap admin token already exchanged or expired; ADMIN_TOKEN is ignored (use admin-token rotate)")
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"hermit/internal/service"
+)
+
+// runBlobGCJob deletes unreferenced blobs every interval until ctx is
+// cancelled. Unlike the trending job it does not run at startup.
+func runBlobGCJob(ctx context.Context, svc *service.Service, interval, grace time.Duration) {
+	log.Printf("[blob-gc] job started (interval=%s, grace=%s)", interval, grace)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := svc.CollectBlobGarbage(ctx, service.BlobGCOptions{GracePeriod: grace})
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[blob-gc] run failed: %v", err)
+			}
+			continue
+		}
+		if report.Deleted > 0 || len(report.Errors) > 0 {
+			log.Printf("[blob-gc] deleted %d of %d blobs (%d bytes), %d errors",
+				report.Deleted, report.Scanned, report.FreedBytes, len(report.Errors))
+		}
+	}
+}
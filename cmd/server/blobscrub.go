@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"hermit/internal/service"
+)
+
+// runBlobScrubJob verifies every stored archive every interval until ctx is
+// cancelled, logging each damaged blob.
+func runBlobScrubJob(ctx context.Context, svc *service.Service, interval time.Duration, refetch bool) {
+	log.Printf("[blob-scrub] job started (interval=%s, refetch=%t)", interval, refetch)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := svc.ScrubBlobs(ctx, service.BlobScrubOptions{Refetch: refetch})
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[blob-scrub] run failed: %v", err)
+			}
+			continue
+		}
+		for _, p := range report.Problems {
+			log.Printf("[blob-scrub] %s %s@%s (%s): %s: %s repaired=%t %s",
+				p.Repository, p.Slug, p.Version, p.BlobPath, p.Problem, p.Detail, p.Repaired, p.RepairError)
+		}
+		log.Printf("[blob-scrub] checked %d blobs: %d ok, %d missing, %d corrupt, %d unreadable, %d repaired",
+			report.Scanned, report.OK, report.Missing, report.Corrupt, report.Unreadable, report.Repaired)
+	}
+}
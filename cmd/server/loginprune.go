@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"hermit/internal/service"
+)
+
+// loginPruneInterval is how often old login attempts are deleted.
+const loginPruneInterval = time.Hour
+
+// runLoginPruneJob deletes login attempts older than retention every hour
+// until ctx is cancelled.
+func runLoginPruneJob(ctx context.Context, svc *service.Service, retention time.Duration) {
+	log.Printf("[auth] login history pruning started (retention=%s)", retention)
+	ticker := time.NewTicker(loginPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := svc.PruneLoginHistory(ctx, retention)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[auth] prune login history failed: %v", err)
+			}
+			continue
+		}
+		if n > 0 {
+			log.Printf("[auth] pruned %d login attempts", n)
+		}
+	}
+}
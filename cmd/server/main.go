@@ -45,6 +45,18 @@ func main() {
 	}
 	defer pool.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(ctx, pool, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	if cfg.AutoMigrate {
+		if err := migrateUp(ctx, pool); err != nil {
+			log.Fatalf("migrate database: %v", err)
+		}
+	}
+
 	blobStore, err := initBlobStorage(ctx, cfg)
 	if err != nil {
 		log.Fatalf("init storage: %v", err)
@@ -62,7 +74,23 @@ func main() {
 			ProxyRepo:      cfg.DefaultProxyRepo,
 			ProxyUpstreams: cfg.ProxyUpstreamURLs,
 		},
+		service.SessionPolicy{
+			IdleTimeout: cfg.SessionIdleTimeout,
+			MaxAge:      cfg.SessionMaxAge,
+		},
+		service.LoginPolicy{
+			MaxFailures:     cfg.LoginMaxFailures,
+			MaxIPFailures:   cfg.LoginMaxIPFailures,
+			FailureWindow:   cfg.LoginFailureWindow,
+			LockoutDuration: cfg.LoginLockout,
+		},
 	)
+	if len(os.Args) > 1 && os.Args[1] == "admin-token" {
+		if err := runAdminTokenCommand(ctx, svc, os.Args[2:]); err != nil {
+			log.Fatalf("admin-token: %v", err)
+		}
+		return
+	}
 	if cfg.BootstrapDefaults {
 		if err := svc.BootstrapDefaults(ctx, cfg.AdminUsername, cfg.AdminPassword); err != nil {
 			log.Fatalf("bootstrap defaults: %v", err)
@@ -83,10 +111,49 @@ func main() {
 		},
 		proxysync.NewClawHubBuilder(),
 	)
+	if n, err := svc.BackfillVersionSortKeys(ctx); err != nil {
+		log.Printf("warning: backfill version sort keys: %v", err)
+	} else if n > 0 {
+		log.Printf("computed semver sort keys for %d versions", n)
+	}
+	if n, err := svc.BackfillSkillReadmes(ctx); err != nil {
+		log.Printf("warning: backfill search index: %v", err)
+	} else if n > 0 {
+		log.Printf("indexed SKILL.md of %d skills for search", n)
+	}
+	if n, err := svc.InterruptStaleSyncRuns(ctx); err != nil {
+		log.Printf("warning: mark interrupted sync runs: %v", err)
+	} else if n > 0 {
+		log.Printf("marked %d unfinished sync runs as interrupted", n)
+	}
+
 	runner := proxysync.NewRunner(svc, factory, log.Default())
 	syncTrigger := httpapi.NewSyncTrigger(runner, svc, cfg.ProxySyncPageSize, log.Default())
+	if cfg.SyncSchedulerEnabled {
+		scheduler := proxysync.NewScheduler(svc, syncTrigger, log.Default())
+		go scheduler.Run(ctx)
+	}
 
-	authn := auth.NewAuthenticator(pool, cfg.AdminToken)
+	if cfg.TrendingInterval > 0 {
+		go runTrendingJob(ctx, svc, cfg.TrendingInterval, service.TrendingConfig{
+			HalfLife: cfg.TrendingHalfLife,
+			Window:   cfg.TrendingWindow,
+		})
+	}
+
+	if cfg.BlobGCInterval > 0 {
+		go runBlobGCJob(ctx, svc, cfg.BlobGCInterval, cfg.BlobGCGrace)
+	}
+	if cfg.BlobScrubInterval > 0 {
+		go runBlobScrubJob(ctx, svc, cfg.BlobScrubInterval, cfg.BlobScrubRefetch)
+	}
+
+	if cfg.LoginRetention > 0 {
+		go runLoginPruneJob(ctx, svc, cfg.LoginRetention)
+	}
+
+	staticAdminToken := setupAdminToken(ctx, svc, cfg)
+	authn := auth.NewAuthenticator(pool, staticAdminToken, cfg.SessionIdleTimeout)
 
 	api := httpapi.New(cfg, svc, authn, syncTrigger, cfg.WebDir)
 	echoServer := api.NewEcho()
@@ -128,6 +195,11 @@ func seedAuthConfigs(ctx context.Context, svc *service.Service, cfg config.Confi
 			StartTLS:     cfg.LDAPStartTLS,
 			SkipVerify:   cfg.LDAPSkipVerify,
 			AdminGroups:  cfg.LDAPAdminGroups,
+
+			GroupAttr:     cfg.LDAPGroupAttr,
+			GroupBaseDN:   cfg.LDAPGroupBaseDN,
+			GroupFilter:   cfg.LDAPGroupFilter,
+			GroupNameAttr: cfg.LDAPGroupName,
 		}
 		raw, _ := json.Marshal(ldapCfg)
 		if err := svc.SeedAuthConfigFromEnv(ctx, service.ProviderTypeLDAP, true, raw); err != nil {
@@ -137,6 +209,26 @@ func seedAuthConfigs(ctx context.Context, svc *service.Service, cfg config.Confi
 		}
 	}
 
+	if cfg.OIDCEnabled {
+		oidcCfg := extauth.OIDCConfig{
+			Issuer:              cfg.OIDCIssuer,
+			ClientID:            cfg.OIDCClientID,
+			ClientSecret:        cfg.OIDCClientSecret,
+			RedirectURL:         cfg.OIDCRedirectURL,
+			Scopes:              cfg.OIDCScopes,
+			SubjectClaim:        cfg.OIDCSubjectClaim,
+			AllowMutableSubject: cfg.OIDCAllowMutableSubject,
+			GroupsClaim:         cfg.OIDCGroupsClaim,
+			AdminGroups:         cfg.OIDCAdminGroups,
+		}
+		raw, _ := json.Marshal(oidcCfg)
+		if err := svc.SeedAuthConfigFromEnv(ctx, service.ProviderTypeOIDC, true, raw); err != nil {
+			log.Printf("warning: seed OIDC config: %v", err)
+		} else {
+			log.Printf("OIDC config seeded from env vars")
+		}
+	}
+
 }
 
 func seedProxySyncConfig(ctx context.Context, svc *service.Service, cfg config.Config) {
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"hermit/internal/db"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const migrateUsage = "usage: hermit-server migrate [up | down [steps] | status]"
+
+func migrateUp(ctx context.Context, pool *pgxpool.Pool) error {
+	migrator, err := db.NewMigrator(pool)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	if applied > 0 {
+		log.Printf("applied %d schema migrations", applied)
+	}
+	return nil
+}
+
+// runMigrateCommand implements the migrate subcommand.
+func runMigrateCommand(ctx context.Context, pool *pgxpool.Pool, args []string) error {
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	migrator, err := db.NewMigrator(pool)
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("applied %d schema migrations", applied)
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid steps %q\n%s", args[1], migrateUsage)
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("reverted %d schema migrations", reverted)
+		return nil
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = "applied " + st.AppliedAt.UTC().Format("2006-01-02 15:04:05Z")
+			}
+			fmt.Printf("%04d  %-32s %s\n", st.Version, st.Name, applied)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q\n%s", cmd, migrateUsage)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"hermit/internal/service"
+)
+
+// runTrendingJob recomputes trending scores once at startup and then every
+// interval until ctx is cancelled.
+func runTrendingJob(ctx context.Context, svc *service.Service, interval time.Duration, cfg service.TrendingConfig) {
+	log.Printf("[trending] job started (interval=%s)", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := svc.RecomputeTrendingScores(ctx, cfg); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[trending] recompute failed: %v", err)
+			}
+		} else if n > 0 {
+			log.Printf("[trending] updated %d skills", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -15,7 +16,21 @@ import (
 
 type Claims struct {
 	Subject string
+	// TokenID and TokenType identify the api_tokens row the request
+	// authenticated with; both are empty for the static admin token.
+	TokenID   string
+	TokenType string
+	// IsAdmin grants administrator access. A scoped token only has it when
+	// its subject is an administrator and it carries the admin scope.
 	IsAdmin bool
+	// SubjectIsAdmin reports whether the subject is an administrator,
+	// whatever the token's scopes.
+	SubjectIsAdmin bool
+	// Scopes restricts what the token may do; nil means unrestricted.
+	Scopes []string
+	// Repositories restricts publish access to the named repositories;
+	// empty means every repository the subject may push to.
+	Repositories []string
 }
 
 // Actor represents the identity performing a request.
@@ -38,15 +53,29 @@ func ActorFromClaims(c Claims) Actor {
 
 const claimsContextKey = "auth_claims"
 
+// Errors returned by Authenticate for credentials that are no longer valid.
+var (
+	ErrTokenExpired   = errors.New("token expired")
+	ErrSessionExpired = errors.New("session expired")
+)
+
+// TokenTypeSession marks tokens issued by a login.
+const TokenTypeSession = "session"
+
 type Authenticator struct {
-	db         *pgxpool.Pool
-	adminToken string
+	db                 *pgxpool.Pool
+	adminToken         string
+	sessionIdleTimeout time.Duration
 }
 
-func NewAuthenticator(db *pgxpool.Pool, adminToken string) *Authenticator {
+// NewAuthenticator returns an Authenticator. A non-empty adminToken is
+// accepted as the static admin credential. Sessions unused for longer than
+// sessionIdleTimeout are rejected; 0 disables the idle timeout.
+func NewAuthenticator(db *pgxpool.Pool, adminToken string, sessionIdleTimeout time.Duration) *Authenticator {
 	return &Authenticator{
-		db:         db,
-		adminToken: adminToken,
+		db:                 db,
+		adminToken:         adminToken,
+		sessionIdleTimeout: sessionIdleTimeout,
 	}
 }
 
@@ -60,6 +89,12 @@ func (a *Authenticator) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
 
 		claims, err := a.Authenticate(c.Request().Context(), token)
 		if err != nil {
+			switch {
+			case errors.Is(err, ErrSessionExpired):
+				return echo.NewHTTPError(http.StatusUnauthorized, "session expired")
+			case errors.Is(err, ErrTokenExpired):
+				return echo.NewHTTPError(http.StatusUnauthorized, "API token expired")
+			}
 			return echo.NewHTTPError(http.StatusUnauthorized, "invalid API token")
 		}
 		c.Set(claimsContextKey, claims)
@@ -85,8 +120,8 @@ func (a *Authenticator) OptionalMiddleware(next echo.HandlerFunc) echo.HandlerFu
 }
 
 func (a *Authenticator) Authenticate(ctx context.Context, token string) (Claims, error) {
-	if token == a.adminToken {
-		return Claims{Subject: "admin", IsAdmin: true}, nil
+	if a.adminToken != "" && token == a.adminToken {
+		return Claims{Subject: "admin", IsAdmin: true, SubjectIsAdmin: true}, nil
 	}
 
 	hash := sha256.Sum256([]byte(token))
@@ -94,13 +129,19 @@ func (a *Authenticator) Authenticate(ctx context.Context, token string) (Claims,
 
 	var tokenID string
 	var subject string
+	var tokenType string
 	var isAdmin bool
 	var disabled bool
+	var scopes []string
+	var repositories []string
+	var expiresAt *time.Time
+	var lastActivity time.Time
 	err := a.db.QueryRow(ctx, `
-		SELECT id, subject, is_admin, disabled
+		SELECT id, subject, token_type, is_admin, disabled, scopes, repositories, expires_at,
+		       COALESCE(last_used_at, created_at)
 		FROM api_tokens
 		WHERE token_hash = $1
-	`, tokenHash).Scan(&tokenID, &subject, &isAdmin, &disabled)
+	`, tokenHash).Scan(&tokenID, &subject, &tokenType, &isAdmin, &disabled, &scopes, &repositories, &expiresAt, &lastActivity)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return Claims{}, err
@@ -110,6 +151,14 @@ func (a *Authenticator) Authenticate(ctx context.Context, token string) (Claims,
 	if disabled {
 		return Claims{}, errors.New("token disabled")
 	}
+	now := time.Now()
+	if tokenType == TokenTypeSession {
+		if sessionExpired(expiresAt, lastActivity, a.sessionIdleTimeout, now) {
+			return Claims{}, ErrSessionExpired
+		}
+	} else if expiresAt != nil && !now.Before(*expiresAt) {
+		return Claims{}, ErrTokenExpired
+	}
 
 	// Async update last_used_at
 	go func() {
@@ -117,7 +166,25 @@ func (a *Authenticator) Authenticate(ctx context.Context, token string) (Claims,
 			`UPDATE api_tokens SET last_used_at = now() WHERE id = $1`, tokenID)
 	}()
 
-	return Claims{Subject: subject, IsAdmin: isAdmin}, nil
+	claims := Claims{
+		Subject:        subject,
+		TokenID:        tokenID,
+		TokenType:      tokenType,
+		SubjectIsAdmin: isAdmin,
+		Scopes:         scopes,
+		Repositories:   repositories,
+	}
+	claims.IsAdmin = isAdmin && claims.HasScope(ScopeAdmin)
+	return claims, nil
+}
+
+// sessionExpired reports whether a session has passed its absolute expiry
+// or has been idle for longer than idleTimeout (0 disables the idle check).
+func sessionExpired(expiresAt *time.Time, lastActivity time.Time, idleTimeout time.Duration, now time.Time) bool {
+	if expiresAt != nil && !now.Before(*expiresAt) {
+		return true
+	}
+	return idleTimeout > 0 && !now.Before(lastActivity.Add(idleTimeout))
 }
 
 func GetClaims(c echo.Context) (Claims, bool) {
@@ -3,6 +3,7 @@ package auth
 import (
 	"net/http"
 	"testing"
+	"time"
 )
 
 func TestExtractToken_BearerHeader(t *testing.T) {
@@ -103,3 +104,72 @@ func TestActorFromClaims(t *testing.T) {
 		})
 	}
 }
+
+func TestClaimsHasScope(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{"unrestricted admin", nil, ScopeAdmin, true},
+		{"read reads", []string{ScopeRead}, ScopeRead, true},
+		{"read cannot publish", []string{ScopeRead}, ScopePublish, false},
+		{"publish reads", []string{ScopePublish}, ScopeRead, true},
+		{"publish is not admin", []string{ScopePublish}, ScopeAdmin, false},
+		{"admin publishes", []string{ScopeAdmin}, ScopePublish, true},
+		{"empty scopes", []string{}, ScopeRead, false},
+		{"unknown scope", []string{ScopeAdmin}, "write", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := Claims{Subject: "u", Scopes: tt.scopes}
+			if got := c.HasScope(tt.scope); got != tt.want {
+				t.Fatalf("HasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimsAllowsRepository(t *testing.T) {
+	t.Parallel()
+	if !(Claims{}).AllowsRepository("hosted") {
+		t.Fatal("AllowsRepository() = false, want true without a restriction")
+	}
+	c := Claims{Scopes: []string{ScopePublish}, Repositories: []string{"team-a"}}
+	if !c.AllowsRepository("team-a") {
+		t.Fatal("AllowsRepository(team-a) = false, want true")
+	}
+	if c.AllowsRepository("team-b") {
+		t.Fatal("AllowsRepository(team-b) = true, want false")
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	tests := []struct {
+		name         string
+		expiresAt    *time.Time
+		lastActivity time.Time
+		idle         time.Duration
+		want         bool
+	}{
+		{"active", &future, now.Add(-time.Hour), 2 * time.Hour, false},
+		{"past absolute expiry", &past, now, 2 * time.Hour, true},
+		{"idle too long", &future, now.Add(-3 * time.Hour), 2 * time.Hour, true},
+		{"idle check disabled", nil, now.Add(-100 * time.Hour), 0, false},
+		{"no absolute expiry", nil, now.Add(-time.Hour), 2 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := sessionExpired(tt.expiresAt, tt.lastActivity, tt.idle, now); got != tt.want {
+				t.Fatalf("sessionExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package auth
+
+// Token scopes, from least to most powerful. Each scope includes the ones
+// before it: publish tokens can read, admin tokens can do anything.
+const (
+	ScopeRead    = "read"
+	ScopePublish = "publish"
+	ScopeAdmin   = "admin"
+)
+
+var scopeRank = map[string]int{
+	ScopeRead:    1,
+	ScopePublish: 2,
+	ScopeAdmin:   3,
+}
+
+// ValidScope reports whether scope is a known token scope.
+func ValidScope(scope string) bool {
+	_, ok := scopeRank[scope]
+	return ok
+}
+
+// Unrestricted reports whether the credential carries no scopes, as is the
+// case for login sessions and tokens created without scopes.
+func (c Claims) Unrestricted() bool {
+	return c.Scopes == nil
+}
+
+// HasScope reports whether the credential grants scope.
+func (c Claims) HasScope(scope string) bool {
+	if c.Unrestricted() {
+		return true
+	}
+	need, ok := scopeRank[scope]
+	if !ok {
+		return false
+	}
+	for _, s := range c.Scopes {
+		if scopeRank[s] >= need {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsRepository reports whether the credential may publish to the named
+// repository.
+func (c Claims) AllowsRepository(name string) bool {
+	if len(c.Repositories) == 0 {
+		return true
+	}
+	for _, r := range c.Repositories {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
@@ -71,3 +71,54 @@ func TestLoadProxySyncConfig(t *testing.T) {
 		t.Fatalf("ProxyUpstreamURLs len = %d, want 2", len(cfg.ProxyUpstreamURLs))
 	}
 }
+
+func TestLoadAdminTokenMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{
+			name: "static default token in development",
+			env:  map[string]string{"ADMIN_TOKEN": DefaultAdminToken},
+		},
+		{
+			name:    "static default token in production",
+			env:     map[string]string{"APP_ENV": "production", "ADMIN_TOKEN": DefaultAdminToken},
+			wantErr: true,
+		},
+		{
+			name:    "static unset token in production",
+			env:     map[string]string{"APP_ENV": "production", "ADMIN_TOKEN": ""},
+			wantErr: true,
+		},
+		{
+			name: "bootstrap without token",
+			env:  map[string]string{"ADMIN_TOKEN_MODE": "bootstrap", "ADMIN_TOKEN": ""},
+		},
+		{
+			name:    "bootstrap default token in production",
+			env:     map[string]string{"APP_ENV": "production", "ADMIN_TOKEN_MODE": "bootstrap", "ADMIN_TOKEN": DefaultAdminToken},
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			env:     map[string]string{"ADMIN_TOKEN_MODE": "rotating", "ADMIN_TOKEN": "token"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_ENV", "")
+			t.Setenv("ADMIN_TOKEN_MODE", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
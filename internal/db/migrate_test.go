@@ -0,0 +1,77 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Parallel()
+	fsys := fstest.MapFS{
+		"m/0002_add_stars.up.sql":   {Data: []byte("CREATE TABLE stars ();")},
+		"m/0002_add_stars.down.sql": {Data: []byte("DROP TABLE stars;")},
+		"m/0001_init.up.sql":        {Data: []byte("CREATE TABLE a ();")},
+		"m/0010_backfill.up.sql":    {Data: []byte("UPDATE a SET x = 1;")},
+		"m/README.md":               {Data: []byte("ignored")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("LoadMigrations() error = %v", err)
+	}
+	var got []string
+	for _, m := range migrations {
+		got = append(got, m.Name)
+	}
+	if strings.Join(got, ",") != "init,add_stars,backfill" {
+		t.Fatalf("LoadMigrations() order = %v", got)
+	}
+	if migrations[1].Down != "DROP TABLE stars;" || migrations[0].Down != "" {
+		t.Fatalf("LoadMigrations() down scripts = %q, %q", migrations[0].Down, migrations[1].Down)
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"missing direction", fstest.MapFS{"m/0001_init.sql": {Data: []byte("SELECT 1")}}},
+		{"missing name", fstest.MapFS{"m/0001.up.sql": {Data: []byte("SELECT 1")}}},
+		{"bad version", fstest.MapFS{"m/v1_init.up.sql": {Data: []byte("SELECT 1")}}},
+		{"down only", fstest.MapFS{"m/0001_init.down.sql": {Data: []byte("SELECT 1")}}},
+		{"name conflict", fstest.MapFS{
+			"m/0001_init.up.sql":  {Data: []byte("SELECT 1")},
+			"m/0001_other.up.sql": {Data: []byte("SELECT 1")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := LoadMigrations(tt.fsys, "m"); err == nil {
+				t.Fatalf("LoadMigrations() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	t.Parallel()
+	migrations, err := LoadMigrations(embeddedMigrations, "migrations")
+	if err != nil {
+		t.Fatalf("LoadMigrations() error = %v", err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		t.Fatalf("embedded migrations must start at version 1, got %+v", migrations)
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Fatalf("migration %s has version %d, want %d (no gaps)", m.Name, m.Version, i+1)
+		}
+		if strings.TrimSpace(m.Down) == "" {
+			t.Fatalf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+	}
+}
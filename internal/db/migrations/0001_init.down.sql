@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS api_tokens;
+DROP TABLE IF EXISTS auth_configs;
+DROP TABLE IF EXISTS system_configs;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS proxy_cache;
+DROP TABLE IF EXISTS assets;
+DROP TABLE IF EXISTS versions;
+DROP TABLE IF EXISTS packages;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS repo_members;
+DROP TABLE IF EXISTS repositories;
+
+DROP TYPE IF EXISTS proxy_cache_status;
+DROP TYPE IF EXISTS repo_role;
+DROP TYPE IF EXISTS repo_type;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS sync_runs (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  trigger TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'running',
+  repositories INTEGER NOT NULL DEFAULT 0,
+  skills INTEGER NOT NULL DEFAULT 0,
+  versions INTEGER NOT NULL DEFAULT 0,
+  cached INTEGER NOT NULL DEFAULT 0,
+  failed INTEGER NOT NULL DEFAULT 0,
+  skipped INTEGER NOT NULL DEFAULT 0,
+  by_repository JSONB NOT NULL DEFAULT '[]',
+  error TEXT NULL,
+  started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  finished_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_runs_started_at ON sync_runs (started_at DESC, id DESC);
+
+CREATE TABLE IF NOT EXISTS sync_run_items (
+  id BIGSERIAL PRIMARY KEY,
+  run_id UUID NOT NULL REFERENCES sync_runs(id) ON DELETE CASCADE,
+  repository TEXT NOT NULL,
+  slug TEXT NOT NULL,
+  outcome TEXT NOT NULL,
+  versions INTEGER NOT NULL DEFAULT 0,
+  cached INTEGER NOT NULL DEFAULT 0,
+  failed INTEGER NOT NULL DEFAULT 0,
+  error TEXT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_run_items_run ON sync_run_items (run_id, outcome, id);
@@ -0,0 +1 @@
+ALTER TABLE repo_members DROP COLUMN IF EXISTS source;
@@ -0,0 +1,4 @@
+-- Databases created by the old docker/init/001_init.sql predate this
+-- column; 0001 leaves their existing repo_members table alone, so the column
+-- is always added here rather than in CREATE TABLE.
+ALTER TABLE repo_members ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'manual';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_versions_package_sort;
+ALTER TABLE versions DROP COLUMN IF EXISTS prerelease;
+ALTER TABLE versions DROP COLUMN IF EXISTS sort_key;
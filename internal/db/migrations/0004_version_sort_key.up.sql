@@ -0,0 +1,8 @@
+-- sort_key orders versions by semver precedence under the "C" collation.
+-- It is '' for versions that are not semantic versions and NULL until the
+-- server has backfilled rows written before this migration.
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS sort_key TEXT NULL;
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS prerelease BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_versions_package_sort
+  ON versions (package_id, prerelease, sort_key COLLATE "C" DESC);
@@ -0,0 +1,2 @@
+ALTER TABLE packages DROP COLUMN IF EXISTS upstream_stars;
+DROP TABLE IF EXISTS skill_stars;
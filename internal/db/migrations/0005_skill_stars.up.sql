@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS skill_stars (
+  package_id UUID NOT NULL REFERENCES packages(id) ON DELETE CASCADE,
+  subject TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (package_id, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_skill_stars_subject ON skill_stars (subject, created_at DESC);
+
+-- Star counts reported by a proxy's upstream; packages.stars only counts
+-- local skill_stars rows.
+ALTER TABLE packages ADD COLUMN IF NOT EXISTS upstream_stars BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_packages_trending;
+DROP TABLE IF EXISTS skill_download_daily;
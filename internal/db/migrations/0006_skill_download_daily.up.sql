@@ -0,0 +1,11 @@
+-- Downloads per package and UTC day; packages.trending_score is a decayed
+-- sum over the recent buckets, recomputed periodically.
+CREATE TABLE IF NOT EXISTS skill_download_daily (
+  package_id UUID NOT NULL REFERENCES packages(id) ON DELETE CASCADE,
+  day DATE NOT NULL,
+  downloads BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (package_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_skill_download_daily_day ON skill_download_daily (day);
+CREATE INDEX IF NOT EXISTS idx_packages_trending ON packages (repo_id, trending_score DESC);
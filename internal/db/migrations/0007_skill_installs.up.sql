@@ -0,0 +1,16 @@
+-- One row per (package, client) that reported an install. Re-reports from
+-- the same client only refresh the row, so install counters are not
+-- inflated by retries or reinstalls.
+CREATE TABLE IF NOT EXISTS skill_installs (
+  package_id UUID NOT NULL REFERENCES packages(id) ON DELETE CASCADE,
+  client_id TEXT NOT NULL,
+  version TEXT,
+  installed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  uninstalled_at TIMESTAMPTZ,
+  PRIMARY KEY (package_id, client_id)
+);
+
+-- installs_current used to be bumped on every download and never went down;
+-- from now on it is the number of clients with an active install.
+UPDATE packages SET installs_current = 0 WHERE installs_current <> 0;
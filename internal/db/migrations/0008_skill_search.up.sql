@@ -0,0 +1,14 @@
+-- SKILL.md of the latest version, indexed for search. NULL means it has not
+-- been extracted yet (backfilled on startup); '' means there is none.
+ALTER TABLE packages ADD COLUMN IF NOT EXISTS readme TEXT;
+
+-- The 'simple' configuration keeps slugs and technical terms unstemmed, so
+-- prefix queries such as "pdf:*" behave predictably.
+ALTER TABLE packages ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+  setweight(to_tsvector('simple', coalesce(display_name, '')), 'A') ||
+  setweight(to_tsvector('simple', coalesce(summary, '')), 'B') ||
+  setweight(to_tsvector('simple', coalesce(readme, '')), 'D')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_packages_search ON packages USING GIN (search_vector);
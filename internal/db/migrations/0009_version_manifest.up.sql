@@ -0,0 +1,3 @@
+-- Parsed SKILL.md frontmatter of each version; NULL when the archive has no
+-- frontmatter or it predates manifest parsing.
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS manifest JSONB;
@@ -0,0 +1,4 @@
+ALTER TABLE packages DROP COLUMN IF EXISTS deprecated;
+ALTER TABLE versions DROP COLUMN IF EXISTS deprecated;
+ALTER TABLE versions DROP COLUMN IF EXISTS yank_reason;
+ALTER TABLE versions DROP COLUMN IF EXISTS yanked_at;
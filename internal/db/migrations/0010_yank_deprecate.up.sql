@@ -0,0 +1,8 @@
+-- A yanked version stays downloadable by exact version but is skipped by
+-- latest and range resolution.
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS yanked_at TIMESTAMPTZ;
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS yank_reason TEXT;
+
+-- Deprecation messages shown to clients; NULL when not deprecated.
+ALTER TABLE versions ADD COLUMN IF NOT EXISTS deprecated TEXT;
+ALTER TABLE packages ADD COLUMN IF NOT EXISTS deprecated TEXT;
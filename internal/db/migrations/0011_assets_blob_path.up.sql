@@ -0,0 +1,2 @@
+-- Blob garbage collection re-checks each candidate key before deleting it.
+CREATE INDEX IF NOT EXISTS idx_assets_blob_path ON assets (blob_path);
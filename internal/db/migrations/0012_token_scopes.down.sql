@@ -0,0 +1,3 @@
+ALTER TABLE api_tokens DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE api_tokens DROP COLUMN IF EXISTS repositories;
+ALTER TABLE api_tokens DROP COLUMN IF EXISTS scopes;
@@ -0,0 +1,5 @@
+-- NULL scopes keep a token unrestricted (sessions and tokens created before
+-- scopes existed). An empty repositories list allows every repository.
+ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS scopes TEXT[] NULL;
+ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS repositories TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NULL;
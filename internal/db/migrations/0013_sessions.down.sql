@@ -0,0 +1,2 @@
+ALTER TABLE api_tokens DROP COLUMN IF EXISTS client_ip;
+ALTER TABLE api_tokens DROP COLUMN IF EXISTS user_agent;
@@ -0,0 +1,4 @@
+-- Login sessions record the client they were issued to, so users can tell
+-- their devices apart when listing and revoking sessions.
+ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS client_ip TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,27 @@
+-- Failed login counters, one row per username and one per client IP.
+-- blocked_until holds both the progressive delay and the lockout.
+CREATE TABLE IF NOT EXISTS login_throttles (
+  kind TEXT NOT NULL,
+  key TEXT NOT NULL,
+  failures INT NOT NULL DEFAULT 0,
+  last_failed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  blocked_until TIMESTAMPTZ NULL,
+  locked BOOLEAN NOT NULL DEFAULT FALSE,
+  PRIMARY KEY (kind, key)
+);
+
+-- Every local and LDAP login attempt, for admins to review.
+CREATE TABLE IF NOT EXISTS login_attempts (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  provider TEXT NOT NULL,
+  username TEXT NOT NULL,
+  client_ip TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  outcome TEXT NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_created ON login_attempts (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_username ON login_attempts (username, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_client_ip ON login_attempts (client_ip, created_at DESC);
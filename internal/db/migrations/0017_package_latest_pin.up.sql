@@ -0,0 +1,4 @@
+-- latest_pinned marks a latest tag moved by hand. Publishes leave a pinned
+-- tag alone; it is released when its version is yanked or purged, or when
+-- the tag is deleted.
+ALTER TABLE packages ADD COLUMN IF NOT EXISTS latest_pinned BOOLEAN NOT NULL DEFAULT FALSE;
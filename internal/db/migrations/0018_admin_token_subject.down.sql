@@ -0,0 +1 @@
+UPDATE api_tokens SET subject = 'admin' WHERE token_type IN ('bootstrap', 'admin');
@@ -0,0 +1,3 @@
+-- Admin and bootstrap tokens used to share the subject of the local admin
+-- user, which let that user see and revoke them.
+UPDATE api_tokens SET subject = 'system:admin' WHERE token_type IN ('bootstrap', 'admin');
@@ -0,0 +1,13 @@
+-- OIDC logins sent to the identity provider and waiting for its callback,
+-- keyed by the hash of the OAuth state. Kept in the database so any replica
+-- can finish the login.
+CREATE TABLE IF NOT EXISTS oidc_logins (
+  state_hash TEXT PRIMARY KEY,
+  verifier TEXT NOT NULL,
+  nonce TEXT NOT NULL,
+  return_to TEXT NOT NULL DEFAULT '',
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_logins_expires_at ON oidc_logins (expires_at);
@@ -0,0 +1,5 @@
+-- Running syncs refresh heartbeat_at, so a starting replica only marks runs
+-- as interrupted when their replica has stopped updating them.
+ALTER TABLE sync_runs ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+UPDATE sync_runs SET heartbeat_at = COALESCE(finished_at, started_at);
@@ -2,12 +2,18 @@ package extauth
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/go-ldap/ldap/v3"
 )
 
+// ErrInvalidCredentials is returned when the user does not exist or the
+// password is wrong. The two are not told apart, so logins cannot be used
+// to probe for account names.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
 // LDAPConfig holds LDAP connection and search parameters.
 type LDAPConfig struct {
 	URL          string   `json:"url"`
@@ -20,6 +26,29 @@ type LDAPConfig struct {
 	StartTLS     bool     `json:"start_tls"`
 	SkipVerify   bool     `json:"skip_verify"`
 	AdminGroups  []string `json:"admin_groups"`
+
+	// Group lookup. When GroupBaseDN is empty, groups are read from the
+	// GroupAttr attribute (memberOf by default) of the user entry; otherwise
+	// GroupFilter is searched under GroupBaseDN and GroupNameAttr of each
+	// match is used. GroupFilter may reference {{.UserDN}} and {{.Username}}.
+	GroupAttr     string `json:"group_attr"`
+	GroupBaseDN   string `json:"group_base_dn"`
+	GroupFilter   string `json:"group_filter"`
+	GroupNameAttr string `json:"group_name_attr"`
+
+	// GroupRoles maps directory groups to per-repository roles.
+	GroupRoles []GroupRole `json:"group_roles"`
+}
+
+const (
+	defaultLDAPGroupAttr     = "memberOf"
+	defaultLDAPGroupFilter   = "(|(member={{.UserDN}})(uniqueMember={{.UserDN}})(memberUid={{.Username}}))"
+	defaultLDAPGroupNameAttr = "cn"
+)
+
+// needsGroups reports whether the config uses group membership at all.
+func (c LDAPConfig) needsGroups() bool {
+	return len(c.AdminGroups) > 0 || len(c.GroupRoles) > 0
 }
 
 // LDAPAuthenticator performs bind-based LDAP authentication.
@@ -50,14 +79,22 @@ func (la *LDAPAuthenticator) Authenticate(username, password string) (*IdentityR
 		}
 	}
 
-	userDN, attrs, err := la.searchUser(conn, username)
+	userDN, attrs, memberOf, err := la.searchUser(conn, username)
 	if err != nil {
 		return nil, err
 	}
 
 	// Bind as the user to verify their password
 	if err := conn.Bind(userDN, password); err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, ErrInvalidCredentials
+	}
+
+	var groups []string
+	if la.cfg.needsGroups() {
+		groups, err = la.lookupGroups(conn, userDN, username, memberOf)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	subject := username
@@ -71,12 +108,126 @@ func (la *LDAPAuthenticator) Authenticate(username, password string) (*IdentityR
 	email, _ := attrs["mail"]
 
 	return &IdentityResult{
-		Subject:     subject,
-		DisplayName: displayName,
-		Email:       email,
+		Subject:      subject,
+		DisplayName:  displayName,
+		Email:        email,
+		IsAdmin:      matchesAnyGroup(la.cfg.AdminGroups, groups),
+		Groups:       groups,
+		ManagesRoles: len(la.cfg.GroupRoles) > 0,
+		RoleGrants:   resolveGroupRoles(la.cfg.GroupRoles, groups),
 	}, nil
 }
 
+// lookupGroups returns the groups the user belongs to, either from the
+// user's own group attribute or from a group search.
+func (la *LDAPAuthenticator) lookupGroups(conn *ldap.Conn, userDN, username string, memberOf []string) ([]string, error) {
+	if la.cfg.GroupBaseDN == "" {
+		return memberOf, nil
+	}
+
+	// The user bind may not be allowed to search groups, so switch back to
+	// the service account when one is configured.
+	if la.cfg.BindDN != "" {
+		if err := conn.Bind(la.cfg.BindDN, la.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap service bind: %w", err)
+		}
+	}
+
+	filter := la.cfg.GroupFilter
+	if filter == "" {
+		filter = defaultLDAPGroupFilter
+	}
+	filter = strings.NewReplacer(
+		"{{.UserDN}}", ldap.EscapeFilter(userDN),
+		"{{.Username}}", ldap.EscapeFilter(username),
+	).Replace(filter)
+
+	nameAttr := la.cfg.GroupNameAttr
+	if nameAttr == "" {
+		nameAttr = defaultLDAPGroupNameAttr
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		la.cfg.GroupBaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,  // size limit
+		10, // time limit seconds
+		false,
+		filter,
+		[]string{nameAttr},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("ldap group search: %w", err)
+	}
+
+	groups := make([]string, 0, len(result.Entries)*2)
+	for _, entry := range result.Entries {
+		groups = append(groups, entry.DN)
+		if name := entry.GetAttributeValue(nameAttr); name != "" {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
+
+func (la *LDAPAuthenticator) groupAttr() string {
+	if la.cfg.GroupAttr != "" {
+		return la.cfg.GroupAttr
+	}
+	return defaultLDAPGroupAttr
+}
+
+// groupMatches reports whether a configured group name refers to the given
+// group, which may be a full DN or a plain name.
+func groupMatches(want, group string) bool {
+	want = strings.TrimSpace(want)
+	group = strings.TrimSpace(group)
+	if want == "" || group == "" {
+		return false
+	}
+	if strings.EqualFold(want, group) {
+		return true
+	}
+	if dn, err := ldap.ParseDN(group); err == nil && len(dn.RDNs) > 0 && len(dn.RDNs[0].Attributes) > 0 {
+		if strings.EqualFold(want, dn.RDNs[0].Attributes[0].Value) {
+			return true
+		}
+		if wantDN, err := ldap.ParseDN(want); err == nil && len(wantDN.RDNs) > 0 && wantDN.EqualFold(dn) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyGroup(wanted []string, groups []string) bool {
+	for _, want := range wanted {
+		for _, group := range groups {
+			if groupMatches(want, group) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func resolveGroupRoles(mappings []GroupRole, groups []string) []RoleGrant {
+	var grants []RoleGrant
+	for _, m := range mappings {
+		if strings.TrimSpace(m.Repository) == "" || strings.TrimSpace(m.Role) == "" {
+			continue
+		}
+		if matchesAnyGroup([]string{m.Group}, groups) {
+			grants = append(grants, RoleGrant{
+				Repository: strings.TrimSpace(m.Repository),
+				Role:       strings.TrimSpace(m.Role),
+			})
+		}
+	}
+	return grants
+}
+
 func (la *LDAPAuthenticator) connect() (*ldap.Conn, error) {
 	tlsCfg := &tls.Config{InsecureSkipVerify: la.cfg.SkipVerify}
 
@@ -98,10 +249,17 @@ func (la *LDAPAuthenticator) connect() (*ldap.Conn, error) {
 	return conn, nil
 }
 
-func (la *LDAPAuthenticator) searchUser(conn *ldap.Conn, username string) (string, map[string]string, error) {
+// searchUser finds the user entry and returns its DN, its single-valued
+// attributes and, when groups are read from the entry, its group attribute.
+func (la *LDAPAuthenticator) searchUser(conn *ldap.Conn, username string) (string, map[string]string, []string, error) {
 	filter := strings.ReplaceAll(la.cfg.UserFilter, "{{.Username}}", ldap.EscapeFilter(username))
 
 	searchAttrs := []string{"dn", la.cfg.UserAttr, la.cfg.DisplayAttr, "mail"}
+	requestAttrs := append([]string(nil), searchAttrs...)
+	readGroups := la.cfg.needsGroups() && la.cfg.GroupBaseDN == ""
+	if readGroups {
+		requestAttrs = append(requestAttrs, la.groupAttr())
+	}
 
 	result, err := conn.Search(ldap.NewSearchRequest(
 		la.cfg.BaseDN,
@@ -111,14 +269,14 @@ func (la *LDAPAuthenticator) searchUser(conn *ldap.Conn, username string) (strin
 		10,   // time limit seconds
 		false,
 		filter,
-		searchAttrs,
+		requestAttrs,
 		nil,
 	))
 	if err != nil {
-		return "", nil, fmt.Errorf("ldap search: %w", err)
+		return "", nil, nil, fmt.Errorf("ldap search: %w", err)
 	}
 	if len(result.Entries) == 0 {
-		return "", nil, fmt.Errorf("user not found")
+		return "", nil, nil, ErrInvalidCredentials
 	}
 
 	entry := result.Entries[0]
@@ -128,5 +286,9 @@ func (la *LDAPAuthenticator) searchUser(conn *ldap.Conn, username string) (strin
 			attrs[a] = entry.GetAttributeValue(a)
 		}
 	}
-	return entry.DN, attrs, nil
+	var memberOf []string
+	if readGroups {
+		memberOf = entry.GetAttributeValues(la.groupAttr())
+	}
+	return entry.DN, attrs, memberOf, nil
 }
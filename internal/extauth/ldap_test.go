@@ -0,0 +1,73 @@
+package extauth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupMatches(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		want  string
+		group string
+		match bool
+	}{
+		{"same dn", "cn=admins,ou=groups,dc=example,dc=com", "cn=admins,ou=groups,dc=example,dc=com", true},
+		{"dn case and spacing", "CN=Admins, OU=Groups, DC=example, DC=com", "cn=admins,ou=groups,dc=example,dc=com", true},
+		{"name against dn", "admins", "cn=admins,ou=groups,dc=example,dc=com", true},
+		{"name case insensitive", "Admins", "admins", true},
+		{"other group", "admins", "cn=developers,ou=groups,dc=example,dc=com", false},
+		{"other dn", "cn=admins,ou=other,dc=example,dc=com", "cn=admins,ou=groups,dc=example,dc=com", false},
+		{"empty want", "", "admins", false},
+		{"empty group", "admins", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := groupMatches(tt.want, tt.group); got != tt.match {
+				t.Fatalf("groupMatches(%q, %q) = %v, want %v", tt.want, tt.group, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestMatchesAnyGroup(t *testing.T) {
+	t.Parallel()
+	groups := []string{
+		"cn=developers,ou=groups,dc=example,dc=com",
+		"cn=registry-admins,ou=groups,dc=example,dc=com",
+	}
+	if !matchesAnyGroup([]string{"ops", "registry-admins"}, groups) {
+		t.Fatalf("matchesAnyGroup() = false, want true")
+	}
+	if matchesAnyGroup([]string{"ops"}, groups) {
+		t.Fatalf("matchesAnyGroup() = true, want false")
+	}
+	if matchesAnyGroup(nil, groups) {
+		t.Fatalf("matchesAnyGroup(nil) = true, want false")
+	}
+}
+
+func TestResolveGroupRoles(t *testing.T) {
+	t.Parallel()
+	mappings := []GroupRole{
+		{Group: "developers", Repository: "hosted", Role: "developer"},
+		{Group: "readers", Repository: "proxy", Role: "viewer"},
+		{Group: "cn=leads,ou=groups,dc=example,dc=com", Repository: " hosted ", Role: "admin"},
+		{Group: "developers", Repository: "", Role: "admin"},
+	}
+	groups := []string{
+		"cn=developers,ou=groups,dc=example,dc=com",
+		"cn=leads,ou=groups,dc=example,dc=com",
+	}
+
+	got := resolveGroupRoles(mappings, groups)
+	want := []RoleGrant{
+		{Repository: "hosted", Role: "developer"},
+		{Repository: "hosted", Role: "admin"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("resolveGroupRoles() = %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,321 @@
+package extauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCConfig holds OpenID Connect client settings.
+type OIDCConfig struct {
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+
+	// Claim mapping. SubjectClaim defaults to sub, the only claim the IdP
+	// guarantees to be stable and unique. Any other claim is used only when
+	// AllowMutableSubject is set. Either way the subject is qualified by the
+	// issuer (see OIDCSubject), so it never names a local or LDAP user.
+	SubjectClaim        string `json:"subject_claim"`
+	AllowMutableSubject bool   `json:"allow_mutable_subject"`
+	DisplayClaim        string `json:"display_claim"`
+	EmailClaim          string `json:"email_claim"`
+	GroupsClaim         string `json:"groups_claim"`
+
+	AdminGroups []string    `json:"admin_groups"`
+	GroupRoles  []GroupRole `json:"group_roles"`
+}
+
+const (
+	defaultOIDCSubjectClaim = "sub"
+	defaultOIDCDisplayClaim = "name"
+	defaultOIDCEmailClaim   = "email"
+	defaultOIDCGroupsClaim  = "groups"
+)
+
+var defaultOIDCScopes = []string{"openid", "profile", "email"}
+
+// OIDCAuthenticator performs the authorization code flow with PKCE against
+// an OpenID Connect provider and maps the ID token claims to an identity.
+// Discovery, the JWKS cache and ID token verification are done by go-oidc,
+// the code exchange by golang.org/x/oauth2.
+type OIDCAuthenticator struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func NewOIDCAuthenticator(cfg OIDCConfig, client *http.Client) *OIDCAuthenticator {
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &OIDCAuthenticator{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+// NewPKCEVerifier returns a random PKCE code verifier (RFC 7636).
+func NewPKCEVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+// NewOIDCNonce returns a random value usable as OAuth state or OIDC nonce.
+func NewOIDCNonce() (string, error) {
+	return randomURLString(24)
+}
+
+// AuthCodeURL builds the authorization endpoint URL the browser is sent to.
+func (a *OIDCAuthenticator) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	conf, _, err := a.oauth2Config(ctx)
+	if err != nil {
+		return "", err
+	}
+	return conf.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange redeems an authorization code, verifies the returned ID token
+// and maps its claims to an identity.
+func (a *OIDCAuthenticator) Exchange(ctx context.Context, code, verifier, nonce string) (*IdentityResult, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, fmt.Errorf("authorization code required")
+	}
+	conf, provider, err := a.oauth2Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = oidc.ClientContext(ctx, a.client)
+	tok, err := conf.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc token request: %w", err)
+	}
+	rawIDToken, _ := tok.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("oidc token response has no id_token")
+	}
+
+	claims, err := a.verifyIDToken(ctx, provider, rawIDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if provider.UserInfoEndpoint() != "" && a.needsUserinfo(claims) {
+		info, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(tok))
+		if err != nil {
+			return nil, fmt.Errorf("oidc userinfo: %w", err)
+		}
+		if info.Subject != claims["sub"] {
+			return nil, fmt.Errorf("oidc userinfo subject mismatch")
+		}
+		var extra map[string]any
+		if err := info.Claims(&extra); err != nil {
+			return nil, fmt.Errorf("oidc userinfo: %w", err)
+		}
+		for k, v := range extra {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+
+	return a.identityFromClaims(claims)
+}
+
+// discover returns the provider, fetching the discovery document on first
+// use. A failed discovery is retried on the next call.
+func (a *OIDCAuthenticator) discover(ctx context.Context) (*oidc.Provider, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.provider != nil {
+		return a.provider, nil
+	}
+
+	issuer := strings.TrimSpace(a.cfg.Issuer)
+	if issuer == "" {
+		return nil, fmt.Errorf("oidc issuer not configured")
+	}
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, a.client), issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	a.provider = provider
+	return provider, nil
+}
+
+func (a *OIDCAuthenticator) oauth2Config(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	if strings.TrimSpace(a.cfg.RedirectURL) == "" {
+		return nil, nil, fmt.Errorf("oidc redirect_url not configured")
+	}
+	provider, err := a.discover(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scopes := a.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultOIDCScopes
+	}
+	if !slices.Contains(scopes, oidc.ScopeOpenID) {
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+	}
+	return &oauth2.Config{
+		ClientID:     a.cfg.ClientID,
+		ClientSecret: a.cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  a.cfg.RedirectURL,
+		Scopes:       scopes,
+	}, provider, nil
+}
+
+// verifyIDToken checks the signature, issuer, audience and expiry of an ID
+// token, then the nonce and authorized party, and returns its claims.
+func (a *OIDCAuthenticator) verifyIDToken(ctx context.Context, provider *oidc.Provider, raw, nonce string) (map[string]any, error) {
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: a.cfg.ClientID}).Verify(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("id_token: %w", err)
+	}
+	if nonce == "" || idToken.Nonce != nonce {
+		return nil, fmt.Errorf("id_token nonce mismatch")
+	}
+	if idToken.Subject == "" {
+		return nil, fmt.Errorf("id_token has no subject")
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("id_token claims: %w", err)
+	}
+	// go-oidc checks that the client is among the audiences; the authorized
+	// party, when present, must be the client itself.
+	if azp := claimString(claims, "azp"); azp != "" && azp != a.cfg.ClientID {
+		return nil, fmt.Errorf("id_token authorized party %q not accepted", azp)
+	}
+	return claims, nil
+}
+
+func (a *OIDCAuthenticator) identityFromClaims(claims map[string]any) (*IdentityResult, error) {
+	if err := CheckOIDCSubjectClaim(a.cfg); err != nil {
+		return nil, err
+	}
+	subjectClaim := orDefault(a.cfg.SubjectClaim, defaultOIDCSubjectClaim)
+	value := claimString(claims, subjectClaim)
+	if value == "" {
+		return nil, fmt.Errorf("oidc claim %q missing", subjectClaim)
+	}
+	issuer := claimString(claims, "iss")
+	if issuer == "" {
+		issuer = a.cfg.Issuer
+	}
+	subject := OIDCSubject(issuer, value)
+
+	displayName := claimString(claims, orDefault(a.cfg.DisplayClaim, defaultOIDCDisplayClaim))
+	if displayName == "" {
+		displayName = value
+	}
+	groups := claimStrings(claims, orDefault(a.cfg.GroupsClaim, defaultOIDCGroupsClaim))
+
+	return &IdentityResult{
+		Subject:      subject,
+		DisplayName:  displayName,
+		Email:        claimString(claims, orDefault(a.cfg.EmailClaim, defaultOIDCEmailClaim)),
+		IsAdmin:      matchesAnyGroup(a.cfg.AdminGroups, groups),
+		Groups:       groups,
+		ManagesRoles: len(a.cfg.GroupRoles) > 0,
+		RoleGrants:   resolveGroupRoles(a.cfg.GroupRoles, groups),
+	}, nil
+}
+
+// OIDCSubjectPrefix starts every OIDC subject. Local and LDAP usernames
+// must not use it.
+const OIDCSubjectPrefix = "oidc:"
+
+// OIDCSubject returns the hermit subject for a claim value issued by issuer,
+// e.g. "oidc:login.example.com/00u1". Qualifying by issuer keeps IdP users
+// apart from local and LDAP users of the same name and from the users of
+// another issuer.
+func OIDCSubject(issuer, value string) string {
+	issuer = strings.TrimRight(strings.TrimSpace(issuer), "/")
+	issuer = strings.TrimPrefix(strings.TrimPrefix(issuer, "https://"), "http://")
+	return OIDCSubjectPrefix + issuer + "/" + value
+}
+
+// CheckOIDCSubjectClaim rejects a subject claim other than sub unless the
+// config opts in with AllowMutableSubject. Claims such as
+// preferred_username or email can be changed by the user or reassigned by
+// the IdP, handing the subject's tokens, stars and roles to someone else.
+func CheckOIDCSubjectClaim(cfg OIDCConfig) error {
+	claim := strings.TrimSpace(cfg.SubjectClaim)
+	if claim == "" || claim == defaultOIDCSubjectClaim || cfg.AllowMutableSubject {
+		return nil
+	}
+	return fmt.Errorf("oidc subject_claim %q requires allow_mutable_subject", claim)
+}
+
+// needsUserinfo reports whether a mapped claim is missing from the ID token.
+func (a *OIDCAuthenticator) needsUserinfo(claims map[string]any) bool {
+	for _, name := range []string{
+		orDefault(a.cfg.SubjectClaim, defaultOIDCSubjectClaim),
+		orDefault(a.cfg.EmailClaim, defaultOIDCEmailClaim),
+		orDefault(a.cfg.GroupsClaim, defaultOIDCGroupsClaim),
+	} {
+		if _, ok := claims[name]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func claimString(claims map[string]any, name string) string {
+	v, _ := claims[name].(string)
+	return strings.TrimSpace(v)
+}
+
+// claimStrings reads a claim that may be a single string or a list.
+func claimStrings(claims map[string]any, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func orDefault(v, fallback string) string {
+	if strings.TrimSpace(v) == "" {
+		return fallback
+	}
+	return v
+}
+
+func randomURLString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,388 @@
+package extauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// stubIdP is a minimal OpenID provider: discovery, JWKS, authorization
+// (recorded, not served) and a token endpoint that enforces PKCE.
+type stubIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu        sync.Mutex
+	challenge string
+	nonce     string
+	claims    map[string]any
+	userinfo  map[string]any
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	idp := &stubIdP{t: t, key: key, kid: "test-key"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"userinfo_endpoint":      idp.server.URL + "/userinfo",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]any{{
+				"kty": "RSA",
+				"kid": idp.kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "hermit" || pass != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_client"})
+			return
+		}
+		idp.mu.Lock()
+		challenge, claims := idp.challenge, idp.claims
+		idp.mu.Unlock()
+		if r.Form.Get("code") != "good-code" || oauth2.S256ChallengeFromVerifier(r.Form.Get("code_verifier")) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_grant"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access-123",
+			"token_type":   "Bearer",
+			"id_token":     idp.sign(claims),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		idp.mu.Lock()
+		info := idp.userinfo
+		idp.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(info)
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *stubIdP) sign(claims map[string]any) string {
+	return signTestJWT(idp.t, idp.key, idp.kid, claims)
+}
+
+func signTestJWT(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]any{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatalf("SignPKCS1v15() error = %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// authorize mimics the browser leg: it records the PKCE challenge and
+// nonce from the authorization URL and sets the claims of the ID token.
+func (idp *stubIdP) authorize(t *testing.T, authURL string, claims map[string]any) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse auth URL: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("response_type") != "code" {
+		t.Fatalf("auth URL query = %v", q)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.challenge = q.Get("code_challenge")
+	idp.nonce = q.Get("nonce")
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = idp.nonce
+	}
+	idp.claims = claims
+}
+
+func (idp *stubIdP) baseClaims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":                idp.server.URL,
+		"aud":                "hermit",
+		"sub":                "00u1",
+		"preferred_username": "alice",
+		"name":               "Alice",
+		"email":              "alice@example.com",
+		"groups":             []string{"engineering", "registry-admins"},
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func newTestOIDC(idp *stubIdP) *OIDCAuthenticator {
+	return NewOIDCAuthenticator(OIDCConfig{
+		Issuer:       idp.server.URL,
+		ClientID:     "hermit",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://hermit.local/cb",
+		AdminGroups:  []string{"registry-admins"},
+		GroupRoles: []GroupRole{
+			{Group: "engineering", Repository: "hosted", Role: "developer"},
+			{Group: "sales", Repository: "hosted", Role: "viewer"},
+		},
+	}, idp.server.Client())
+}
+
+func TestOIDCAuthenticator_CodeFlow(t *testing.T) {
+	t.Parallel()
+	idp := newStubIdP(t)
+	auth := newTestOIDC(idp)
+	ctx := context.Background()
+
+	verifier := NewPKCEVerifier()
+	authURL, err := auth.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	if !strings.HasPrefix(authURL, idp.server.URL+"/authorize?") {
+		t.Fatalf("AuthCodeURL() = %q", authURL)
+	}
+	idp.authorize(t, authURL, idp.baseClaims())
+
+	identity, err := auth.Exchange(ctx, "good-code", verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	wantSubject := OIDCSubject(idp.server.URL, "00u1")
+	if identity.Subject != wantSubject || identity.DisplayName != "Alice" || identity.Email != "alice@example.com" {
+		t.Fatalf("identity = %#v", identity)
+	}
+	if !identity.IsAdmin {
+		t.Fatalf("identity.IsAdmin = false, want true")
+	}
+	if !identity.ManagesRoles || len(identity.RoleGrants) != 1 || identity.RoleGrants[0] != (RoleGrant{Repository: "hosted", Role: "developer"}) {
+		t.Fatalf("identity.RoleGrants = %#v", identity.RoleGrants)
+	}
+}
+
+func TestOIDCAuthenticator_UserinfoFillsMissingClaims(t *testing.T) {
+	t.Parallel()
+	idp := newStubIdP(t)
+	auth := newTestOIDC(idp)
+	ctx := context.Background()
+
+	claims := idp.baseClaims()
+	delete(claims, "groups")
+	delete(claims, "email")
+	idp.userinfo = map[string]any{"sub": "00u1", "email": "alice@corp.example", "groups": []string{"sales"}}
+
+	verifier := NewPKCEVerifier()
+	authURL, err := auth.AuthCodeURL(ctx, "state", "nonce", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	idp.authorize(t, authURL, claims)
+
+	identity, err := auth.Exchange(ctx, "good-code", verifier, "nonce")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if identity.Email != "alice@corp.example" || identity.IsAdmin {
+		t.Fatalf("identity = %#v", identity)
+	}
+	if len(identity.RoleGrants) != 1 || identity.RoleGrants[0].Role != "viewer" {
+		t.Fatalf("identity.RoleGrants = %#v", identity.RoleGrants)
+	}
+}
+
+func TestOIDCAuthenticator_RejectsInvalidTokens(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		mutate func(map[string]any)
+		nonce  string
+		code   string
+		verify func(string) string
+	}{
+		{name: "wrong audience", mutate: func(c map[string]any) { c["aud"] = "other" }},
+		{name: "other authorized party", mutate: func(c map[string]any) {
+			c["aud"] = []string{"hermit", "other"}
+			c["azp"] = "other"
+		}},
+		{name: "wrong issuer", mutate: func(c map[string]any) { c["iss"] = "https://evil.example" }},
+		{name: "expired", mutate: func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "nonce mismatch", mutate: func(c map[string]any) { c["nonce"] = "replayed" }},
+		{name: "bad code", code: "bad-code"},
+		{name: "wrong verifier", verify: func(v string) string { return v + "x" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			idp := newStubIdP(t)
+			auth := newTestOIDC(idp)
+			ctx := context.Background()
+
+			verifier := NewPKCEVerifier()
+			authURL, err := auth.AuthCodeURL(ctx, "state", "nonce", verifier)
+			if err != nil {
+				t.Fatalf("AuthCodeURL() error = %v", err)
+			}
+			claims := idp.baseClaims()
+			if tt.mutate != nil {
+				tt.mutate(claims)
+			}
+			idp.authorize(t, authURL, claims)
+
+			code := "good-code"
+			if tt.code != "" {
+				code = tt.code
+			}
+			if tt.verify != nil {
+				verifier = tt.verify(verifier)
+			}
+			if _, err := auth.Exchange(ctx, code, verifier, "nonce"); err == nil {
+				t.Fatalf("Exchange() error = nil, want rejection")
+			}
+		})
+	}
+}
+
+func TestOIDCAuthenticator_RejectsForgedSignature(t *testing.T) {
+	t.Parallel()
+	idp := newStubIdP(t)
+	auth := newTestOIDC(idp)
+	ctx := context.Background()
+
+	provider, err := auth.discover(ctx)
+	if err != nil {
+		t.Fatalf("discover() error = %v", err)
+	}
+	claims := idp.baseClaims()
+	claims["nonce"] = "n"
+	if _, err := auth.verifyIDToken(ctx, provider, idp.sign(claims), "n"); err != nil {
+		t.Fatalf("verifyIDToken(valid) error = %v", err)
+	}
+
+	forgedKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	payload, _ := json.Marshal(claims)
+	encode := func(header map[string]any, sig []byte) string {
+		h, _ := json.Marshal(header)
+		return base64.RawURLEncoding.EncodeToString(h) + "." +
+			base64.RawURLEncoding.EncodeToString(payload) + "." +
+			base64.RawURLEncoding.EncodeToString(sig)
+	}
+	// hs256 signs with the IdP's public key as the HMAC secret, the classic
+	// RSA/HMAC algorithm confusion.
+	hs256 := func() string {
+		h, _ := json.Marshal(map[string]any{"alg": "HS256", "kid": idp.kid})
+		signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		mac := hmac.New(sha256.New, x509.MarshalPKCS1PublicKey(&idp.key.PublicKey))
+		mac.Write([]byte(signed))
+		return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "foreign key", token: signTestJWT(t, forgedKey, idp.kid, claims)},
+		{name: "foreign key without kid", token: signTestJWT(t, forgedKey, "", claims)},
+		{name: "unknown kid", token: signTestJWT(t, forgedKey, "other-key", claims)},
+		{name: "alg none", token: encode(map[string]any{"alg": "none", "kid": idp.kid}, nil)},
+		{name: "hs256 with public key", token: hs256()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := auth.verifyIDToken(ctx, provider, tt.token, "n"); err == nil {
+				t.Fatalf("verifyIDToken() error = nil, want signature failure")
+			}
+		})
+	}
+}
+
+func TestOIDCAuthenticator_SubjectClaim(t *testing.T) {
+	t.Parallel()
+	claims := map[string]any{
+		"iss":                "https://login.example.com/",
+		"sub":                "00u1",
+		"preferred_username": "admin",
+	}
+	tests := []struct {
+		name    string
+		cfg     OIDCConfig
+		want    string
+		wantErr bool
+	}{
+		{name: "default sub", cfg: OIDCConfig{}, want: "oidc:login.example.com/00u1"},
+		{name: "explicit sub", cfg: OIDCConfig{SubjectClaim: "sub"}, want: "oidc:login.example.com/00u1"},
+		{name: "mutable claim without opt-in", cfg: OIDCConfig{SubjectClaim: "preferred_username"}, wantErr: true},
+		{name: "mutable claim with opt-in", cfg: OIDCConfig{SubjectClaim: "preferred_username", AllowMutableSubject: true}, want: "oidc:login.example.com/admin"},
+		{name: "missing claim", cfg: OIDCConfig{SubjectClaim: "upn", AllowMutableSubject: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			identity, err := NewOIDCAuthenticator(tt.cfg, nil).identityFromClaims(claims)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("identityFromClaims() = %#v, want error", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("identityFromClaims() error = %v", err)
+			}
+			if identity.Subject != tt.want {
+				t.Fatalf("identityFromClaims().Subject = %q, want %q", identity.Subject, tt.want)
+			}
+		})
+	}
+}
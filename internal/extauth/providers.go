@@ -4,7 +4,7 @@ package extauth
 type Provider struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
-	Type string `json:"type"` // "token", "ldap", "standard"
+	Type string `json:"type"` // "token", "ldap", "oidc", "standard"
 }
 
 // IdentityResult is returned after successful external authentication.
@@ -13,4 +13,26 @@ type IdentityResult struct {
 	DisplayName string
 	Email       string
 	IsAdmin     bool
+	Groups      []string
+	// ManagesRoles reports whether the provider maps groups to repository
+	// roles. When true, RoleGrants replaces every role the provider granted
+	// to the subject on a previous login.
+	ManagesRoles bool
+	RoleGrants   []RoleGrant
+}
+
+// GroupRole grants Role on Repository to members of Group. For LDAP, Group
+// may be a full DN or the value of its first RDN (e.g. "developers" for
+// "cn=developers,ou=groups,dc=example,dc=com"); names are compared
+// case-insensitively.
+type GroupRole struct {
+	Group      string `json:"group"`
+	Repository string `json:"repository"`
+	Role       string `json:"role"`
+}
+
+// RoleGrant is a repository role derived from external group membership.
+type RoleGrant struct {
+	Repository string
+	Role       string
 }
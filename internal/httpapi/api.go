@@ -1,6 +1,7 @@
 package httpapi
 
 import (
+	"net"
 	"net/http"
 
 	"hermit/internal/auth"
@@ -41,6 +42,7 @@ func (a *API) NewEcho() *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
+	e.IPExtractor = ipExtractor(a.cfg.TrustedProxies)
 	e.Use(middleware.Recover())
 	e.Use(middleware.RequestID())
 	e.Use(middleware.RequestLogger())
@@ -76,3 +78,21 @@ func (a *API) NewEcho() *echo.Echo {
 	a.registerRoutes(e)
 	return e
 }
+
+// ipExtractor takes the client IP from X-Forwarded-For only on requests
+// relayed by a trusted proxy, and from the connection otherwise, so clients
+// cannot pick the IP that login throttling and rate limits see.
+func ipExtractor(trusted []*net.IPNet) echo.IPExtractor {
+	if len(trusted) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, r := range trusted {
+		opts = append(opts, echo.TrustIPRange(r))
+	}
+	return echo.ExtractIPFromXFFHeader(opts...)
+}
@@ -0,0 +1,43 @@
+package httpapi
+
+import (
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestIPExtractor(t *testing.T) {
+	t.Parallel()
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("ParseCIDR() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		trusted    []*net.IPNet
+		remoteAddr string
+		xff        string
+		want       string
+	}{
+		{name: "no proxies ignores header", remoteAddr: "203.0.113.5:4000", xff: "198.51.100.1", want: "203.0.113.5"},
+		{name: "trusted proxy", trusted: []*net.IPNet{proxies}, remoteAddr: "10.1.2.3:4000", xff: "198.51.100.1", want: "198.51.100.1"},
+		{name: "untrusted peer", trusted: []*net.IPNet{proxies}, remoteAddr: "203.0.113.5:4000", xff: "198.51.100.1", want: "203.0.113.5"},
+		{name: "private peer not trusted by default", trusted: []*net.IPNet{proxies}, remoteAddr: "192.168.1.1:4000", xff: "198.51.100.1", want: "192.168.1.1"},
+		{name: "spoofed hop before proxy", trusted: []*net.IPNet{proxies}, remoteAddr: "10.1.2.3:4000", xff: "1.1.1.1, 198.51.100.1", want: "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", tt.xff)
+			if got := ipExtractor(tt.trusted)(req); got != tt.want {
+				t.Fatalf("ipExtractor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"hermit/internal/auth"
 	"hermit/internal/service"
@@ -87,6 +89,35 @@ func (h *Handler) ToggleSyncSource(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]any{"ok": true})
 }
 
+func (h *Handler) SaveSyncSchedule(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	var req service.SyncSchedule
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	if err := h.svc.SaveSyncSchedule(c.Request().Context(), id, req); err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
+
+func (h *Handler) DeleteSyncSchedule(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	if err := h.svc.DeleteSyncSchedule(c.Request().Context(), id); err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
+
 func (h *Handler) TriggerSync(c echo.Context) error {
 	if err := h.requireAdmin(c); err != nil {
 		return err
@@ -125,14 +156,91 @@ func (h *Handler) GetSyncStatus(c echo.Context) error {
 	}
 
 	status := h.syncTrigger.Status()
+	lastRun, err := h.svc.GetLatestSyncRun(c.Request().Context())
+	if err != nil {
+		return mapServiceError(err)
+	}
 	return c.JSON(http.StatusOK, map[string]any{
 		"configured": true,
 		"running":    status.Running,
 		"lastResult": status.LastResult,
 		"lastError":  status.LastError,
+		"lastRun":    lastRun,
 	})
 }
 
+func (h *Handler) ListSyncRuns(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	limit := clampInt(queryInt(c, "limit", 25), 1, 200)
+	offset, err := decodeCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
+	}
+
+	runs, err := h.svc.ListSyncRuns(c.Request().Context(), limit+1, offset)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	hasMore := len(runs) > limit
+	if hasMore {
+		runs = runs[:limit]
+	}
+	var nextCursor any = nil
+	if hasMore {
+		nextCursor = encodeCursor(offset + limit)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"items": runs, "nextCursor": nextCursor})
+}
+
+func (h *Handler) GetSyncRun(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	run, err := h.svc.GetSyncRun(c.Request().Context(), strings.TrimSpace(c.Param("id")))
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, run)
+}
+
+func (h *Handler) ListSyncRunItems(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	limit := clampInt(queryInt(c, "limit", 50), 1, 500)
+	offset, err := decodeCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
+	}
+
+	items, err := h.svc.ListSyncRunItems(
+		c.Request().Context(),
+		strings.TrimSpace(c.Param("id")),
+		c.QueryParam("outcome"),
+		limit+1,
+		offset,
+	)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	var nextCursor any = nil
+	if hasMore {
+		nextCursor = encodeCursor(offset + limit)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"items": items, "nextCursor": nextCursor})
+}
+
 // --- Proxy Sync Config ---
 
 func (h *Handler) GetProxySyncConfig(c echo.Context) error {
@@ -221,6 +329,336 @@ func (h *Handler) RemoveMember(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]any{"ok": true})
 }
 
+// ---- Repository Management (admin) ----
+
+func (h *Handler) ListRepositories(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	repos, err := h.svc.ListRepositories(c.Request().Context())
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"repositories": repos})
+}
+
+func (h *Handler) GetRepository(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	repo, err := h.svc.GetRepositoryByID(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, repo)
+}
+
+func (h *Handler) CreateRepository(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	var req struct {
+		Name        string `json:"name"`
+		Type        string `json:"type"`
+		UpstreamURL string `json:"upstreamUrl"`
+		Public      bool   `json:"public"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	repo, err := h.svc.CreateRepository(c.Request().Context(), service.RepositoryInput{
+		Name:        req.Name,
+		Type:        req.Type,
+		UpstreamURL: req.UpstreamURL,
+		Public:      req.Public,
+	})
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusCreated, repo)
+}
+
+func (h *Handler) UpdateRepository(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	var req struct {
+		Name        *string `json:"name"`
+		UpstreamURL *string `json:"upstreamUrl"`
+		Enabled     *bool   `json:"enabled"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	repo, err := h.svc.UpdateRepository(c.Request().Context(), c.Param("id"), service.RepositoryPatch{
+		Name:        req.Name,
+		UpstreamURL: req.UpstreamURL,
+		Enabled:     req.Enabled,
+	})
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, repo)
+}
+
+func (h *Handler) DeleteRepository(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	if err := h.svc.DeleteRepository(c.Request().Context(), c.Param("id")); err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
+
+func (h *Handler) ListGroupMembers(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	members, err := h.svc.ListGroupMembers(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"members": members})
+}
+
+func (h *Handler) AddGroupMember(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	var req struct {
+		RepositoryID string `json:"repositoryId"`
+		Priority     *int   `json:"priority"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	members, err := h.svc.AddGroupMember(c.Request().Context(), c.Param("id"), req.RepositoryID, req.Priority)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"members": members})
+}
+
+func (h *Handler) UpdateGroupMember(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	var req struct {
+		Priority *int `json:"priority"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if req.Priority == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "priority required")
+	}
+
+	members, err := h.svc.SetGroupMemberPriority(c.Request().Context(), c.Param("id"), c.Param("memberId"), *req.Priority)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"members": members})
+}
+
+func (h *Handler) ReorderGroupMembers(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	var req struct {
+		Order []string `json:"order"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	members, err := h.svc.ReorderGroupMembers(c.Request().Context(), c.Param("id"), req.Order)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"members": members})
+}
+
+func (h *Handler) RemoveGroupMember(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	if err := h.svc.RemoveGroupMember(c.Request().Context(), c.Param("id"), c.Param("memberId")); err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
+
+// ---- Purge & Blob GC (admin) ----
+
+func (h *Handler) PurgeSkill(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	if err := h.svc.PurgeSkill(c.Request().Context(), c.Param("id"), c.Param("slug")); err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
+
+func (h *Handler) PurgeSkillVersion(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	if err := h.svc.PurgeSkillVersion(c.Request().Context(), c.Param("id"), c.Param("slug"), c.Param("version")); err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
+
+func (h *Handler) RunBlobGC(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	var req struct {
+		DryRun      bool   `json:"dryRun"`
+		GracePeriod string `json:"gracePeriod"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	opts := service.BlobGCOptions{DryRun: req.DryRun, GracePeriod: service.DefaultBlobGCGracePeriod}
+	if req.GracePeriod != "" {
+		grace, err := time.ParseDuration(req.GracePeriod)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid gracePeriod")
+		}
+		opts.GracePeriod = grace
+	}
+
+	report, err := h.svc.CollectBlobGarbage(c.Request().Context(), opts)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+func (h *Handler) RunBlobScrub(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	var req struct {
+		RepositoryID string `json:"repositoryId"`
+		Refetch      bool   `json:"refetch"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	report, err := h.svc.ScrubBlobs(c.Request().Context(), service.BlobScrubOptions{
+		RepositoryID: req.RepositoryID,
+		Refetch:      req.Refetch,
+	})
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// ---- Admin Token (admin) ----
+
+// RotateAdminToken issues a new admin token and revokes the previous one,
+// including the bootstrap token. The new token is only returned here.
+func (h *Handler) RotateAdminToken(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+	claims, _ := auth.GetClaims(c)
+
+	rawToken, tok, err := h.svc.RotateAdminToken(c.Request().Context(), claims.Subject)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	log.Printf("[auth] admin token rotated by %s", claims.Subject)
+	return c.JSON(http.StatusOK, map[string]any{
+		"token":      rawToken,
+		"id":         tok.ID.String(),
+		"subject":    tok.Subject,
+		"created_at": tok.CreatedAt,
+	})
+}
+
+// ---- Login Attempts (admin) ----
+
+func (h *Handler) ListLoginAttempts(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+
+	limit := clampInt(queryInt(c, "limit", 50), 1, 500)
+	offset, err := decodeCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
+	}
+
+	attempts, err := h.svc.ListLoginAttempts(c.Request().Context(), service.LoginAttemptQuery{
+		Username: c.QueryParam("username"),
+		ClientIP: c.QueryParam("ip"),
+		Outcome:  c.QueryParam("outcome"),
+	}, limit+1, offset)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	hasMore := len(attempts) > limit
+	if hasMore {
+		attempts = attempts[:limit]
+	}
+	var nextCursor any = nil
+	if hasMore {
+		nextCursor = encodeCursor(offset + limit)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"items": attempts, "nextCursor": nextCursor})
+}
+
+func (h *Handler) ListLoginLockouts(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+	lockouts, err := h.svc.ListLoginLockouts(c.Request().Context())
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"items": lockouts})
+}
+
+// ClearLoginLockout lifts the lockout of a username or client IP.
+func (h *Handler) ClearLoginLockout(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+	claims, _ := auth.GetClaims(c)
+
+	kind, key := c.Param("kind"), c.Param("key")
+	if err := h.svc.ClearLoginLockout(c.Request().Context(), kind, key); err != nil {
+		return mapServiceError(err)
+	}
+	log.Printf("[auth] login lockout of %s %q cleared by %s", kind, key, claims.Subject)
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
+
 // ---- Local User Management (admin) ----
 
 func (h *Handler) ListUsers(c echo.Context) error {
@@ -296,6 +734,67 @@ func (h *Handler) ResetUserPassword(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]any{"ok": true})
 }
 
+// UnlockUser lifts the login lockout of a local user.
+func (h *Handler) UnlockUser(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+	claims, _ := auth.GetClaims(c)
+
+	u, cleared, err := h.svc.UnlockUser(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+	if cleared {
+		log.Printf("[auth] user %s unlocked by %s", u.Username, claims.Subject)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true, "unlocked": cleared})
+}
+
+// ResetUserTwoFactor turns off 2FA for a user who lost their device.
+func (h *Handler) ResetUserTwoFactor(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+	claims, _ := auth.GetClaims(c)
+
+	u, err := h.svc.ResetUserTwoFactor(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return mapServiceError(err)
+	}
+	log.Printf("[auth] two-factor authentication of %s reset by %s", u.Username, claims.Subject)
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
+
+func (h *Handler) GetTwoFactorPolicy(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+	policy, err := h.svc.GetTwoFactorPolicy(c.Request().Context())
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+func (h *Handler) SetTwoFactorPolicy(c echo.Context) error {
+	if err := h.requireAdmin(c); err != nil {
+		return err
+	}
+	claims, _ := auth.GetClaims(c)
+
+	var req service.TwoFactorPolicy
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	policy, err := h.svc.SetTwoFactorPolicy(c.Request().Context(), req)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	log.Printf("[auth] two-factor policy set to require_admins=%t by %s", policy.RequireAdmins, claims.Subject)
+	return c.JSON(http.StatusOK, policy)
+}
+
 func (h *Handler) DeleteUserByID(c echo.Context) error {
 	if err := h.requireAdmin(c); err != nil {
 		return err
@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"hermit/internal/auth"
 	"hermit/internal/service"
@@ -32,6 +34,9 @@ func (h *Handler) ChangePassword(c echo.Context) error {
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
 	}
+	if err := requireUnrestricted(claims); err != nil {
+		return err
+	}
 	var req struct {
 		OldPassword string `json:"old_password"`
 		NewPassword string `json:"new_password"`
@@ -39,31 +44,147 @@ func (h *Handler) ChangePassword(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
 	}
-	if err := h.svc.ChangePassword(c.Request().Context(), claims.Subject, req.OldPassword, req.NewPassword); err != nil {
+	if err := h.svc.ChangePassword(c.Request().Context(), claims.Subject, req.OldPassword, req.NewPassword, claims.TokenID); err != nil {
 		return mapServiceError(err)
 	}
 	return c.JSON(http.StatusOK, map[string]any{"ok": true})
 }
 
-func (h *Handler) PublishSkill(c echo.Context) error {
+// ---- Two-factor authentication (self-service) ----
+
+func (h *Handler) GetTwoFactorStatus(c echo.Context) error {
 	claims, ok := auth.GetClaims(c)
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
 	}
+	status, err := h.svc.GetTwoFactorStatus(c.Request().Context(), claims.Subject)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, status)
+}
 
-	repo, err := h.svc.GetPublishRepository(c.Request().Context())
+// BeginTwoFactorSetup returns a new TOTP secret to enroll in an
+// authenticator app; EnableTwoFactor confirms it.
+func (h *Handler) BeginTwoFactorSetup(c echo.Context) error {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if err := requireUnrestricted(claims); err != nil {
+		return err
+	}
+	setup, err := h.svc.BeginTOTPSetup(c.Request().Context(), claims.Subject)
 	if err != nil {
 		return mapServiceError(err)
 	}
-	allowed, err := h.svc.HasRepoPermission(c.Request().Context(), repo, claims.Subject, store.RolePush, claims.IsAdmin)
+	return c.JSON(http.StatusOK, setup)
+}
+
+func (h *Handler) EnableTwoFactor(c echo.Context) error {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if err := requireUnrestricted(claims); err != nil {
+		return err
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	codes, err := h.svc.EnableTOTP(c.Request().Context(), claims.Subject, req.Code)
 	if err != nil {
 		return mapServiceError(err)
 	}
-	if !allowed {
-		return echo.NewHTTPError(http.StatusForbidden, "missing push permission")
+	return c.JSON(http.StatusOK, map[string]any{"ok": true, "recovery_codes": codes})
+}
+
+func (h *Handler) DisableTwoFactor(c echo.Context) error {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if err := requireUnrestricted(claims); err != nil {
+		return err
 	}
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if err := h.svc.DisableTOTP(c.Request().Context(), claims.Subject, req.Password, req.Code, sessionClient(c)); err != nil {
+		return accountFactorError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
 
-	if err := c.Request().ParseMultipartForm(h.cfg.MaxUploadBytes); err != nil {
+func (h *Handler) RegenerateRecoveryCodes(c echo.Context) error {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if err := requireUnrestricted(claims); err != nil {
+		return err
+	}
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	codes, err := h.svc.RegenerateRecoveryCodes(c.Request().Context(), claims.Subject, req.Password, req.Code, sessionClient(c))
+	if err != nil {
+		return accountFactorError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"recovery_codes": codes})
+}
+
+// accountFactorError maps errors of 2FA changes that check the password and
+// code, which are throttled like logins.
+func accountFactorError(c echo.Context, err error) error {
+	var blocked *service.LoginBlockedError
+	if errors.As(err, &blocked) {
+		return loginError(c, err)
+	}
+	return mapServiceError(err)
+}
+
+// publishFormMemory is how much of a publish form is kept in memory; larger
+// uploads are spooled to disk.
+const publishFormMemory = 4 << 20
+
+func (h *Handler) PublishSkill(c echo.Context) error {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	// Authorize before reading the upload, so a caller without push
+	// permission cannot make the server spool a large body.
+	repoName, err := targetRepoName(c.Param("repo"), c.QueryParam("repository"))
+	if err != nil {
+		return err
+	}
+	repo, err := h.requirePushRepo(c, claims, repoName)
+	if err != nil {
+		return err
+	}
+
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, h.cfg.MaxUploadBytes)
+	// Parts beyond publishFormMemory are spooled to temp files, which
+	// net/http removes once the request finishes.
+	if err := req.ParseMultipartForm(publishFormMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "upload too large")
+		}
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid multipart form")
 	}
 	form := c.Request().MultipartForm
@@ -87,57 +208,61 @@ func (h *Handler) PublishSkill(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid payload json")
 	}
 
-	fileHeaders := form.File["files"]
-	if len(fileHeaders) == 0 {
-		return echo.NewHTTPError(http.StatusBadRequest, "files[] is required")
+	publish := service.PublishPayload{
+		Slug:        payload.Slug,
+		DisplayName: payload.DisplayName,
+		Version:     payload.Version,
+		Changelog:   payload.Changelog,
+		Summary:     payload.Summary,
+		Tags:        payload.Tags,
 	}
+	fileHeaders := form.File["files"]
+	archiveHeaders := form.File["archive"]
 
-	files := make([]service.PublishFileInput, 0, len(fileHeaders))
-	for _, header := range fileHeaders {
-		if header.Size > h.cfg.MaxUploadBytes {
-			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "file too large")
+	var result service.PublishResult
+	switch {
+	case len(archiveHeaders) > 0:
+		if len(fileHeaders) > 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "send either files[] or archive, not both")
+		}
+		if len(archiveHeaders) > 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, "only one archive is allowed")
 		}
+		header := archiveHeaders[0]
 		f, err := header.Open()
 		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid uploaded file")
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid uploaded archive")
 		}
-		data, readErr := io.ReadAll(io.LimitReader(f, h.cfg.MaxUploadBytes+1))
-		_ = f.Close()
-		if readErr != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "failed to read uploaded file")
+		defer f.Close()
+		result, err = h.svc.PublishSkillArchive(c.Request().Context(), repo, publish, f, header.Size, claims.Subject)
+		if err != nil {
+			return mapServiceError(err)
 		}
-		if int64(len(data)) > h.cfg.MaxUploadBytes {
-			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "file too large")
+	case len(fileHeaders) > 0:
+		files := make([]service.PublishFileInput, 0, len(fileHeaders))
+		for _, header := range fileHeaders {
+			if header.Size > h.cfg.MaxUploadBytes {
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "file too large")
+			}
+			files = append(files, service.PublishFileInput{
+				Path:        header.Filename,
+				ContentType: header.Header.Get(echo.HeaderContentType),
+				Open:        func() (io.ReadCloser, error) { return header.Open() },
+			})
 		}
-		files = append(files, service.PublishFileInput{
-			Path:        header.Filename,
-			ContentType: header.Header.Get(echo.HeaderContentType),
-			Bytes:       data,
-		})
-	}
-
-	result, err := h.svc.PublishSkill(
-		c.Request().Context(),
-		repo,
-		service.PublishPayload{
-			Slug:        payload.Slug,
-			DisplayName: payload.DisplayName,
-			Version:     payload.Version,
-			Changelog:   payload.Changelog,
-			Summary:     payload.Summary,
-			Tags:        payload.Tags,
-		},
-		files,
-		claims.Subject,
-	)
-	if err != nil {
-		return mapServiceError(err)
+		result, err = h.svc.PublishSkill(c.Request().Context(), repo, publish, files, claims.Subject)
+		if err != nil {
+			return mapServiceError(err)
+		}
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "files[] or archive is required")
 	}
 
 	return c.JSON(http.StatusOK, map[string]any{
-		"ok":        true,
-		"skillId":   result.SkillID,
-		"versionId": result.VersionID,
+		"ok":         true,
+		"skillId":    result.SkillID,
+		"versionId":  result.VersionID,
+		"repository": repo.Name,
 	})
 }
 
@@ -154,25 +279,187 @@ func (h *Handler) setDelete(c echo.Context, deleted bool) error {
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
 	}
-	repo, err := h.svc.GetPublishRepository(c.Request().Context())
+	repoName, err := targetRepoName(c.Param("repo"), c.QueryParam("repository"))
+	if err != nil {
+		return err
+	}
+	repo, err := h.requirePushRepo(c, claims, repoName)
+	if err != nil {
+		return err
+	}
+
+	slug := strings.TrimSpace(c.Param("slug"))
+	if err := h.svc.DeleteSkill(c.Request().Context(), repo, slug, deleted); err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
+
+// SetSkillTag points a dist-tag at an existing version.
+func (h *Handler) SetSkillTag(c echo.Context) error {
+	var req struct {
+		Version    string `json:"version"`
+		Repository string `json:"repository"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	repo, err := h.skillWriteRepo(c, req.Repository)
 	if err != nil {
+		return err
+	}
+
+	slug := strings.TrimSpace(c.Param("slug"))
+	tag := strings.TrimSpace(c.Param("tag"))
+	if err := h.svc.SetSkillTag(c.Request().Context(), repo, slug, tag, req.Version); err != nil {
 		return mapServiceError(err)
 	}
-	allowed, err := h.svc.HasRepoPermission(c.Request().Context(), repo, claims.Subject, store.RolePush, claims.IsAdmin)
+	return c.JSON(http.StatusOK, map[string]any{"ok": true, "tag": tag, "version": strings.TrimSpace(req.Version)})
+}
+
+// DeleteSkillTag removes a dist-tag. latest cannot be removed.
+func (h *Handler) DeleteSkillTag(c echo.Context) error {
+	repo, err := h.skillWriteRepo(c, "")
 	if err != nil {
+		return err
+	}
+
+	slug := strings.TrimSpace(c.Param("slug"))
+	tag := strings.TrimSpace(c.Param("tag"))
+	if err := h.svc.DeleteSkillTag(c.Request().Context(), repo, slug, tag); err != nil {
 		return mapServiceError(err)
 	}
-	if !allowed {
-		return echo.NewHTTPError(http.StatusForbidden, "missing push permission")
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
+
+// YankVersion hides a version from latest and range resolution.
+func (h *Handler) YankVersion(c echo.Context) error {
+	var req struct {
+		Reason     string `json:"reason"`
+		Repository string `json:"repository"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	return h.setYanked(c, req.Repository, true, req.Reason)
+}
+
+// UnyankVersion restores a yanked version.
+func (h *Handler) UnyankVersion(c echo.Context) error {
+	return h.setYanked(c, "", false, "")
+}
+
+func (h *Handler) setYanked(c echo.Context, requestRepo string, yanked bool, reason string) error {
+	repo, err := h.skillWriteRepo(c, requestRepo)
+	if err != nil {
+		return err
 	}
 
 	slug := strings.TrimSpace(c.Param("slug"))
-	if err := h.svc.DeleteSkill(c.Request().Context(), repo, slug, deleted); err != nil {
+	version := strings.TrimSpace(c.Param("version"))
+	if err := h.svc.SetVersionYanked(c.Request().Context(), repo, slug, version, yanked, reason); err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true, "yanked": yanked})
+}
+
+// SetSkillDeprecation sets the deprecation message of a skill, or of one
+// version when the route names a version.
+func (h *Handler) SetSkillDeprecation(c echo.Context) error {
+	var req struct {
+		Message    string `json:"message"`
+		Repository string `json:"repository"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "message is required")
+	}
+	return h.setDeprecation(c, req.Repository, req.Message)
+}
+
+// ClearSkillDeprecation removes the deprecation message of a skill, or of
+// one version when the route names a version.
+func (h *Handler) ClearSkillDeprecation(c echo.Context) error {
+	return h.setDeprecation(c, "", "")
+}
+
+func (h *Handler) setDeprecation(c echo.Context, requestRepo string, message string) error {
+	repo, err := h.skillWriteRepo(c, requestRepo)
+	if err != nil {
+		return err
+	}
+
+	slug := strings.TrimSpace(c.Param("slug"))
+	version := strings.TrimSpace(c.Param("version"))
+	if version == "" {
+		err = h.svc.SetSkillDeprecation(c.Request().Context(), repo, slug, message)
+	} else {
+		err = h.svc.SetVersionDeprecation(c.Request().Context(), repo, slug, version, message)
+	}
+	if err != nil {
 		return mapServiceError(err)
 	}
 	return c.JSON(http.StatusOK, map[string]any{"ok": true})
 }
 
+// skillWriteRepo resolves the hosted repository a skill write targets from
+// the route, the request body or the repository query parameter, and checks
+// push permission.
+func (h *Handler) skillWriteRepo(c echo.Context, requestRepo string) (store.Repository, error) {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return store.Repository{}, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if requestRepo == "" {
+		requestRepo = c.QueryParam("repository")
+	}
+	repoName, err := targetRepoName(c.Param("repo"), requestRepo)
+	if err != nil {
+		return store.Repository{}, err
+	}
+	return h.requirePushRepo(c, claims, repoName)
+}
+
+// requirePushRepo resolves the hosted repository a write targets (the
+// default hosted repository when repoName is empty) and checks that the
+// caller may push to it.
+func (h *Handler) requirePushRepo(c echo.Context, claims auth.Claims, repoName string) (store.Repository, error) {
+	if !claims.HasScope(auth.ScopePublish) {
+		return store.Repository{}, echo.NewHTTPError(http.StatusForbidden, "token lacks the publish scope")
+	}
+	repo, err := h.svc.GetPublishRepository(c.Request().Context(), repoName)
+	if err != nil {
+		return store.Repository{}, mapServiceError(err)
+	}
+	if !claims.AllowsRepository(repo.Name) {
+		return store.Repository{}, echo.NewHTTPError(http.StatusForbidden, "token is not valid for repository "+repo.Name)
+	}
+	allowed, err := h.svc.HasRepoPermission(c.Request().Context(), repo, claims.Subject, store.RolePush, claims.SubjectIsAdmin)
+	if err != nil {
+		return store.Repository{}, mapServiceError(err)
+	}
+	if !allowed {
+		return store.Repository{}, echo.NewHTTPError(http.StatusForbidden, "missing push permission")
+	}
+	return repo, nil
+}
+
+// targetRepoName picks the repository named in the URL path or, for the
+// unscoped routes, in the request. Both may be given only if they agree.
+func targetRepoName(pathRepo, requestRepo string) (string, error) {
+	pathRepo = strings.TrimSpace(pathRepo)
+	requestRepo = strings.TrimSpace(requestRepo)
+	if pathRepo != "" && requestRepo != "" && pathRepo != requestRepo {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "repository does not match the request path")
+	}
+	if pathRepo != "" {
+		return pathRepo, nil
+	}
+	return requestRepo, nil
+}
+
 // AdminCreateToken allows admins to create a token for any subject.
 func (h *Handler) AdminCreateToken(c echo.Context) error {
 	claims, ok := auth.GetClaims(c)
@@ -184,8 +471,11 @@ func (h *Handler) AdminCreateToken(c echo.Context) error {
 	}
 
 	var req struct {
-		Subject string `json:"subject"`
-		Name    string `json:"name"`
+		Subject      string     `json:"subject"`
+		Name         string     `json:"name"`
+		Scopes       []string   `json:"scopes"`
+		Repositories []string   `json:"repositories"`
+		ExpiresAt    *time.Time `json:"expires_at"`
 	}
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
@@ -193,15 +483,22 @@ func (h *Handler) AdminCreateToken(c echo.Context) error {
 	if req.Name == "" {
 		req.Name = "admin-created"
 	}
-	rawToken, tok, err := h.svc.CreatePersonalToken(c.Request().Context(), req.Subject, req.Name, false)
+	rawToken, tok, err := h.svc.CreatePersonalToken(c.Request().Context(), req.Subject, req.Name, false, service.TokenOptions{
+		Scopes:       req.Scopes,
+		Repositories: req.Repositories,
+		ExpiresAt:    req.ExpiresAt,
+	})
 	if err != nil {
 		return mapServiceError(err)
 	}
 	return c.JSON(http.StatusOK, map[string]any{
-		"subject": req.Subject,
-		"token":   rawToken,
-		"id":      tok.ID.String(),
-		"name":    tok.Name,
+		"subject":      req.Subject,
+		"token":        rawToken,
+		"id":           tok.ID.String(),
+		"name":         tok.Name,
+		"scopes":       tok.Scopes,
+		"repositories": tok.Repositories,
+		"expires_at":   tok.ExpiresAt,
 	})
 }
 
@@ -227,20 +524,33 @@ func (h *Handler) CreateMyToken(c echo.Context) error {
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
 	}
+	if err := requireUnrestricted(claims); err != nil {
+		return err
+	}
 	var req struct {
-		Name string `json:"name"`
+		Name         string     `json:"name"`
+		Scopes       []string   `json:"scopes"`
+		Repositories []string   `json:"repositories"`
+		ExpiresAt    *time.Time `json:"expires_at"`
 	}
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
 	}
-	rawToken, tok, err := h.svc.CreatePersonalToken(c.Request().Context(), claims.Subject, req.Name, claims.IsAdmin)
+	rawToken, tok, err := h.svc.CreatePersonalToken(c.Request().Context(), claims.Subject, req.Name, claims.IsAdmin, service.TokenOptions{
+		Scopes:       req.Scopes,
+		Repositories: req.Repositories,
+		ExpiresAt:    req.ExpiresAt,
+	})
 	if err != nil {
 		return mapServiceError(err)
 	}
 	return c.JSON(http.StatusOK, map[string]any{
-		"token": rawToken,
-		"id":    tok.ID.String(),
-		"name":  tok.Name,
+		"token":        rawToken,
+		"id":           tok.ID.String(),
+		"name":         tok.Name,
+		"scopes":       tok.Scopes,
+		"repositories": tok.Repositories,
+		"expires_at":   tok.ExpiresAt,
 	})
 }
 
@@ -250,9 +560,22 @@ func (h *Handler) RevokeMyToken(c echo.Context) error {
 	if !ok {
 		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
 	}
+	if err := requireUnrestricted(claims); err != nil {
+		return err
+	}
 	tokenID := c.Param("tokenId")
 	if err := h.svc.RevokeToken(c.Request().Context(), tokenID, claims.Subject, claims.IsAdmin); err != nil {
 		return mapServiceError(err)
 	}
 	return c.JSON(http.StatusOK, map[string]any{"ok": true})
 }
+
+// requireUnrestricted rejects scoped tokens from account and token
+// management, so a token cannot change the password or mint a more
+// powerful token.
+func requireUnrestricted(claims auth.Claims) error {
+	if !claims.Unrestricted() {
+		return echo.NewHTTPError(http.StatusForbidden, "this action requires a login session or an unscoped token")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import "testing"
+
+func TestTargetRepoName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		pathRepo    string
+		requestRepo string
+		want        string
+		wantErr     bool
+	}{
+		{"default", "", "", "", false},
+		{"path only", "team-a", "", "team-a", false},
+		{"request only", "", " team-b ", "team-b", false},
+		{"both agree", "team-a", "team-a", "team-a", false},
+		{"both differ", "team-a", "team-b", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := targetRepoName(tt.pathRepo, tt.requestRepo)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("targetRepoName(%q, %q) = %q, want %q", tt.pathRepo, tt.requestRepo, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"hermit/internal/auth"
 	"hermit/internal/extauth"
 	"hermit/internal/service"
 
@@ -18,6 +25,9 @@ func (h *Handler) AuthProviders(c echo.Context) error {
 	if h.svc.IsAuthProviderEnabled(ctx, service.ProviderTypeLDAP) {
 		providers = append(providers, extauth.Provider{ID: "ldap", Name: "LDAP", Type: "ldap"})
 	}
+	if h.svc.IsAuthProviderEnabled(ctx, service.ProviderTypeOIDC) {
+		providers = append(providers, extauth.Provider{ID: "oidc", Name: "SSO", Type: "oidc"})
+	}
 	return c.JSON(http.StatusOK, map[string]any{"providers": providers})
 }
 
@@ -31,18 +41,82 @@ func (h *Handler) LocalLogin(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
 	}
 
-	token, user, err := h.svc.LocalLogin(c.Request().Context(), body.Username, body.Password)
+	res, err := h.svc.LocalLogin(c.Request().Context(), body.Username, body.Password, sessionClient(c))
 	if err != nil {
-		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		return loginError(c, err)
+	}
+	if res.Challenge != nil {
+		return c.JSON(http.StatusOK, map[string]any{
+			"mfa_required":       true,
+			"mfa_token":          res.Challenge.Token,
+			"mfa_setup_required": res.Challenge.Setup,
+			"expires_at":         res.Challenge.ExpiresAt,
+		})
 	}
+	return c.JSON(http.StatusOK, localLoginResponse(res))
+}
 
-	return c.JSON(http.StatusOK, map[string]any{
-		"token":        token,
-		"subject":      user.Username,
-		"display_name": user.DisplayName,
-		"email":        user.Email,
-		"is_admin":     user.IsAdmin,
-	})
+// TwoFactorLogin completes a local login with a TOTP or recovery code.
+func (h *Handler) TwoFactorLogin(c echo.Context) error {
+	var body struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	res, err := h.svc.CompleteTwoFactorLogin(c.Request().Context(), body.MFAToken, body.Code, sessionClient(c))
+	if err != nil {
+		return loginError(c, err)
+	}
+	return c.JSON(http.StatusOK, localLoginResponse(res))
+}
+
+// TwoFactorLoginSetup starts 2FA enrollment for a login that requires it.
+func (h *Handler) TwoFactorLoginSetup(c echo.Context) error {
+	var body struct {
+		MFAToken string `json:"mfa_token"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	setup, err := h.svc.BeginLoginTOTPSetup(c.Request().Context(), body.MFAToken)
+	if err != nil {
+		return loginError(c, err)
+	}
+	return c.JSON(http.StatusOK, setup)
+}
+
+// TwoFactorLoginEnable finishes the enrollment started by
+// TwoFactorLoginSetup and completes the login.
+func (h *Handler) TwoFactorLoginEnable(c echo.Context) error {
+	var body struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	res, codes, err := h.svc.CompleteLoginTOTPSetup(c.Request().Context(), body.MFAToken, body.Code, sessionClient(c))
+	if err != nil {
+		return loginError(c, err)
+	}
+	resp := localLoginResponse(res)
+	resp["recovery_codes"] = codes
+	return c.JSON(http.StatusOK, resp)
+}
+
+func localLoginResponse(res service.LocalLoginResult) map[string]any {
+	return map[string]any{
+		"token":        res.Token,
+		"subject":      res.User.Username,
+		"display_name": res.User.DisplayName,
+		"email":        res.User.Email,
+		"is_admin":     res.User.IsAdmin,
+	}
 }
 
 // LDAPLogin authenticates via LDAP and returns a session token.
@@ -64,12 +138,16 @@ func (h *Handler) LDAPLogin(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
 	}
 
-	identity, err := ldapAuth.Authenticate(body.Username, body.Password)
+	identity, err := h.svc.AuthenticateLDAP(ctx, ldapAuth, body.Username, body.Password, sessionClient(c))
 	if err != nil {
-		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		return loginError(c, err)
 	}
 
-	token, err := h.svc.IssueSessionToken(ctx, identity.Subject, identity.IsAdmin)
+	if err := h.svc.SyncExternalRoles(ctx, service.ProviderTypeLDAP, identity); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to apply directory roles")
+	}
+
+	token, err := h.svc.IssueSessionToken(ctx, identity.Subject, identity.IsAdmin, sessionClient(c))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to issue token")
 	}
@@ -82,3 +160,156 @@ func (h *Handler) LDAPLogin(c echo.Context) error {
 		"is_admin":     identity.IsAdmin,
 	})
 }
+
+const oidcStateCookie = "hermit_oidc_state"
+
+// OIDCLogin starts the authorization code flow and redirects the browser to
+// the identity provider. The optional redirect query parameter is the SPA
+// path to return to after login.
+func (h *Handler) OIDCLogin(c echo.Context) error {
+	authURL, state, err := h.svc.BeginOIDCLogin(c.Request().Context(), normalizeReturnPath(c.QueryParam("redirect")))
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "OIDC not enabled")
+		}
+		return echo.NewHTTPError(http.StatusBadGateway, err.Error())
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/v1/auth/oidc",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	return c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes the login and hands the session token to the SPA
+// in the URL fragment, so it never reaches server logs.
+func (h *Handler) OIDCCallback(c echo.Context) error {
+	c.SetCookie(&http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Path:     "/api/v1/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if idpErr := strings.TrimSpace(c.QueryParam("error")); idpErr != "" {
+		return redirectLoginError(c, "sign-in was rejected by the identity provider: "+idpErr)
+	}
+
+	state := c.QueryParam("state")
+	cookie, err := c.Cookie(oidcStateCookie)
+	if err != nil || state == "" || cookie.Value != state {
+		return redirectLoginError(c, "sign-in state mismatch, please try again")
+	}
+
+	token, identity, returnTo, err := h.svc.CompleteOIDCLogin(c.Request().Context(), state, c.QueryParam("code"), sessionClient(c))
+	if err != nil {
+		c.Logger().Warnf("oidc login failed: %v", err)
+		return redirectLoginError(c, "sign-in failed")
+	}
+
+	fragment := url.Values{}
+	fragment.Set("token", token)
+	fragment.Set("subject", identity.Subject)
+	return c.Redirect(http.StatusFound, "/login?"+url.Values{"redirect": {returnTo}}.Encode()+"#"+fragment.Encode())
+}
+
+// Logout ends the login session the request was made with.
+func (h *Handler) Logout(c echo.Context) error {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if claims.TokenType != auth.TokenTypeSession {
+		return echo.NewHTTPError(http.StatusBadRequest, "not a login session; revoke API tokens with DELETE /api/v1/tokens/:tokenId")
+	}
+	if err := h.svc.RevokeSession(c.Request().Context(), claims.Subject, claims.TokenID); err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
+
+// ListMySessions returns the current user's login sessions.
+func (h *Handler) ListMySessions(c echo.Context) error {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	sessions, err := h.svc.ListMySessions(c.Request().Context(), claims.Subject, claims.TokenID)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"sessions": sessions})
+}
+
+// RevokeMySession ends one of the current user's sessions.
+func (h *Handler) RevokeMySession(c echo.Context) error {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if err := requireUnrestricted(claims); err != nil {
+		return err
+	}
+	if err := h.svc.RevokeSession(c.Request().Context(), claims.Subject, c.Param("sessionId")); err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true})
+}
+
+// RevokeOtherSessions ends every session of the current user except the
+// one making the request.
+func (h *Handler) RevokeOtherSessions(c echo.Context) error {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	if err := requireUnrestricted(claims); err != nil {
+		return err
+	}
+	n, err := h.svc.RevokeOtherSessions(c.Request().Context(), claims.Subject, claims.TokenID)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true, "revoked": n})
+}
+
+func sessionClient(c echo.Context) service.SessionClient {
+	return service.SessionClient{UserAgent: c.Request().UserAgent(), IP: c.RealIP()}
+}
+
+// loginError answers a failed login with 429 and Retry-After while further
+// attempts are blocked, and with 401 otherwise.
+func loginError(c echo.Context, err error) error {
+	var blocked *service.LoginBlockedError
+	if errors.As(err, &blocked) {
+		retryAfter := int64(math.Ceil(blocked.RetryAfter(time.Now()).Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+		return echo.NewHTTPError(http.StatusTooManyRequests, blocked.Error())
+	}
+	return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+}
+
+func redirectLoginError(c echo.Context, msg string) error {
+	return c.Redirect(http.StatusFound, "/login?"+url.Values{"error": {msg}}.Encode())
+}
+
+// normalizeReturnPath only allows local absolute paths as post-login targets.
+func normalizeReturnPath(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "//") || strings.HasPrefix(raw, "/\\") {
+		return "/"
+	}
+	if strings.HasPrefix(raw, "/login") {
+		return "/"
+	}
+	return raw
+}
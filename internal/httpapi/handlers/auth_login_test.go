@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"hermit/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestLoginError(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name:       "bad credentials",
+			err:        errors.New("invalid username or password"),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "locked",
+			err:            &service.LoginBlockedError{Until: time.Now().Add(90 * time.Second), Locked: true},
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "90",
+		},
+		{
+			name:           "block already over",
+			err:            &service.LoginBlockedError{Until: time.Now().Add(-time.Second)},
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil), rec)
+
+			err := loginError(c, tt.err)
+			var httpErr *echo.HTTPError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("loginError() = %v, want *echo.HTTPError", err)
+			}
+			if httpErr.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", httpErr.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Fatalf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
@@ -2,13 +2,19 @@ package handlers
 
 import (
 	"context"
+	"time"
 
 	"hermit/internal/auth"
 	"hermit/internal/config"
 	"hermit/internal/proxysync"
+	"hermit/internal/ratelimit"
 	"hermit/internal/service"
 )
 
+// anonymousInstallReportsPerMinute caps install reports per client IP from
+// callers without an account, whose client ids cost nothing to invent.
+const anonymousInstallReportsPerMinute = 30
+
 type SyncTriggerer interface {
 	TriggerSync(ctx context.Context) (bool, error)
 	Status() SyncStatus
@@ -25,6 +31,8 @@ type Handler struct {
 	svc         *service.Service
 	auth        *auth.Authenticator
 	syncTrigger SyncTriggerer
+
+	installLimiter *ratelimit.Limiter
 }
 
 func New(
@@ -38,5 +46,9 @@ func New(
 		svc:         svc,
 		auth:        authn,
 		syncTrigger: syncTrigger,
+		installLimiter: ratelimit.New(ratelimit.Config{
+			Window:  time.Minute,
+			WriteIP: anonymousInstallReportsPerMinute,
+		}),
 	}
 }
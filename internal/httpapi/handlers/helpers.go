@@ -5,12 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"hermit/internal/service"
+	"hermit/internal/store"
 
 	"github.com/labstack/echo/v4"
 )
@@ -130,3 +133,62 @@ func baseURL(r *http.Request) string {
 	}
 	return scheme + "://" + host
 }
+
+// renderHighlight HTML-escapes a search fragment and wraps its matches in
+// <mark> elements.
+func renderHighlight(fragment string) string {
+	escaped := html.EscapeString(fragment)
+	escaped = strings.ReplaceAll(escaped, store.HighlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, store.HighlightStop, "</mark>")
+}
+
+// addVersionNotice adds the yank and deprecation fields of a version to a
+// response object.
+func addVersionNotice(dst map[string]any, n service.VersionNotice) {
+	dst["yanked"] = n.YankedAt != nil
+	dst["yankedAt"] = toMillisPtr(n.YankedAt)
+	dst["yankReason"] = n.YankReason
+	dst["deprecated"] = n.Deprecation
+}
+
+// Download response headers that let clients warn about what they fetched.
+const (
+	headerSkillDeprecated   = "X-Skill-Deprecated"
+	headerVersionDeprecated = "X-Version-Deprecated"
+	headerVersionYanked     = "X-Version-Yanked"
+)
+
+func setNoticeHeaders(h http.Header, a store.Artifact) {
+	if a.SkillDeprecation != nil {
+		h.Set(headerSkillDeprecated, headerText(*a.SkillDeprecation))
+	}
+	if a.VersionDeprecation != nil {
+		h.Set(headerVersionDeprecated, headerText(*a.VersionDeprecation))
+	}
+	if a.YankedAt != nil {
+		reason := "true"
+		if a.YankReason != nil {
+			reason = headerText(*a.YankReason)
+		}
+		h.Set(headerVersionYanked, reason)
+	}
+}
+
+// headerText makes a free-form message safe for a header value: control
+// characters become spaces and non-ASCII text (and '%') is percent-encoded.
+func headerText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			b.WriteByte(' ')
+		case r == '%':
+			b.WriteString("%25")
+		case r < 0x80:
+			b.WriteRune(r)
+		default:
+			b.WriteString(url.PathEscape(string(r)))
+		}
+	}
+	return b.String()
+}
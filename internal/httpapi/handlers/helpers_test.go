@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"hermit/internal/service"
+	"hermit/internal/store"
 
 	"github.com/labstack/echo/v4"
 )
@@ -300,3 +301,52 @@ func TestQueryInt(t *testing.T) {
 		}
 	})
 }
+
+func TestRenderHighlight(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{name: "plain", fragment: "no matches", want: "no matches"},
+		{name: "match", fragment: "convert \x02pdf\x03 files", want: "convert <mark>pdf</mark> files"},
+		{name: "escapes markup", fragment: "<script>\x02x\x03</script>", want: "&lt;script&gt;<mark>x</mark>&lt;/script&gt;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := renderHighlight(tt.fragment); got != tt.want {
+				t.Fatalf("renderHighlight() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetNoticeHeaders(t *testing.T) {
+	t.Parallel()
+	yankedAt := time.Now()
+	skillMsg := "use pdf-tools-2 instead"
+	versionMsg := "broken on\nwindows: 100% CPU — upgrade"
+	h := http.Header{}
+	setNoticeHeaders(h, store.Artifact{
+		YankedAt:           &yankedAt,
+		SkillDeprecation:   &skillMsg,
+		VersionDeprecation: &versionMsg,
+	})
+	if got := h.Get("X-Skill-Deprecated"); got != skillMsg {
+		t.Fatalf("X-Skill-Deprecated = %q, want %q", got, skillMsg)
+	}
+	if got, want := h.Get("X-Version-Deprecated"), "broken on windows: 100%25 CPU %E2%80%94 upgrade"; got != want {
+		t.Fatalf("X-Version-Deprecated = %q, want %q", got, want)
+	}
+	if got := h.Get("X-Version-Yanked"); got != "true" {
+		t.Fatalf("X-Version-Yanked = %q, want true", got)
+	}
+
+	h = http.Header{}
+	setNoticeHeaders(h, store.Artifact{})
+	if len(h) != 0 {
+		t.Fatalf("headers = %v, want none", h)
+	}
+}
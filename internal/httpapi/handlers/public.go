@@ -6,9 +6,12 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"hermit/internal/auth"
+	"hermit/internal/ratelimit"
 	"hermit/internal/service"
+	"hermit/internal/store"
 
 	"github.com/labstack/echo/v4"
 )
@@ -40,12 +43,20 @@ func (h *Handler) Search(c echo.Context) error {
 
 	out := make([]map[string]any, 0, len(results))
 	for _, item := range results {
+		highlight := map[string]any{
+			"displayName": renderHighlight(item.DisplayNameHighlight),
+			"summary":     renderHighlight(item.SummaryHighlight),
+		}
+		if item.ContentSnippet != "" {
+			highlight["content"] = renderHighlight(item.ContentSnippet)
+		}
 		out = append(out, map[string]any{
 			"slug":        item.Slug,
 			"displayName": item.DisplayName,
 			"summary":     item.Summary,
 			"version":     item.Version,
 			"score":       item.Score,
+			"highlight":   highlight,
 			"updatedAt":   toMillisPtr(item.UpdatedAt),
 		})
 	}
@@ -82,28 +93,7 @@ func (h *Handler) ListSkills(c echo.Context) error {
 
 	respItems := make([]map[string]any, 0, len(items))
 	for _, item := range items {
-		payload := map[string]any{
-			"slug":        item.Slug,
-			"displayName": item.DisplayName,
-			"summary":     item.Summary,
-			"tags":        decodeAnyJSON(item.Tags, map[string]any{}),
-			"stats": map[string]any{
-				"downloads":       item.Downloads,
-				"stars":           item.Stars,
-				"installsCurrent": item.InstallsCurrent,
-				"installsAllTime": item.InstallsAllTime,
-			},
-			"createdAt": toMillis(item.CreatedAt),
-			"updatedAt": toMillis(item.UpdatedAt),
-		}
-		if item.LatestVersion != nil {
-			payload["latestVersion"] = map[string]any{
-				"version":   item.LatestVersion.Version,
-				"createdAt": toMillis(item.LatestVersion.CreatedAt),
-				"changelog": item.LatestVersion.Changelog,
-			}
-		}
-		respItems = append(respItems, payload)
+		respItems = append(respItems, skillListItemPayload(item))
 	}
 
 	var nextCursor any = nil
@@ -142,23 +132,21 @@ func (h *Handler) GetSkill(c echo.Context) error {
 			"displayName": view.Skill.DisplayName,
 			"summary":     view.Skill.Summary,
 			"tags":        decodeAnyJSON(view.Skill.Tags, map[string]any{}),
-			"stats": map[string]any{
-				"downloads":       view.Skill.Downloads,
-				"stars":           view.Skill.Stars,
-				"installsCurrent": view.Skill.InstallsCurrent,
-				"installsAllTime": view.Skill.InstallsAllTime,
-			},
-			"createdAt": toMillis(view.Skill.CreatedAt),
-			"updatedAt": toMillis(view.Skill.UpdatedAt),
+			"stats":       skillStatsPayload(view.Skill),
+			"starred":     view.Starred,
+			"deprecated":  view.Skill.Deprecation,
+			"createdAt":   toMillis(view.Skill.CreatedAt),
+			"updatedAt":   toMillis(view.Skill.UpdatedAt),
 		},
 		"latestVersion": nil,
 		"owner":         nil,
 	}
 	if view.LatestVersion != nil {
 		resp["latestVersion"] = map[string]any{
-			"version":   view.LatestVersion.Version,
-			"createdAt": toMillis(view.LatestVersion.CreatedAt),
-			"changelog": view.LatestVersion.Changelog,
+			"version":    view.LatestVersion.Version,
+			"createdAt":  toMillis(view.LatestVersion.CreatedAt),
+			"changelog":  view.LatestVersion.Changelog,
+			"deprecated": view.LatestVersion.Deprecation,
 		}
 	}
 	return c.JSON(http.StatusOK, resp)
@@ -192,12 +180,14 @@ func (h *Handler) ListVersions(c echo.Context) error {
 	}
 	items := make([]map[string]any, 0, len(versions))
 	for _, v := range versions {
-		items = append(items, map[string]any{
+		item := map[string]any{
 			"version":         v.Version,
 			"createdAt":       toMillis(v.CreatedAt),
 			"changelog":       v.Changelog,
 			"changelogSource": v.ChangelogSource,
-		})
+		}
+		addVersionNotice(item, service.VersionNotice{YankedAt: v.YankedAt, YankReason: v.YankReason, Deprecation: v.Deprecation})
+		items = append(items, item)
 	}
 	var nextCursor any = nil
 	if hasMore {
@@ -230,6 +220,11 @@ func (h *Handler) GetVersion(c echo.Context) error {
 			"changelog":       view.Version.Changelog,
 			"changelogSource": view.Version.ChangelogSource,
 			"files":           decodeAnyJSON(view.Version.Files, []any{}),
+			"manifest":        decodeAnyJSON(view.Version.Manifest, nil),
+			"yanked":          view.Version.YankedAt != nil,
+			"yankedAt":        toMillisPtr(view.Version.YankedAt),
+			"yankReason":      view.Version.YankReason,
+			"deprecated":      view.Version.Deprecation,
 		},
 		"skill": map[string]any{
 			"slug":        view.Skill.Slug,
@@ -238,6 +233,58 @@ func (h *Handler) GetVersion(c echo.Context) error {
 	})
 }
 
+func (h *Handler) DiffVersions(c echo.Context) error {
+	repo, err := h.svc.GetReadRepository(c.Request().Context())
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	actor := auth.GetActor(c)
+	slug := strings.TrimSpace(c.Param("slug"))
+	from := strings.TrimSpace(c.QueryParam("from"))
+	to := strings.TrimSpace(c.QueryParam("to"))
+	if from == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "from is required")
+	}
+
+	view, err := h.svc.DiffSkillVersions(c.Request().Context(), repo, actor, slug, from, to)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, view)
+}
+
+func (h *Handler) ListSkillTags(c echo.Context) error {
+	repo, err := h.svc.GetReadRepository(c.Request().Context())
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	actor := auth.GetActor(c)
+	slug := strings.TrimSpace(c.Param("slug"))
+	tags, err := h.svc.ListSkillTags(c.Request().Context(), repo, actor, slug)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"tags": tags})
+}
+
+func (h *Handler) GetSkillTag(c echo.Context) error {
+	repo, err := h.svc.GetReadRepository(c.Request().Context())
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	actor := auth.GetActor(c)
+	slug := strings.TrimSpace(c.Param("slug"))
+	tag := strings.TrimSpace(c.Param("tag"))
+	version, err := h.svc.GetSkillTag(c.Request().Context(), repo, actor, slug, tag)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"tag": tag, "version": version})
+}
+
 func (h *Handler) GetSkillFile(c echo.Context) error {
 	repo, err := h.svc.GetReadRepository(c.Request().Context())
 	if err != nil {
@@ -266,11 +313,12 @@ func (h *Handler) Resolve(c echo.Context) error {
 	actor := auth.GetActor(c)
 	slug := strings.TrimSpace(c.QueryParam("slug"))
 	hash := strings.TrimSpace(c.QueryParam("hash"))
+	versionRange := strings.TrimSpace(c.QueryParam("version"))
 	if slug == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "slug is required")
 	}
 
-	result, err := h.svc.ResolveSkillVersion(c.Request().Context(), repo, actor, slug, hash)
+	result, err := h.svc.ResolveSkillVersion(c.Request().Context(), repo, actor, slug, hash, versionRange)
 	if err != nil {
 		return mapServiceError(err)
 	}
@@ -278,12 +326,25 @@ func (h *Handler) Resolve(c echo.Context) error {
 	resp := map[string]any{
 		"match":         nil,
 		"latestVersion": nil,
+		"deprecated":    result.SkillDeprecation,
 	}
 	if result.MatchVersion != nil {
-		resp["match"] = map[string]any{"version": *result.MatchVersion}
+		match := map[string]any{"version": *result.MatchVersion}
+		addVersionNotice(match, result.Match)
+		resp["match"] = match
 	}
 	if result.LatestVersion != nil {
-		resp["latestVersion"] = map[string]any{"version": *result.LatestVersion}
+		latest := map[string]any{"version": *result.LatestVersion}
+		addVersionNotice(latest, result.Latest)
+		resp["latestVersion"] = latest
+	}
+	if versionRange != "" {
+		resp["resolved"] = nil
+		if result.RangeVersion != nil {
+			resolved := map[string]any{"version": *result.RangeVersion}
+			addVersionNotice(resolved, result.Range)
+			resp["resolved"] = resolved
+		}
 	}
 	return c.JSON(http.StatusOK, resp)
 }
@@ -317,5 +378,167 @@ func (h *Handler) Download(c echo.Context) error {
 	c.Response().Header().Set(echo.HeaderContentLength, strconv.FormatInt(file.Size(), 10))
 	c.Response().Header().Set("ETag", artifact.Digest)
 	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", artifact.FileName))
+	setNoticeHeaders(c.Response().Header(), artifact)
 	return c.Stream(http.StatusOK, "application/zip", file)
 }
+
+func (h *Handler) StarSkill(c echo.Context) error {
+	return h.setStar(c, true)
+}
+
+func (h *Handler) UnstarSkill(c echo.Context) error {
+	return h.setStar(c, false)
+}
+
+func (h *Handler) setStar(c echo.Context, starred bool) error {
+	actor := auth.GetActor(c)
+	if actor.Anonymous {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	repo, err := h.svc.GetReadRepository(c.Request().Context())
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	slug := strings.TrimSpace(c.Param("slug"))
+	view, err := h.svc.SetSkillStar(c.Request().Context(), repo, actor, slug, starred)
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{
+		"ok":            true,
+		"starred":       view.Starred,
+		"stars":         view.Stars,
+		"upstreamStars": view.UpstreamStars,
+	})
+}
+
+func (h *Handler) ListMyStars(c echo.Context) error {
+	actor := auth.GetActor(c)
+	if actor.Anonymous {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	repo, err := h.svc.GetReadRepository(c.Request().Context())
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	limit := clampInt(queryInt(c, "limit", 25), 1, 200)
+	offset, err := decodeCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
+	}
+
+	items, err := h.svc.ListStarredSkills(c.Request().Context(), repo, actor, limit+1, offset)
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+
+	respItems := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		payload := skillListItemPayload(item.SkillListItem)
+		payload["starredAt"] = toMillis(item.StarredAt)
+		respItems = append(respItems, payload)
+	}
+
+	var nextCursor any = nil
+	if hasMore {
+		nextCursor = encodeCursor(offset + limit)
+	}
+	return c.JSON(http.StatusOK, map[string]any{
+		"items":      respItems,
+		"nextCursor": nextCursor,
+	})
+}
+
+func (h *Handler) ReportInstall(c echo.Context) error {
+	return h.reportInstall(c, true)
+}
+
+func (h *Handler) ReportUninstall(c echo.Context) error {
+	return h.reportInstall(c, false)
+}
+
+func (h *Handler) reportInstall(c echo.Context, installed bool) error {
+	repo, err := h.svc.GetReadRepository(c.Request().Context())
+	if err != nil {
+		return mapServiceError(err)
+	}
+
+	var req struct {
+		ClientID string `json:"clientId"`
+		Version  string `json:"version"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if req.ClientID == "" {
+		req.ClientID = c.Request().Header.Get("X-Client-Id")
+	}
+
+	actor := auth.GetActor(c)
+	if actor.Anonymous {
+		result := h.installLimiter.Take(time.Now().UTC(), ratelimit.ScopeWrite, ratelimit.BucketIP, c.RealIP())
+		if !result.Allowed {
+			c.Response().Header().Set("Retry-After", strconv.FormatInt(result.ResetIn, 10))
+			return echo.NewHTTPError(http.StatusTooManyRequests, "too many install reports")
+		}
+	}
+	slug := strings.TrimSpace(c.Param("slug"))
+	report := service.InstallReport{ClientID: req.ClientID, Version: req.Version}
+	var view service.InstallView
+	if installed {
+		view, err = h.svc.ReportSkillInstall(c.Request().Context(), repo, actor, slug, report)
+	} else {
+		view, err = h.svc.ReportSkillUninstall(c.Request().Context(), repo, actor, slug, report)
+	}
+	if err != nil {
+		return mapServiceError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{
+		"ok":              true,
+		"installsCurrent": view.InstallsCurrent,
+		"installsAllTime": view.InstallsAllTime,
+	})
+}
+
+func skillListItemPayload(item store.SkillListItem) map[string]any {
+	payload := map[string]any{
+		"slug":        item.Slug,
+		"displayName": item.DisplayName,
+		"summary":     item.Summary,
+		"tags":        decodeAnyJSON(item.Tags, map[string]any{}),
+		"stats":       skillStatsPayload(item.Skill),
+		"createdAt":   toMillis(item.CreatedAt),
+		"updatedAt":   toMillis(item.UpdatedAt),
+	}
+	if item.Deprecation != nil {
+		payload["deprecated"] = *item.Deprecation
+	}
+	if item.LatestVersion != nil {
+		payload["latestVersion"] = map[string]any{
+			"version":    item.LatestVersion.Version,
+			"createdAt":  toMillis(item.LatestVersion.CreatedAt),
+			"changelog":  item.LatestVersion.Changelog,
+			"deprecated": item.LatestVersion.Deprecation,
+		}
+	}
+	return payload
+}
+
+// skillStatsPayload reports local stars and the upstream registry's stars
+// separately; "stars" stays the local count for ClawHub compatibility.
+func skillStatsPayload(skill store.Skill) map[string]any {
+	return map[string]any{
+		"downloads":       skill.Downloads,
+		"stars":           skill.Stars,
+		"upstreamStars":   skill.UpstreamStars,
+		"installsCurrent": skill.InstallsCurrent,
+		"installsAllTime": skill.InstallsAllTime,
+	}
+}
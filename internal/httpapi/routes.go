@@ -35,7 +35,12 @@ func (a *API) registerPublicV1Routes(v1 *echo.Group) {
 	// Auth endpoints (public, no token required)
 	v1.GET("/auth/providers", a.handler.AuthProviders)
 	v1.POST("/auth/login", a.handler.LocalLogin)
+	v1.POST("/auth/login/2fa", a.handler.TwoFactorLogin)
+	v1.POST("/auth/login/2fa/setup", a.handler.TwoFactorLoginSetup)
+	v1.POST("/auth/login/2fa/enable", a.handler.TwoFactorLoginEnable)
 	v1.POST("/auth/ldap", a.handler.LDAPLogin)
+	v1.GET("/auth/oidc/login", a.handler.OIDCLogin)
+	v1.GET("/auth/oidc/callback", a.handler.OIDCCallback)
 }
 
 func (a *API) registerAuthV1Routes(v1 *echo.Group) {
@@ -47,15 +52,55 @@ func (a *API) registerAuthV1Routes(v1 *echo.Group) {
 	v1Auth.GET("/skills/:slug/versions", a.handler.ListVersions)
 	v1Auth.GET("/skills/:slug/versions/:version", a.handler.GetVersion)
 	v1Auth.GET("/skills/:slug/file", a.handler.GetSkillFile)
+	v1Auth.GET("/skills/:slug/diff", a.handler.DiffVersions)
+	v1Auth.GET("/skills/:slug/tags", a.handler.ListSkillTags)
+	v1Auth.GET("/skills/:slug/tags/:tag", a.handler.GetSkillTag)
 	v1Auth.GET("/resolve", a.handler.Resolve)
 	v1Auth.GET("/download", a.handler.Download)
 	v1Auth.GET("/whoami", a.handler.Whoami)
 	v1Auth.POST("/skills", a.handler.PublishSkill)
 	v1Auth.DELETE("/skills/:slug", a.handler.DeleteSkill)
 	v1Auth.POST("/skills/:slug/undelete", a.handler.UndeleteSkill)
+	v1Auth.POST("/skills/:slug/star", a.handler.StarSkill)
+	v1Auth.DELETE("/skills/:slug/star", a.handler.UnstarSkill)
+	v1Auth.POST("/skills/:slug/install", a.handler.ReportInstall)
+	v1Auth.POST("/skills/:slug/uninstall", a.handler.ReportUninstall)
+	v1Auth.PUT("/skills/:slug/tags/:tag", a.handler.SetSkillTag)
+	v1Auth.DELETE("/skills/:slug/tags/:tag", a.handler.DeleteSkillTag)
+	v1Auth.POST("/skills/:slug/versions/:version/yank", a.handler.YankVersion)
+	v1Auth.DELETE("/skills/:slug/versions/:version/yank", a.handler.UnyankVersion)
+	v1Auth.PUT("/skills/:slug/deprecation", a.handler.SetSkillDeprecation)
+	v1Auth.DELETE("/skills/:slug/deprecation", a.handler.ClearSkillDeprecation)
+	v1Auth.PUT("/skills/:slug/versions/:version/deprecation", a.handler.SetSkillDeprecation)
+	v1Auth.DELETE("/skills/:slug/versions/:version/deprecation", a.handler.ClearSkillDeprecation)
+
+	// Repository-scoped writes into a specific hosted repository
+	v1Auth.POST("/repos/:repo/skills", a.handler.PublishSkill)
+	v1Auth.DELETE("/repos/:repo/skills/:slug", a.handler.DeleteSkill)
+	v1Auth.POST("/repos/:repo/skills/:slug/undelete", a.handler.UndeleteSkill)
+	v1Auth.PUT("/repos/:repo/skills/:slug/tags/:tag", a.handler.SetSkillTag)
+	v1Auth.DELETE("/repos/:repo/skills/:slug/tags/:tag", a.handler.DeleteSkillTag)
+	v1Auth.POST("/repos/:repo/skills/:slug/versions/:version/yank", a.handler.YankVersion)
+	v1Auth.DELETE("/repos/:repo/skills/:slug/versions/:version/yank", a.handler.UnyankVersion)
+	v1Auth.PUT("/repos/:repo/skills/:slug/deprecation", a.handler.SetSkillDeprecation)
+	v1Auth.DELETE("/repos/:repo/skills/:slug/deprecation", a.handler.ClearSkillDeprecation)
+	v1Auth.PUT("/repos/:repo/skills/:slug/versions/:version/deprecation", a.handler.SetSkillDeprecation)
+	v1Auth.DELETE("/repos/:repo/skills/:slug/versions/:version/deprecation", a.handler.ClearSkillDeprecation)
 
 	// Self-service account management
 	v1Auth.POST("/account/change-password", a.handler.ChangePassword)
+	v1Auth.GET("/account/2fa", a.handler.GetTwoFactorStatus)
+	v1Auth.POST("/account/2fa/setup", a.handler.BeginTwoFactorSetup)
+	v1Auth.POST("/account/2fa/enable", a.handler.EnableTwoFactor)
+	v1Auth.POST("/account/2fa/disable", a.handler.DisableTwoFactor)
+	v1Auth.POST("/account/2fa/recovery-codes", a.handler.RegenerateRecoveryCodes)
+	v1Auth.GET("/account/stars", a.handler.ListMyStars)
+
+	// Login sessions (user self-service)
+	v1Auth.POST("/auth/logout", a.handler.Logout)
+	v1Auth.GET("/sessions", a.handler.ListMySessions)
+	v1Auth.DELETE("/sessions", a.handler.RevokeOtherSessions)
+	v1Auth.DELETE("/sessions/:sessionId", a.handler.RevokeMySession)
 
 	// Personal Access Tokens (user self-service)
 	v1Auth.GET("/tokens", a.handler.ListMyTokens)
@@ -67,6 +112,7 @@ func (a *API) registerInternalRoutes(e *echo.Echo) {
 	internal := e.Group("/api/internal")
 	internal.Use(a.auth.Middleware)
 	internal.POST("/tokens", a.handler.AdminCreateToken)
+	internal.POST("/admin-token/rotate", a.handler.RotateAdminToken)
 
 	// Dashboard & sync
 	internal.GET("/stats", a.handler.GetDashboardStats)
@@ -74,11 +120,34 @@ func (a *API) registerInternalRoutes(e *echo.Echo) {
 	internal.POST("/sync-sources", a.handler.AddSyncSource)
 	internal.DELETE("/sync-sources/:id", a.handler.RemoveSyncSource)
 	internal.PATCH("/sync-sources/:id", a.handler.ToggleSyncSource)
+	internal.PUT("/sync-sources/:id/schedule", a.handler.SaveSyncSchedule)
+	internal.DELETE("/sync-sources/:id/schedule", a.handler.DeleteSyncSchedule)
 	internal.POST("/sync", a.handler.TriggerSync)
 	internal.GET("/sync/status", a.handler.GetSyncStatus)
+	internal.GET("/sync/runs", a.handler.ListSyncRuns)
+	internal.GET("/sync/runs/:id", a.handler.GetSyncRun)
+	internal.GET("/sync/runs/:id/items", a.handler.ListSyncRunItems)
 	internal.GET("/sync/config", a.handler.GetProxySyncConfig)
 	internal.PUT("/sync/config", a.handler.SaveProxySyncConfig)
 
+	// Repository lifecycle
+	internal.GET("/repositories", a.handler.ListRepositories)
+	internal.POST("/repositories", a.handler.CreateRepository)
+	internal.GET("/repositories/:id", a.handler.GetRepository)
+	internal.PATCH("/repositories/:id", a.handler.UpdateRepository)
+	internal.DELETE("/repositories/:id", a.handler.DeleteRepository)
+	internal.GET("/repositories/:id/members", a.handler.ListGroupMembers)
+	internal.POST("/repositories/:id/members", a.handler.AddGroupMember)
+	internal.PUT("/repositories/:id/members", a.handler.ReorderGroupMembers)
+	internal.PATCH("/repositories/:id/members/:memberId", a.handler.UpdateGroupMember)
+	internal.DELETE("/repositories/:id/members/:memberId", a.handler.RemoveGroupMember)
+	internal.DELETE("/repositories/:id/skills/:slug", a.handler.PurgeSkill)
+	internal.DELETE("/repositories/:id/skills/:slug/versions/:version", a.handler.PurgeSkillVersion)
+
+	// Blob storage
+	internal.POST("/blobs/gc", a.handler.RunBlobGC)
+	internal.POST("/blobs/scrub", a.handler.RunBlobScrub)
+
 	// RBAC management
 	internal.GET("/rbac/members", a.handler.ListAllMembers)
 	internal.GET("/rbac/repos/:id/members", a.handler.ListMembers)
@@ -91,6 +160,15 @@ func (a *API) registerInternalRoutes(e *echo.Echo) {
 	internal.PATCH("/users/:id", a.handler.UpdateUser)
 	internal.POST("/users/:id/reset-password", a.handler.ResetUserPassword)
 	internal.DELETE("/users/:id", a.handler.DeleteUserByID)
+	internal.POST("/users/:id/unlock", a.handler.UnlockUser)
+	internal.DELETE("/users/:id/2fa", a.handler.ResetUserTwoFactor)
+	internal.GET("/two-factor-policy", a.handler.GetTwoFactorPolicy)
+	internal.PUT("/two-factor-policy", a.handler.SetTwoFactorPolicy)
+
+	// Login attempts and lockouts (admin)
+	internal.GET("/login-attempts", a.handler.ListLoginAttempts)
+	internal.GET("/login-lockouts", a.handler.ListLoginLockouts)
+	internal.DELETE("/login-lockouts/:kind/:key", a.handler.ClearLoginLockout)
 
 	// Auth provider config (admin)
 	internal.GET("/auth-configs", a.handler.ListAuthConfigs)
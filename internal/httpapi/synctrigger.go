@@ -4,10 +4,14 @@ import (
 	"context"
 	"log"
 	"sync"
+	"time"
 
 	"hermit/internal/httpapi/handlers"
 	"hermit/internal/proxysync"
 	"hermit/internal/service"
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
 )
 
 type SyncTrigger struct {
@@ -32,10 +36,40 @@ func NewSyncTrigger(runner *proxysync.Runner, svc *service.Service, fallbackPage
 }
 
 func (st *SyncTrigger) TriggerSync(_ context.Context) (bool, error) {
+	return st.start("manual sync", service.SyncTriggerManual, st.runner.Run), nil
+}
+
+// TriggerRepositorySync starts a background sync of a single repository.
+// It is used by the scheduler and shares the running flag with manual syncs,
+// so a scheduled run never overlaps a manual one. The repository's sync lock
+// is held for the whole run, so only one replica runs each activation.
+func (st *SyncTrigger) TriggerRepositorySync(ctx context.Context, repo store.Repository) (bool, error) {
+	release := func() {}
+	if st.svc != nil {
+		unlock, ok, err := st.svc.TryLockScheduledSync(ctx, repo.ID)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, proxysync.ErrSyncedElsewhere
+		}
+		release = unlock
+	}
+	started := st.start("scheduled sync of "+repo.Name, service.SyncTriggerScheduled, func(ctx context.Context, pageSize int) (proxysync.Summary, error) {
+		defer release()
+		return st.runner.RunRepositories(ctx, []store.Repository{repo}, pageSize)
+	})
+	if !started {
+		release()
+	}
+	return started, nil
+}
+
+func (st *SyncTrigger) start(label, trigger string, run func(context.Context, int) (proxysync.Summary, error)) bool {
 	st.mu.Lock()
 	if st.running {
 		st.mu.Unlock()
-		return false, nil
+		return false
 	}
 	st.running = true
 	st.mu.Unlock()
@@ -48,25 +82,43 @@ func (st *SyncTrigger) TriggerSync(_ context.Context) (bool, error) {
 			}
 		}
 
-		summary, err := st.runner.Run(context.Background(), pageSize)
+		runID := uuid.Nil
+		if st.svc != nil {
+			id, err := st.svc.StartSyncRun(context.Background(), trigger)
+			if err != nil {
+				st.logger.Printf("%s: failed to record sync run: %v", label, err)
+			} else {
+				runID = id
+			}
+		}
+
+		stopHeartbeat := st.heartbeat(label, runID)
+		summary, err := run(context.Background(), pageSize)
+		stopHeartbeat()
+
+		if runID != uuid.Nil {
+			if recErr := st.svc.FinishSyncRun(context.Background(), runID, summary, err); recErr != nil {
+				st.logger.Printf("%s: failed to record sync run result: %v", label, recErr)
+			}
+		}
 
 		st.mu.Lock()
 		st.running = false
 		st.lastResult = &summary
 		if err != nil {
 			st.lastError = err
-			st.logger.Printf("manual sync failed: %v", err)
+			st.logger.Printf("%s failed: %v", label, err)
 		} else {
 			st.lastError = nil
 			st.logger.Printf(
-				"manual sync finished: repos=%d skills=%d versions=%d cached=%d failed=%d skipped=%d",
-				summary.Repositories, summary.Skills, summary.Versions, summary.Cached, summary.Failed, summary.Skipped,
+				"%s finished: repos=%d skills=%d versions=%d cached=%d failed=%d skipped=%d",
+				label, summary.Repositories, summary.Skills, summary.Versions, summary.Cached, summary.Failed, summary.Skipped,
 			)
 		}
 		st.mu.Unlock()
 	}()
 
-	return true, nil
+	return true
 }
 
 func (st *SyncTrigger) Status() handlers.SyncStatus {
@@ -83,3 +135,27 @@ func (st *SyncTrigger) Status() handlers.SyncStatus {
 		LastError:  errStr,
 	}
 }
+
+// heartbeat refreshes the heartbeat of runID until the returned func is
+// called, so replicas starting up do not mark the run interrupted.
+func (st *SyncTrigger) heartbeat(label string, runID uuid.UUID) (stop func()) {
+	if runID == uuid.Nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(service.SyncRunHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := st.svc.HeartbeatSyncRun(context.Background(), runID); err != nil {
+					st.logger.Printf("%s: failed to record sync run heartbeat: %v", label, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
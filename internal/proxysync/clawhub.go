@@ -70,10 +70,13 @@ type clawHubSyncer struct {
 
 type upstreamSkillsListResponse struct {
 	Items []struct {
-		Slug          string         `json:"slug"`
-		DisplayName   string         `json:"displayName"`
-		Summary       *string        `json:"summary"`
-		Tags          map[string]any `json:"tags"`
+		Slug        string         `json:"slug"`
+		DisplayName string         `json:"displayName"`
+		Summary     *string        `json:"summary"`
+		Tags        map[string]any `json:"tags"`
+		Stats       *struct {
+			Stars *int64 `json:"stars"`
+		} `json:"stats"`
 		LatestVersion *struct {
 			Version         string  `json:"version"`
 			CreatedAt       *int64  `json:"createdAt"`
@@ -142,6 +145,13 @@ func (s *clawHubSyncer) Sync(ctx context.Context, pageSize int) (RepoStats, erro
 			}
 			stats.Skills++
 
+			if statsCacher, ok := s.cache.(ProxySkillStatsCacher); ok && item.Stats != nil && item.Stats.Stars != nil {
+				// Star counts are informational; a failure here does not fail the skill.
+				if err := statsCacher.SyncProxySkillStats(ctx, s.repo, slug, *item.Stats.Stars); err != nil {
+					s.logger.Printf("[sync] [%s] skill %q: failed to sync upstream stars: %v", s.repo.Name, slug, err)
+				}
+			}
+
 			latest := syncVersion{}
 			if item.LatestVersion != nil {
 				latest = syncVersion{
@@ -155,6 +165,7 @@ func (s *clawHubSyncer) Sync(ctx context.Context, pageSize int) (RepoStats, erro
 			if hasVersionChecker && latest.version != "" && versionChecker.HasProxyVersion(ctx, s.repo, slug, latest.version) {
 				s.logger.Printf("[sync] [%s] skill %q: latest version %q already cached, skipping", s.repo.Name, slug, latest.version)
 				stats.Skipped++
+				outcome := SkillOutcome{Slug: slug, Outcome: SkillOutcomeSkipped}
 
 				if metaCacher, ok := s.cache.(ProxySkillMetaCacher); ok {
 					if err := metaCacher.SyncProxySkillMeta(
@@ -167,16 +178,27 @@ func (s *clawHubSyncer) Sync(ctx context.Context, pageSize int) (RepoStats, erro
 					); err != nil {
 						s.logger.Printf("[sync] [%s] skill %q: failed to sync skill metadata: %v", s.repo.Name, slug, err)
 						stats.Failed++
+						outcome.Outcome = SkillOutcomeFailed
+						outcome.Failed++
+						outcome.Error = fmt.Sprintf("sync skill metadata: %v", err)
 					}
 				}
+				stats.Outcomes = append(stats.Outcomes, outcome)
 				continue
 			}
 
+			outcome := SkillOutcome{Slug: slug, Outcome: SkillOutcomeSynced}
 			versions, err := s.fetchAllVersions(ctx, slug, pageSize)
 			if err != nil {
 				s.logger.Printf("[sync] [%s] skill %q: failed to fetch versions: %v", s.repo.Name, slug, err)
 				if latest.version == "" {
 					stats.Failed++
+					stats.Outcomes = append(stats.Outcomes, SkillOutcome{
+						Slug:    slug,
+						Outcome: SkillOutcomeFailed,
+						Failed:  1,
+						Error:   fmt.Sprintf("fetch versions: %v", err),
+					})
 					continue
 				}
 				s.logger.Printf("[sync] [%s] skill %q: falling back to latest version %q", s.repo.Name, slug, latest.version)
@@ -186,9 +208,15 @@ func (s *clawHubSyncer) Sync(ctx context.Context, pageSize int) (RepoStats, erro
 			versions = normalizeVersions(versions, latest)
 			s.logger.Printf("[sync] [%s] skill %q: syncing %d versions", s.repo.Name, slug, len(versions))
 			stats.Versions += len(versions)
-			cached, failed := s.syncVersions(ctx, slug, versions)
+			cached, failed, firstErr := s.syncVersions(ctx, slug, versions)
 			stats.Cached += cached
 			stats.Failed += failed
+			outcome.Versions = len(versions)
+			outcome.Cached = cached
+			outcome.Failed = failed
+			if firstErr != nil {
+				outcome.Error = firstErr.Error()
+			}
 
 			if failed > 0 {
 				s.logger.Printf("[sync] [%s] skill %q: cached=%d failed=%d", s.repo.Name, slug, cached, failed)
@@ -205,8 +233,16 @@ func (s *clawHubSyncer) Sync(ctx context.Context, pageSize int) (RepoStats, erro
 				); err != nil {
 					s.logger.Printf("[sync] [%s] skill %q: failed to sync skill metadata: %v", s.repo.Name, slug, err)
 					stats.Failed++
+					outcome.Failed++
+					if outcome.Error == "" {
+						outcome.Error = fmt.Sprintf("sync skill metadata: %v", err)
+					}
 				}
 			}
+			if outcome.Failed > 0 {
+				outcome.Outcome = SkillOutcomeFailed
+			}
+			stats.Outcomes = append(stats.Outcomes, outcome)
 		}
 
 		if page.NextCursor == nil || strings.TrimSpace(*page.NextCursor) == "" {
@@ -220,9 +256,11 @@ func (s *clawHubSyncer) Sync(ctx context.Context, pageSize int) (RepoStats, erro
 	return stats, nil
 }
 
-func (s *clawHubSyncer) syncVersions(ctx context.Context, slug string, versions []syncVersion) (cached int, failed int) {
+// syncVersions caches the given versions and returns the cached and failed
+// counts together with the first failure encountered.
+func (s *clawHubSyncer) syncVersions(ctx context.Context, slug string, versions []syncVersion) (cached int, failed int, firstErr error) {
 	if len(versions) == 0 {
-		return 0, 0
+		return 0, 0, nil
 	}
 	metaCacher, hasMetaCacher := s.cache.(ProxyVersionMetaCacher)
 
@@ -239,6 +277,9 @@ func (s *clawHubSyncer) syncVersions(ctx context.Context, slug string, versions
 			if err := s.cache.SyncProxyVersion(ctx, s.repo, slug, item.version); err != nil {
 				s.logger.Printf("[sync] [%s] skill %q version %q: cache failed: %v", s.repo.Name, slug, item.version, err)
 				failed++
+				if firstErr == nil {
+					firstErr = fmt.Errorf("version %s: %w", item.version, err)
+				}
 				continue
 			}
 			if hasMetaCacher {
@@ -253,17 +294,24 @@ func (s *clawHubSyncer) syncVersions(ctx context.Context, slug string, versions
 				); err != nil {
 					s.logger.Printf("[sync] [%s] skill %q version %q: version meta sync failed: %v", s.repo.Name, slug, item.version, err)
 					failed++
+					if firstErr == nil {
+						firstErr = fmt.Errorf("version %s meta: %w", item.version, err)
+					}
 				}
 			}
 			cached++
 		}
-		return cached, failed
+		return cached, failed, firstErr
 	}
 
 	jobs := make(chan syncVersion)
 	var cachedCount int64
 	var failedCount int64
 	var wg sync.WaitGroup
+	var errOnce sync.Once
+	recordErr := func(err error) {
+		errOnce.Do(func() { firstErr = err })
+	}
 
 	worker := func() {
 		defer wg.Done()
@@ -271,6 +319,7 @@ func (s *clawHubSyncer) syncVersions(ctx context.Context, slug string, versions
 			if err := s.cache.SyncProxyVersion(ctx, s.repo, slug, item.version); err != nil {
 				s.logger.Printf("[sync] [%s] skill %q version %q: cache failed: %v", s.repo.Name, slug, item.version, err)
 				atomic.AddInt64(&failedCount, 1)
+				recordErr(fmt.Errorf("version %s: %w", item.version, err))
 				continue
 			}
 			if hasMetaCacher {
@@ -285,6 +334,7 @@ func (s *clawHubSyncer) syncVersions(ctx context.Context, slug string, versions
 				); err != nil {
 					s.logger.Printf("[sync] [%s] skill %q version %q: version meta sync failed: %v", s.repo.Name, slug, item.version, err)
 					atomic.AddInt64(&failedCount, 1)
+					recordErr(fmt.Errorf("version %s meta: %w", item.version, err))
 				}
 			}
 			atomic.AddInt64(&cachedCount, 1)
@@ -302,7 +352,7 @@ func (s *clawHubSyncer) syncVersions(ctx context.Context, slug string, versions
 	close(jobs)
 	wg.Wait()
 
-	return int(cachedCount), int(failedCount)
+	return int(cachedCount), int(failedCount), firstErr
 }
 
 func (s *clawHubSyncer) fetchSkillsPage(ctx context.Context, limit int, cursor string) (upstreamSkillsListResponse, error) {
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -329,6 +330,16 @@ func TestClawHubSyncer_DedupAndFailureCount(t *testing.T) {
 	if stats.Skills != 1 || stats.Versions != 2 || stats.Cached != 1 || stats.Failed != 1 {
 		t.Fatalf("stats = %#v", stats)
 	}
+	if len(stats.Outcomes) != 1 {
+		t.Fatalf("outcomes = %#v, want 1 entry", stats.Outcomes)
+	}
+	outcome := stats.Outcomes[0]
+	if outcome.Slug != "delta" || outcome.Outcome != SkillOutcomeFailed || outcome.Versions != 2 || outcome.Cached != 1 || outcome.Failed != 1 {
+		t.Fatalf("outcome = %#v", outcome)
+	}
+	if !strings.Contains(outcome.Error, "2.0.0") {
+		t.Fatalf("outcome.Error = %q, want it to mention the failed version", outcome.Error)
+	}
 }
 
 func TestClawHubSyncer_PassesSkillMetadataToMetaCacher(t *testing.T) {
@@ -411,6 +422,87 @@ func TestClawHubSyncer_PassesSkillMetadataToMetaCacher(t *testing.T) {
 	}
 }
 
+type statsRecordCacher struct {
+	recordCacher
+	statsMu sync.Mutex
+	stars   map[string]int64
+}
+
+func (r *statsRecordCacher) SyncProxySkillStats(_ context.Context, _ store.Repository, slug string, stars int64) error {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+	if r.stars == nil {
+		r.stars = map[string]int64{}
+	}
+	r.stars[slug] = stars
+	return nil
+}
+
+func TestClawHubSyncer_PassesUpstreamStarsToStatsCacher(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/skills", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"items": []map[string]any{
+				{
+					"slug":          "alpha",
+					"stats":         map[string]any{"stars": 42, "downloads": 7},
+					"latestVersion": map[string]any{"version": "1.0.0"},
+				},
+				{
+					"slug":          "beta",
+					"latestVersion": map[string]any{"version": "1.0.0"},
+				},
+			},
+			"nextCursor": nil,
+		})
+	})
+	mux.HandleFunc("/api/v1/skills/alpha/versions", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"items":      []map[string]any{{"version": "1.0.0"}},
+			"nextCursor": nil,
+		})
+	})
+	mux.HandleFunc("/api/v1/skills/beta/versions", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"items":      []map[string]any{{"version": "1.0.0"}},
+			"nextCursor": nil,
+		})
+	})
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+	upstream := s.URL
+	repo := store.Repository{
+		Name:        "proxy",
+		Type:        store.RepoTypeProxy,
+		UpstreamURL: &upstream,
+		Enabled:     true,
+	}
+
+	cacher := &statsRecordCacher{}
+	factory := NewAbstractFactory(
+		FactoryDeps{
+			HTTPClient:    s.Client(),
+			VersionCacher: cacher,
+		},
+		NewClawHubBuilder(),
+	)
+	syncer, err := factory.NewRepoSyncer(repo)
+	if err != nil {
+		t.Fatalf("NewRepoSyncer() error = %v", err)
+	}
+	if _, err := syncer.Sync(context.Background(), 20); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	want := map[string]int64{"alpha": 42}
+	if !reflect.DeepEqual(cacher.stars, want) {
+		t.Fatalf("synced stars = %#v, want %#v", cacher.stars, want)
+	}
+}
+
 func TestClawHubSyncer_PassesVersionMetadataToMetaCacher(t *testing.T) {
 	t.Parallel()
 
@@ -851,6 +943,13 @@ func TestClawHubSyncer_SkipsSkillWhenLatestVersionCached(t *testing.T) {
 	if stats.Versions != 1 {
 		t.Fatalf("stats.Versions = %d, want 1", stats.Versions)
 	}
+	wantOutcomes := []SkillOutcome{
+		{Slug: "cached-skill", Outcome: SkillOutcomeSkipped},
+		{Slug: "new-skill", Outcome: SkillOutcomeSynced, Versions: 1, Cached: 1},
+	}
+	if !reflect.DeepEqual(stats.Outcomes, wantOutcomes) {
+		t.Fatalf("stats.Outcomes = %#v, want %#v", stats.Outcomes, wantOutcomes)
+	}
 
 	if versionsFetched.Load() != 1 {
 		t.Fatalf("versions endpoint fetched %d times, want 1 (cached-skill should be skipped)", versionsFetched.Load())
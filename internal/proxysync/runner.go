@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+
+	"hermit/internal/store"
 )
 
 type Runner struct {
@@ -44,6 +46,24 @@ func (r *Runner) Run(ctx context.Context, pageSize int) (Summary, error) {
 	}
 	r.logger.Printf("[sync] found %d proxy repositories", len(repos))
 
+	return r.runRepositories(ctx, repos, pageSize)
+}
+
+// RunRepositories syncs only the given proxy repositories, e.g. a single
+// sync source whose schedule became due.
+func (r *Runner) RunRepositories(ctx context.Context, repos []store.Repository, pageSize int) (Summary, error) {
+	if r.factory == nil {
+		return Summary{}, fmt.Errorf("syncer factory is nil")
+	}
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+
+	r.logger.Printf("[sync] starting sync run for %d repositories (pageSize=%d)", len(repos), pageSize)
+	return r.runRepositories(ctx, repos, pageSize)
+}
+
+func (r *Runner) runRepositories(ctx context.Context, repos []store.Repository, pageSize int) (Summary, error) {
 	var (
 		summary Summary
 		joined  error
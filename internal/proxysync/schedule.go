@@ -0,0 +1,220 @@
+package proxysync
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinScheduleInterval is the shortest interval accepted by "@every" specs.
+const MinScheduleInterval = time.Minute
+
+// Schedule computes the next activation time strictly after a given instant.
+type Schedule interface {
+	Next(time.Time) time.Time
+}
+
+// ParseSchedule parses a sync schedule spec. Supported forms are a standard
+// five-field cron expression ("minute hour day-of-month month day-of-week"),
+// the descriptors @hourly, @daily (@midnight), @weekly, @monthly and
+// @yearly (@annually), and "@every <duration>" for fixed intervals.
+// Cron expressions are evaluated in UTC.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty schedule")
+	}
+
+	if strings.HasPrefix(spec, "@every") {
+		raw := strings.TrimSpace(strings.TrimPrefix(spec, "@every"))
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval %q: %w", raw, err)
+		}
+		if d < MinScheduleInterval {
+			return nil, fmt.Errorf("interval %s is shorter than %s", d, MinScheduleInterval)
+		}
+		return intervalSchedule{every: d}, nil
+	}
+
+	switch strings.ToLower(spec) {
+	case "@yearly", "@annually":
+		spec = "0 0 1 1 *"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@hourly":
+		spec = "0 * * * *"
+	}
+	return parseCron(spec)
+}
+
+type intervalSchedule struct {
+	every time.Duration
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.every)
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day-of-month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "day-of-week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar/dowStar record whether the day fields were unrestricted, which
+	// decides whether a day must match both fields or either of them.
+	domStar, dowStar bool
+}
+
+func parseCron(spec string) (Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression must have %d fields, got %d", len(cronFields), len(parts))
+	}
+
+	var masks [5]uint64
+	for i, part := range parts {
+		mask, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		masks[i] = mask
+	}
+
+	// Sunday may be written as 7.
+	if masks[4]&(1<<7) != 0 {
+		masks[4] = (masks[4] | 1) &^ (1 << 7)
+	}
+
+	sched := cronSchedule{
+		minute:  masks[0],
+		hour:    masks[1],
+		dom:     masks[2],
+		month:   masks[3],
+		dow:     masks[4],
+		domStar: strings.HasPrefix(parts[2], "*") || parts[2] == "?",
+		dowStar: strings.HasPrefix(parts[4], "*") || parts[4] == "?",
+	}
+	if sched.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never fires", spec)
+	}
+	return sched, nil
+}
+
+func parseCronField(raw string, field cronField) (uint64, error) {
+	var mask uint64
+	for _, item := range strings.Split(raw, ",") {
+		if item == "" {
+			return 0, fmt.Errorf("invalid %s field %q", field.name, raw)
+		}
+
+		rangePart, step := item, 1
+		if idx := strings.IndexByte(item, '/'); idx >= 0 {
+			rangePart = item[:idx]
+			n, err := strconv.Atoi(item[idx+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", field.name, item)
+			}
+			step = n
+		}
+
+		lo, hi := field.min, field.max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = parseCronValue(bounds[0], field); err != nil {
+				return 0, err
+			}
+			if hi, err = parseCronValue(bounds[1], field); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range in %s field %q", field.name, item)
+			}
+		default:
+			v, err := parseCronValue(rangePart, field)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+func parseCronValue(raw string, field cronField) (int, error) {
+	if v, ok := field.names[strings.ToLower(raw)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < field.min || v > field.max {
+		return 0, fmt.Errorf("invalid %s value %q", field.name, raw)
+	}
+	return v, nil
+}
+
+func (s cronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+
+	// Any valid expression fires at least once within a leap-year cycle.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
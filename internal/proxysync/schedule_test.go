@@ -0,0 +1,78 @@
+package proxysync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule_Next(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2025, time.March, 14, 10, 17, 30, 0, time.UTC) // Friday
+	tests := []struct {
+		name string
+		spec string
+		want time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2025, 3, 14, 10, 18, 0, 0, time.UTC)},
+		{"hourly descriptor", "@hourly", time.Date(2025, 3, 14, 11, 0, 0, 0, time.UTC)},
+		{"daily descriptor", "@daily", time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"weekly descriptor", "@weekly", time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"monthly descriptor", "@monthly", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"yearly descriptor", "@yearly", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"step minutes", "*/15 * * * *", time.Date(2025, 3, 14, 10, 30, 0, 0, time.UTC)},
+		{"fixed time later today", "30 22 * * *", time.Date(2025, 3, 14, 22, 30, 0, 0, time.UTC)},
+		{"fixed time tomorrow", "0 3 * * *", time.Date(2025, 3, 15, 3, 0, 0, 0, time.UTC)},
+		{"weekdays range", "0 9 * * mon-fri", time.Date(2025, 3, 17, 9, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 2 * * 7", time.Date(2025, 3, 16, 2, 0, 0, 0, time.UTC)},
+		{"list of hours", "0 6,18 * * *", time.Date(2025, 3, 14, 18, 0, 0, 0, time.UTC)},
+		{"month name", "0 0 1 jun *", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"dom or dow when both restricted", "0 0 20 * sat", time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"interval", "@every 6h", base.Add(6 * time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sched, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q) error = %v", tt.spec, err)
+			}
+			if got := sched.Next(base); !got.Equal(tt.want) {
+				t.Fatalf("Next() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+		"0 0 30 2 *",
+		"@every",
+		"@every 10s",
+		"@every soon",
+	}
+	for _, spec := range tests {
+		spec := spec
+		t.Run(spec, func(t *testing.T) {
+			t.Parallel()
+			if _, err := ParseSchedule(spec); err == nil {
+				t.Fatalf("ParseSchedule(%q) expected error", spec)
+			}
+		})
+	}
+}
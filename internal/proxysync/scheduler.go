@@ -0,0 +1,140 @@
+package proxysync
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
+)
+
+const defaultSchedulerTick = 30 * time.Second
+
+// ScheduledRepository pairs a proxy repository with its parsed sync schedule.
+type ScheduledRepository struct {
+	Repository store.Repository
+	Spec       string
+	Schedule   Schedule
+}
+
+type ScheduleLister interface {
+	ListSyncSchedules(context.Context) ([]ScheduledRepository, error)
+}
+
+// RepositorySyncStarter starts a background sync of a single repository.
+// It reports false when the sync could not start because another run is
+// still in progress, and ErrSyncedElsewhere when another replica is
+// already running the scheduled sync.
+type RepositorySyncStarter interface {
+	TriggerRepositorySync(context.Context, store.Repository) (bool, error)
+}
+
+// ErrSyncedElsewhere reports that another replica runs the sync. The
+// scheduler counts the activation as done instead of retrying it.
+var ErrSyncedElsewhere = errors.New("sync is running on another replica")
+
+type scheduleEntry struct {
+	spec string
+	next time.Time
+}
+
+// Scheduler periodically checks the configured per-repository schedules and
+// starts a sync for every repository whose next activation time has passed.
+type Scheduler struct {
+	lister  ScheduleLister
+	starter RepositorySyncStarter
+	tick    time.Duration
+	logger  *log.Logger
+	nowFn   func() time.Time
+
+	entries map[uuid.UUID]scheduleEntry
+}
+
+func NewScheduler(lister ScheduleLister, starter RepositorySyncStarter, logger *log.Logger) *Scheduler {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Scheduler{
+		lister:  lister,
+		starter: starter,
+		tick:    defaultSchedulerTick,
+		logger:  logger,
+		nowFn:   func() time.Time { return time.Now().UTC() },
+		entries: make(map[uuid.UUID]scheduleEntry),
+	}
+}
+
+// Run blocks until ctx is cancelled, evaluating schedules on every tick.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.logger.Printf("[sync] scheduler started (tick=%s)", s.tick)
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	s.runDue(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Printf("[sync] scheduler stopped")
+			return
+		case <-ticker.C:
+			s.runDue(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) runDue(ctx context.Context) {
+	scheduled, err := s.lister.ListSyncSchedules(ctx)
+	if err != nil {
+		s.logger.Printf("[sync] scheduler: failed to list schedules: %v", err)
+		return
+	}
+
+	now := s.nowFn()
+	active := make(map[uuid.UUID]struct{}, len(scheduled))
+	for _, item := range scheduled {
+		id := item.Repository.ID
+		active[id] = struct{}{}
+
+		entry, ok := s.entries[id]
+		if !ok || entry.spec != item.Spec {
+			entry = scheduleEntry{spec: item.Spec, next: item.Schedule.Next(now)}
+			s.entries[id] = entry
+			s.logger.Printf("[sync] scheduler: repo %q scheduled %q, next run at %s",
+				item.Repository.Name, item.Spec, entry.next.Format(time.RFC3339))
+			continue
+		}
+		if entry.next.IsZero() || now.Before(entry.next) {
+			continue
+		}
+
+		started, err := s.starter.TriggerRepositorySync(ctx, item.Repository)
+		if errors.Is(err, ErrSyncedElsewhere) {
+			entry.next = item.Schedule.Next(now)
+			s.entries[id] = entry
+			s.logger.Printf("[sync] scheduler: repo %q is syncing on another replica, next run at %s",
+				item.Repository.Name, entry.next.Format(time.RFC3339))
+			continue
+		}
+		if err != nil {
+			s.logger.Printf("[sync] scheduler: repo %q: failed to start sync: %v", item.Repository.Name, err)
+			continue
+		}
+		if !started {
+			// Another sync is running; retry on the next tick.
+			continue
+		}
+		entry.next = item.Schedule.Next(now)
+		s.entries[id] = entry
+		s.logger.Printf("[sync] scheduler: repo %q sync started, next run at %s",
+			item.Repository.Name, entry.next.Format(time.RFC3339))
+	}
+
+	for id := range s.entries {
+		if _, ok := active[id]; !ok {
+			delete(s.entries, id)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package proxysync
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
+)
+
+type fakeScheduleLister struct {
+	items []ScheduledRepository
+}
+
+func (f *fakeScheduleLister) ListSyncSchedules(context.Context) ([]ScheduledRepository, error) {
+	return f.items, nil
+}
+
+type fakeSyncStarter struct {
+	busy      bool
+	elsewhere bool
+	started   []string
+}
+
+func (f *fakeSyncStarter) TriggerRepositorySync(_ context.Context, repo store.Repository) (bool, error) {
+	if f.elsewhere {
+		return false, ErrSyncedElsewhere
+	}
+	if f.busy {
+		return false, nil
+	}
+	f.started = append(f.started, repo.Name)
+	return true, nil
+}
+
+func TestScheduler_RunsDueRepositories(t *testing.T) {
+	t.Parallel()
+
+	hourly, err := ParseSchedule("@hourly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := store.Repository{ID: uuid.New(), Name: "proxy", Type: store.RepoTypeProxy}
+	lister := &fakeScheduleLister{items: []ScheduledRepository{{Repository: repo, Spec: "@hourly", Schedule: hourly}}}
+	starter := &fakeSyncStarter{}
+
+	now := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	s := NewScheduler(lister, starter, log.New(io.Discard, "", 0))
+	s.nowFn = func() time.Time { return now }
+
+	s.runDue(context.Background())
+	if len(starter.started) != 0 {
+		t.Fatalf("first evaluation should only compute next run, started %v", starter.started)
+	}
+
+	now = now.Add(20 * time.Minute)
+	s.runDue(context.Background())
+	if len(starter.started) != 0 {
+		t.Fatalf("not yet due, started %v", starter.started)
+	}
+
+	now = time.Date(2025, 1, 1, 11, 0, 5, 0, time.UTC)
+	starter.busy = true
+	s.runDue(context.Background())
+	if len(starter.started) != 0 {
+		t.Fatalf("busy starter should not record a run, started %v", starter.started)
+	}
+
+	starter.busy = false
+	s.runDue(context.Background())
+	if len(starter.started) != 1 || starter.started[0] != "proxy" {
+		t.Fatalf("started = %v, want [proxy]", starter.started)
+	}
+
+	s.runDue(context.Background())
+	if len(starter.started) != 1 {
+		t.Fatalf("should not rerun before next activation, started %v", starter.started)
+	}
+	if want := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC); !s.entries[repo.ID].next.Equal(want) {
+		t.Fatalf("next = %s, want %s", s.entries[repo.ID].next, want)
+	}
+}
+
+func TestScheduler_SkipsActivationSyncedElsewhere(t *testing.T) {
+	t.Parallel()
+
+	hourly, _ := ParseSchedule("@hourly")
+	repo := store.Repository{ID: uuid.New(), Name: "proxy", Type: store.RepoTypeProxy}
+	lister := &fakeScheduleLister{items: []ScheduledRepository{{Repository: repo, Spec: "@hourly", Schedule: hourly}}}
+	starter := &fakeSyncStarter{elsewhere: true}
+
+	now := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	s := NewScheduler(lister, starter, log.New(io.Discard, "", 0))
+	s.nowFn = func() time.Time { return now }
+	s.runDue(context.Background())
+
+	now = time.Date(2025, 1, 1, 11, 0, 5, 0, time.UTC)
+	s.runDue(context.Background())
+	if want := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC); !s.entries[repo.ID].next.Equal(want) {
+		t.Fatalf("next = %s, want %s", s.entries[repo.ID].next, want)
+	}
+
+	starter.elsewhere = false
+	s.runDue(context.Background())
+	if len(starter.started) != 0 {
+		t.Fatalf("started = %v, want the activation to count as done", starter.started)
+	}
+}
+
+func TestScheduler_ResetsOnSpecChangeAndDropsRemoved(t *testing.T) {
+	t.Parallel()
+
+	hourly, _ := ParseSchedule("@hourly")
+	daily, _ := ParseSchedule("@daily")
+	repo := store.Repository{ID: uuid.New(), Name: "proxy", Type: store.RepoTypeProxy}
+	lister := &fakeScheduleLister{items: []ScheduledRepository{{Repository: repo, Spec: "@hourly", Schedule: hourly}}}
+
+	now := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	s := NewScheduler(lister, &fakeSyncStarter{}, log.New(io.Discard, "", 0))
+	s.nowFn = func() time.Time { return now }
+	s.runDue(context.Background())
+
+	lister.items[0] = ScheduledRepository{Repository: repo, Spec: "@daily", Schedule: daily}
+	s.runDue(context.Background())
+	if want := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC); !s.entries[repo.ID].next.Equal(want) {
+		t.Fatalf("next = %s, want %s", s.entries[repo.ID].next, want)
+	}
+
+	lister.items = nil
+	s.runDue(context.Background())
+	if len(s.entries) != 0 {
+		t.Fatalf("entries = %v, want empty", s.entries)
+	}
+}
@@ -7,6 +7,12 @@ import (
 	"hermit/internal/store"
 )
 
+const (
+	SkillOutcomeSkipped = "skipped"
+	SkillOutcomeSynced  = "synced"
+	SkillOutcomeFailed  = "failed"
+)
+
 type RepoStats struct {
 	Repository string
 	Skills     int
@@ -14,6 +20,19 @@ type RepoStats struct {
 	Cached     int
 	Failed     int
 	Skipped    int
+	// Outcomes holds the per-skill decisions of the run. It is persisted in
+	// the sync history rather than returned with the status payload.
+	Outcomes []SkillOutcome `json:"-"`
+}
+
+// SkillOutcome records what a sync did with one upstream skill.
+type SkillOutcome struct {
+	Slug     string
+	Outcome  string
+	Versions int
+	Cached   int
+	Failed   int
+	Error    string
 }
 
 type Summary struct {
@@ -38,6 +57,12 @@ type ProxySkillMetaCacher interface {
 	SyncProxySkillMeta(context.Context, store.Repository, string, string, *string, map[string]string) error
 }
 
+// ProxySkillStatsCacher records upstream counters such as stars, which are
+// kept apart from the locally tracked ones.
+type ProxySkillStatsCacher interface {
+	SyncProxySkillStats(context.Context, store.Repository, string, int64) error
+}
+
 type ProxyVersionMetaCacher interface {
 	SyncProxyVersionMeta(context.Context, store.Repository, string, string, *time.Time, *string, *string) error
 }
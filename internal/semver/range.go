@@ -0,0 +1,278 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+type comparator struct {
+	op string // one of "=", ">", ">=", "<", "<="
+	v  Version
+}
+
+func (c comparator) matches(v Version) bool {
+	cmp := Compare(v, c.v)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	default:
+		return cmp == 0
+	}
+}
+
+// Range is a set of version constraints. Comparators separated by spaces
+// must all hold; alternatives are separated by "||".
+type Range struct {
+	raw  string
+	sets [][]comparator
+}
+
+// ParseRange parses ranges such as "^1.2", "~1.4.0", "1.x", ">=1.0.0 <2"
+// or "^1 || ^2.0.0-rc.1".
+func ParseRange(s string) (Range, error) {
+	r := Range{raw: strings.TrimSpace(s)}
+	for _, alt := range strings.Split(r.raw, "||") {
+		set, err := parseComparatorSet(alt)
+		if err != nil {
+			return Range{}, fmt.Errorf("range %q: %w", s, err)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// IsRange reports whether s is a version range rather than one exact
+// version.
+func IsRange(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" || IsValid(s) {
+		return false
+	}
+	_, err := ParseRange(s)
+	return err == nil
+}
+
+func (r Range) String() string {
+	return r.raw
+}
+
+// Match reports whether v satisfies the range. Like npm, a prerelease only
+// matches when a comparator in the same set names a prerelease of the same
+// MAJOR.MINOR.PATCH.
+func (r Range) Match(v Version) bool {
+	for _, set := range r.sets {
+		if setMatches(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Best returns the highest version in versions that satisfies r.
+func (r Range) Best(versions []string) (string, bool) {
+	var best *Version
+	var bestRaw string
+	for _, raw := range versions {
+		v, err := Parse(raw)
+		if err != nil || !r.Match(v) {
+			continue
+		}
+		if best == nil || Compare(v, *best) > 0 {
+			best, bestRaw = &v, raw
+		}
+	}
+	return bestRaw, best != nil
+}
+
+func setMatches(set []comparator, v Version) bool {
+	for _, c := range set {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	if !v.IsPrerelease() {
+		return true
+	}
+	for _, c := range set {
+		if c.v.IsPrerelease() && c.v.Major == v.Major && c.v.Minor == v.Minor && c.v.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func parseComparatorSet(s string) ([]comparator, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		// An empty alternative matches any release, like "*".
+		return []comparator{{op: ">=", v: Version{}}}, nil
+	}
+	var set []comparator
+	for i := 0; i < len(fields); i++ {
+		token := fields[i]
+		// Allow a space between the operator and the version: ">= 1.2".
+		if isOperator(token) && i+1 < len(fields) {
+			i++
+			token += fields[i]
+		}
+		cs, err := parseComparator(token)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, cs...)
+	}
+	return set, nil
+}
+
+func isOperator(s string) bool {
+	switch s {
+	case "^", "~", "=", ">", ">=", "<", "<=":
+		return true
+	}
+	return false
+}
+
+func parseComparator(token string) ([]comparator, error) {
+	var op string
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(token, prefix) {
+			op = prefix
+			token = token[len(prefix):]
+			break
+		}
+	}
+	p, err := parsePartial(token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "^":
+		return caretRange(p), nil
+	case "~":
+		return tildeRange(p), nil
+	case "", "=":
+		if p.parts == 3 {
+			return []comparator{{op: "=", v: p.v}}, nil
+		}
+		return xRange(p), nil
+	case ">":
+		if p.parts == 0 {
+			// Nothing is greater than every version.
+			return []comparator{{op: "<", v: Version{}}}, nil
+		}
+		if p.parts < 3 {
+			return []comparator{{op: ">=", v: p.bump()}}, nil
+		}
+		return []comparator{{op: ">", v: p.v}}, nil
+	case ">=":
+		return []comparator{{op: ">=", v: p.v}}, nil
+	case "<":
+		return []comparator{{op: "<", v: p.v}}, nil
+	case "<=":
+		if p.parts < 3 {
+			if p.parts == 0 {
+				return []comparator{{op: ">=", v: Version{}}}, nil
+			}
+			return []comparator{{op: "<", v: p.bump()}}, nil
+		}
+		return []comparator{{op: "<=", v: p.v}}, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %q", op)
+}
+
+// partial is a possibly incomplete version such as "1", "1.2" or "1.x".
+type partial struct {
+	v     Version
+	parts int // number of numeric parts given (0-3)
+}
+
+// bump returns the smallest version above every version matched by the
+// partial, e.g. "1.2" → 1.3.0.
+func (p partial) bump() Version {
+	switch p.parts {
+	case 1:
+		return Version{Major: p.v.Major + 1}
+	case 2:
+		return Version{Major: p.v.Major, Minor: p.v.Minor + 1}
+	default:
+		return p.v
+	}
+}
+
+func parsePartial(s string) (partial, error) {
+	s = strings.TrimPrefix(s, "v")
+	if s == "" || s == "*" || s == "x" || s == "X" {
+		return partial{}, nil
+	}
+	if IsValid(s) {
+		v, _ := Parse(s)
+		return partial{v: v, parts: 3}, nil
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return partial{}, fmt.Errorf("invalid version %q", s)
+	}
+	var p partial
+	nums := [3]*uint64{&p.v.Major, &p.v.Minor, &p.v.Patch}
+	for i, part := range parts {
+		if part == "*" || part == "x" || part == "X" {
+			break
+		}
+		n, err := parseNumeric(part)
+		if err != nil {
+			return partial{}, fmt.Errorf("invalid version %q", s)
+		}
+		*nums[i] = n
+		p.parts = i + 1
+	}
+	if p.parts == 3 {
+		return partial{}, fmt.Errorf("invalid version %q", s)
+	}
+	return p, nil
+}
+
+func xRange(p partial) []comparator {
+	if p.parts == 0 {
+		return []comparator{{op: ">=", v: Version{}}}
+	}
+	return []comparator{{op: ">=", v: p.v}, {op: "<", v: p.bump()}}
+}
+
+// caretRange allows changes that do not modify the left-most non-zero part:
+// ^1.2.3 := >=1.2.3 <2.0.0, ^0.2.3 := >=0.2.3 <0.3.0, ^0.0.3 := >=0.0.3 <0.0.4.
+func caretRange(p partial) []comparator {
+	if p.parts == 0 {
+		return xRange(p)
+	}
+	var upper Version
+	switch {
+	case p.v.Major > 0 || p.parts == 1:
+		upper = Version{Major: p.v.Major + 1}
+	case p.v.Minor > 0 || p.parts == 2:
+		upper = Version{Minor: p.v.Minor + 1}
+	default:
+		upper = Version{Patch: p.v.Patch + 1}
+	}
+	return []comparator{{op: ">=", v: p.v}, {op: "<", v: upper}}
+}
+
+// tildeRange allows patch-level changes when a minor version is given:
+// ~1.2.3 := >=1.2.3 <1.3.0, ~1 := >=1.0.0 <2.0.0.
+func tildeRange(p partial) []comparator {
+	if p.parts == 0 {
+		return xRange(p)
+	}
+	upper := Version{Major: p.v.Major + 1}
+	if p.parts > 1 {
+		upper = Version{Major: p.v.Major, Minor: p.v.Minor + 1}
+	}
+	return []comparator{{op: ">=", v: p.v}, {op: "<", v: upper}}
+}
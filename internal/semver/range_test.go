@@ -0,0 +1,103 @@
+package semver
+
+import "testing"
+
+func TestRangeMatch(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		rng   string
+		match []string
+		miss  []string
+	}{
+		{"^1.2", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0", "1.5.0-beta"}},
+		{"^1.2.3", []string{"1.2.3", "1.99.0"}, []string{"1.2.2", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0", "0.2.2"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"^0", []string{"0.0.1", "0.9.0"}, []string{"1.0.0"}},
+		{"~1.4.0", []string{"1.4.0", "1.4.7"}, []string{"1.5.0", "1.3.9"}},
+		{"~1.4", []string{"1.4.0", "1.4.7"}, []string{"1.5.0"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{"1.x", []string{"1.0.0", "1.9.0"}, []string{"2.0.0", "0.9.0"}},
+		{"1.2", []string{"1.2.0", "1.2.9"}, []string{"1.3.0"}},
+		{"*", []string{"0.0.0", "9.9.9"}, []string{"1.0.0-rc.1"}},
+		{"1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{">=1.0.0 <2", []string{"1.0.0", "1.9.9"}, []string{"2.0.0", "0.9.0"}},
+		{">= 1.2 < 1.4", []string{"1.2.0", "1.3.5"}, []string{"1.4.0"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+		{"^1 || ^3", []string{"1.5.0", "3.0.0"}, []string{"2.0.0"}},
+		{"^2.0.0-rc.1", []string{"2.0.0-rc.1", "2.0.0-rc.2", "2.0.0", "2.1.0"}, []string{"2.0.0-beta", "2.1.0-rc.1"}},
+		{"v1.2.x", []string{"1.2.3"}, []string{"1.3.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rng, func(t *testing.T) {
+			t.Parallel()
+			r, err := ParseRange(tt.rng)
+			if err != nil {
+				t.Fatalf("ParseRange(%q) error = %v", tt.rng, err)
+			}
+			for _, s := range tt.match {
+				if v, _ := Parse(s); !r.Match(v) {
+					t.Fatalf("%q.Match(%s) = false, want true", tt.rng, s)
+				}
+			}
+			for _, s := range tt.miss {
+				if v, _ := Parse(s); r.Match(v) {
+					t.Fatalf("%q.Match(%s) = true, want false", tt.rng, s)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRange_Invalid(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{"latest", "^1.2.3.4", ">=abc", "1.0.0 - 2.0.0", "^01.2"} {
+		if _, err := ParseRange(s); err == nil {
+			t.Fatalf("ParseRange(%q) error = nil, want error", s)
+		}
+	}
+}
+
+func TestIsRange(t *testing.T) {
+	t.Parallel()
+	tests := map[string]bool{
+		"1.2.3":   false,
+		"":        false,
+		"nightly": false,
+		"^1.2":    true,
+		"~1.4.0":  true,
+		"1.x":     true,
+		"1.2":     true,
+	}
+	for in, want := range tests {
+		if got := IsRange(in); got != want {
+			t.Fatalf("IsRange(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestRangeBest(t *testing.T) {
+	t.Parallel()
+	versions := []string{"1.2.0", "1.4.2", "1.4.10", "2.0.0", "1.5.0-rc.1", "nightly"}
+	tests := []struct {
+		rng    string
+		want   string
+		wantOK bool
+	}{
+		{"^1.2", "1.4.10", true},
+		{"~1.4.0", "1.4.10", true},
+		{"^2", "2.0.0", true},
+		{"^3", "", false},
+	}
+	for _, tt := range tests {
+		r, err := ParseRange(tt.rng)
+		if err != nil {
+			t.Fatalf("ParseRange(%q) error = %v", tt.rng, err)
+		}
+		got, ok := r.Best(versions)
+		if got != tt.want || ok != tt.wantOK {
+			t.Fatalf("%q.Best() = %q, %v, want %q, %v", tt.rng, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
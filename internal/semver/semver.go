@@ -0,0 +1,232 @@
+// Package semver parses Semantic Versioning 2.0.0 versions and npm-style
+// version ranges (^, ~, x-ranges and comparators).
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version.
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+	Build      string
+}
+
+// Parse parses a full MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD] version.
+func Parse(s string) (Version, error) {
+	var v Version
+	rest := s
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		v.Build = rest[i+1:]
+		rest = rest[:i]
+		if !validIdentifiers(v.Build, false) {
+			return Version{}, fmt.Errorf("invalid build metadata in %q", s)
+		}
+	}
+	if i := strings.IndexByte(rest, '-'); i >= 0 {
+		pre := rest[i+1:]
+		rest = rest[:i]
+		if !validIdentifiers(pre, true) {
+			return Version{}, fmt.Errorf("invalid prerelease in %q", s)
+		}
+		v.Prerelease = strings.Split(pre, ".")
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("version %q must have the form MAJOR.MINOR.PATCH", s)
+	}
+	nums := [3]*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := parseNumeric(p)
+		if err != nil {
+			return Version{}, fmt.Errorf("version %q: %w", s, err)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// IsValid reports whether s is a full semantic version.
+func IsValid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// IsPrerelease reports whether v carries a prerelease suffix.
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Compare returns -1, 0 or 1 following semver precedence. Build metadata
+// is ignored.
+func Compare(a, b Version) int {
+	if c := cmpUint(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := cmpUint(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := cmpUint(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case len(a.Prerelease) == 0 && len(b.Prerelease) == 0:
+		return 0
+	case len(a.Prerelease) == 0:
+		return 1
+	case len(b.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(a.Prerelease) && i < len(b.Prerelease); i++ {
+		if c := compareIdentifier(a.Prerelease[i], b.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return cmpInt(len(a.Prerelease), len(b.Prerelease))
+}
+
+// Latest returns the highest version in versions. Prereleases are only
+// considered when no stable version exists. Strings that are not valid
+// semantic versions are ignored.
+func Latest(versions []string) (string, bool) {
+	var best, bestPre *Version
+	var bestRaw, bestPreRaw string
+	for _, raw := range versions {
+		v, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		if v.IsPrerelease() {
+			if bestPre == nil || Compare(v, *bestPre) > 0 {
+				bestPre, bestPreRaw = &v, raw
+			}
+			continue
+		}
+		if best == nil || Compare(v, *best) > 0 {
+			best, bestRaw = &v, raw
+		}
+	}
+	if best != nil {
+		return bestRaw, true
+	}
+	if bestPre != nil {
+		return bestPreRaw, true
+	}
+	return "", false
+}
+
+// SortKey encodes version so that byte-wise ("C" collation) ordering of keys
+// matches semver precedence. ok is false when version is not a semantic
+// version.
+func SortKey(version string) (key string, prerelease bool, ok bool) {
+	v, err := Parse(version)
+	if err != nil {
+		return "", false, false
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%020d.%020d.%020d", v.Major, v.Minor, v.Patch)
+	if !v.IsPrerelease() {
+		// '~' sorts after '!', so a release follows all of its prereleases.
+		b.WriteByte('~')
+		return b.String(), false, true
+	}
+	for _, id := range v.Prerelease {
+		// '!' sorts below every identifier character, so a shorter set of
+		// identifiers precedes a longer one with the same prefix.
+		b.WriteByte('!')
+		if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+			fmt.Fprintf(&b, "0%020d", n)
+		} else {
+			b.WriteByte('1')
+			b.WriteString(id)
+		}
+	}
+	return b.String(), true, true
+}
+
+func parseNumeric(s string) (uint64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty numeric part")
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("numeric part %q has a leading zero", s)
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("numeric part %q is not a number", s)
+		}
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func validIdentifiers(s string, prerelease bool) bool {
+	if s == "" {
+		return false
+	}
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		numeric := true
+		for _, r := range id {
+			switch {
+			case r >= '0' && r <= '9':
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '-':
+				numeric = false
+			default:
+				return false
+			}
+		}
+		if prerelease && numeric && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
+
+func compareIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return cmpUint(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func cmpUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func cmpInt(a, b int) int {
+	return cmpUint(uint64(a), uint64(b))
+}
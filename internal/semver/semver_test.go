@@ -0,0 +1,111 @@
+package semver
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	valid := []string{"0.0.0", "1.2.3", "10.20.30", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-0.3.7", "1.0.0-x-y.z", "1.0.0+build.1", "1.0.0-rc.1+sha.abc"}
+	for _, s := range valid {
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", s, err)
+		}
+		if v.String() != s {
+			t.Fatalf("Parse(%q).String() = %q", s, v.String())
+		}
+	}
+	invalid := []string{"", "1", "1.2", "v1.2.3", "01.2.3", "1.2.3-", "1.2.3-01", "1.2.3+", "1.2.3-a..b", "1.2.3.4", "latest", "1.2.x"}
+	for _, s := range invalid {
+		if _, err := Parse(s); err == nil {
+			t.Fatalf("Parse(%q) error = nil, want error", s)
+		}
+	}
+}
+
+// precedence lists versions in ascending order (semver spec §11 plus extras).
+var precedence = []string{
+	"0.9.9",
+	"1.0.0-0",
+	"1.0.0-alpha",
+	"1.0.0-alpha.1",
+	"1.0.0-alpha.beta",
+	"1.0.0-beta",
+	"1.0.0-beta.2",
+	"1.0.0-beta.11",
+	"1.0.0-rc.1",
+	"1.0.0",
+	"1.0.1",
+	"1.2.5",
+	"1.10.0",
+	"2.0.0",
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+	for i := 0; i+1 < len(precedence); i++ {
+		a, _ := Parse(precedence[i])
+		b, _ := Parse(precedence[i+1])
+		if Compare(a, b) != -1 || Compare(b, a) != 1 {
+			t.Fatalf("Compare(%s, %s) = %d, want -1", a, b, Compare(a, b))
+		}
+	}
+	a, _ := Parse("1.0.0+a")
+	b, _ := Parse("1.0.0+b")
+	if Compare(a, b) != 0 {
+		t.Fatalf("Compare() must ignore build metadata")
+	}
+}
+
+func TestSortKey(t *testing.T) {
+	t.Parallel()
+	keys := make([]string, len(precedence))
+	byKey := make(map[string]string)
+	for i, s := range precedence {
+		key, pre, ok := SortKey(s)
+		if !ok {
+			t.Fatalf("SortKey(%q) ok = false", s)
+		}
+		if want, _ := Parse(s); pre != want.IsPrerelease() {
+			t.Fatalf("SortKey(%q) prerelease = %v", s, pre)
+		}
+		keys[i] = key
+		byKey[key] = s
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if byKey[key] != precedence[i] {
+			t.Fatalf("sorted keys[%d] = %s, want %s", i, byKey[key], precedence[i])
+		}
+	}
+	if _, _, ok := SortKey("nightly"); ok {
+		t.Fatalf("SortKey(nightly) ok = true")
+	}
+}
+
+func TestLatest(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		versions []string
+		want     string
+		wantOK   bool
+	}{
+		{"backport after major", []string{"1.0.0", "2.0.0", "1.2.5"}, "2.0.0", true},
+		{"prerelease skipped", []string{"1.0.0", "2.0.0-rc.1"}, "1.0.0", true},
+		{"only prereleases", []string{"2.0.0-beta", "2.0.0-rc.1"}, "2.0.0-rc.1", true},
+		{"free text ignored", []string{"nightly", "0.1.0"}, "0.1.0", true},
+		{"none", []string{"nightly"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := Latest(tt.versions)
+			if got != tt.want || ok != tt.wantOK {
+				t.Fatalf("Latest(%v) = %q, %v, want %q, %v", tt.versions, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -66,11 +66,12 @@ func (s *Service) GetDashboardStats(ctx context.Context) (DashboardStats, error)
 }
 
 type SyncSourceView struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	UpstreamURL string `json:"upstreamUrl"`
-	Enabled     bool   `json:"enabled"`
-	SkillCount  int64  `json:"skillCount"`
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	UpstreamURL string        `json:"upstreamUrl"`
+	Enabled     bool          `json:"enabled"`
+	SkillCount  int64         `json:"skillCount"`
+	Schedule    *SyncSchedule `json:"schedule"`
 }
 
 func (s *Service) ListSyncSources(ctx context.Context) ([]SyncSourceView, error) {
@@ -78,6 +79,10 @@ func (s *Service) ListSyncSources(ctx context.Context) ([]SyncSourceView, error)
 	if err != nil {
 		return nil, err
 	}
+	schedules, err := s.GetSyncSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	var sources []SyncSourceView
 	for _, rs := range repoStats {
@@ -88,13 +93,17 @@ func (s *Service) ListSyncSources(ctx context.Context) ([]SyncSourceView, error)
 		if rs.Repository.UpstreamURL != nil {
 			upstream = *rs.Repository.UpstreamURL
 		}
-		sources = append(sources, SyncSourceView{
+		view := SyncSourceView{
 			ID:          rs.Repository.ID.String(),
 			Name:        rs.Repository.Name,
 			UpstreamURL: upstream,
 			Enabled:     rs.Repository.Enabled,
 			SkillCount:  rs.SkillCount,
-		})
+		}
+		if sched, ok := schedules[view.ID]; ok {
+			view.Schedule = &sched
+		}
+		sources = append(sources, view)
 	}
 	return sources, nil
 }
@@ -146,7 +155,7 @@ func (s *Service) RemoveSyncSource(ctx context.Context, id string) error {
 		}
 		return err
 	}
-	return nil
+	return s.DeleteSyncSchedule(ctx, id)
 }
 
 func (s *Service) ToggleSyncSource(ctx context.Context, id string, enabled bool) error {
@@ -204,6 +213,7 @@ type RepoMemberView struct {
 	RepoName string `json:"repoName"`
 	Subject  string `json:"subject"`
 	Role     string `json:"role"`
+	Source   string `json:"source"`
 }
 
 func (s *Service) AssignRepoRole(ctx context.Context, repoID string, subject string, role string) error {
@@ -258,6 +268,7 @@ func (s *Service) ListRepoMembers(ctx context.Context, repoID string) ([]RepoMem
 			RepoName: m.RepoName,
 			Subject:  m.Subject,
 			Role:     mapRoleFromDB(m.Role),
+			Source:   m.Source,
 		})
 	}
 	return views, nil
@@ -276,6 +287,7 @@ func (s *Service) ListAllRepoMembers(ctx context.Context) ([]RepoMemberView, err
 			RepoName: m.RepoName,
 			Subject:  m.Subject,
 			Role:     mapRoleFromDB(m.Role),
+			Source:   m.Source,
 		})
 	}
 	return views, nil
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"hermit/internal/store"
+)
+
+const (
+	configKeyAdminBootstrap = "admin_bootstrap"
+	// systemSubjectPrefix marks subjects that belong to no user; local
+	// usernames may not start with it.
+	systemSubjectPrefix = "system:"
+	// adminTokenSubject is the subject of the bootstrap and admin tokens.
+	// It is not a username, so the tokens never show up among, or can be
+	// revoked as, a user's own tokens.
+	adminTokenSubject = systemSubjectPrefix + "admin"
+)
+
+// AdminBootstrapState records when the bootstrap token was first stored and
+// whether it has been exchanged, after which ADMIN_TOKEN is ignored.
+type AdminBootstrapState struct {
+	IssuedAt   *time.Time `json:"issued_at,omitempty"`
+	ConsumedAt *time.Time `json:"consumed_at,omitempty"`
+	ConsumedBy string     `json:"consumed_by,omitempty"`
+}
+
+func (s *Service) putAdminBootstrapState(ctx context.Context, state AdminBootstrapState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.store.UpsertSystemConfig(ctx, configKeyAdminBootstrap, raw)
+}
+
+func (s *Service) getAdminBootstrapState(ctx context.Context) (AdminBootstrapState, error) {
+	raw, err := s.store.GetSystemConfig(ctx, configKeyAdminBootstrap)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return AdminBootstrapState{}, nil
+		}
+		return AdminBootstrapState{}, err
+	}
+	var state AdminBootstrapState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return AdminBootstrapState{}, fmt.Errorf("decode admin bootstrap state: %w", err)
+	}
+	return state, nil
+}
+
+// BootstrapAdminToken stores a hash of envToken as a one-time admin
+// credential that expires ttl after it was first stored, unless a bootstrap
+// token has already been exchanged. Restarts do not extend it. It returns
+// when the token expires, or nil when it is not usable.
+func (s *Service) BootstrapAdminToken(ctx context.Context, envToken string, ttl time.Duration) (*time.Time, error) {
+	envToken = strings.TrimSpace(envToken)
+	if envToken == "" {
+		return nil, nil
+	}
+	state, err := s.getAdminBootstrapState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if state.ConsumedAt != nil {
+		return nil, nil
+	}
+	now := time.Now().UTC()
+	if state.IssuedAt == nil {
+		state.IssuedAt = &now
+		if err := s.putAdminBootstrapState(ctx, state); err != nil {
+			return nil, err
+		}
+	}
+	expiresAt := state.IssuedAt.Add(ttl)
+	if !now.Before(expiresAt) {
+		return nil, nil
+	}
+	sum := sha256.Sum256([]byte(envToken))
+	if err := s.store.ReplaceBootstrapToken(ctx, adminTokenSubject, hex.EncodeToString(sum[:]), expiresAt); err != nil {
+		return nil, err
+	}
+	return &expiresAt, nil
+}
+
+// RotateAdminToken issues a new admin token. The previous admin token and
+// any bootstrap token stop working, and ADMIN_TOKEN is ignored from then on
+// in bootstrap mode. actor is recorded as who consumed the bootstrap.
+func (s *Service) RotateAdminToken(ctx context.Context, actor string) (string, store.APIToken, error) {
+	rawToken, err := generateToken(32)
+	if err != nil {
+		return "", store.APIToken{}, err
+	}
+	sum := sha256.Sum256([]byte(rawToken))
+
+	// The bootstrap is marked consumed first: if rotation then fails, the
+	// bootstrap token keeps working until the next attempt instead of being
+	// recreated on restart.
+	state, err := s.getAdminBootstrapState(ctx)
+	if err != nil {
+		return "", store.APIToken{}, err
+	}
+	if state.ConsumedAt == nil {
+		now := time.Now().UTC()
+		state.ConsumedAt = &now
+		state.ConsumedBy = strings.TrimSpace(actor)
+		if err := s.putAdminBootstrapState(ctx, state); err != nil {
+			return "", store.APIToken{}, err
+		}
+	}
+
+	tok, err := s.store.RotateAdminToken(ctx, adminTokenSubject, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return "", store.APIToken{}, err
+	}
+	return rawToken, tok, nil
+}
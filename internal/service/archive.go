@@ -0,0 +1,201 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"hermit/internal/store"
+)
+
+const (
+	// maxArchiveFiles bounds the number of files in an uploaded archive.
+	maxArchiveFiles = 10000
+	// maxArchiveExpandedBytes bounds the total uncompressed size of an
+	// uploaded archive.
+	maxArchiveExpandedBytes = 1 << 30
+	// maxArchiveCompressionRatio bounds how much an archive may expand
+	// relative to its own size once it is past minArchiveRatioBytes, which
+	// lets small, highly compressible text bundles through.
+	maxArchiveCompressionRatio = 100
+	minArchiveRatioBytes       = 8 << 20
+)
+
+// PublishSkillArchive publishes a version from a zip or tar.gz archive. The
+// archive is unpacked to a temp directory with the same path rules as
+// individual files, then repacked into the canonical zip, so the stored
+// artifact does not depend on how the bundle was built.
+func (s *Service) PublishSkillArchive(
+	ctx context.Context,
+	repo store.Repository,
+	payload PublishPayload,
+	archive io.ReaderAt,
+	size int64,
+	actor string,
+) (PublishResult, error) {
+	dir, err := os.MkdirTemp("", "hermit-publish-*")
+	if err != nil {
+		return PublishResult{}, fmt.Errorf("create spool dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := extractArchive(archive, size, dir)
+	if err != nil {
+		return PublishResult{}, err
+	}
+	return s.PublishSkill(ctx, repo, payload, files, actor)
+}
+
+// extractArchive unpacks a zip or tar.gz archive into dir and returns its
+// regular files. Entries are spooled under generated names, never under
+// their archive paths.
+func extractArchive(r io.ReaderAt, size int64, dir string) ([]PublishFileInput, error) {
+	var magic [4]byte
+	n, err := r.ReadAt(magic[:], 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	x := &archiveExtractor{
+		dir:    dir,
+		budget: archiveExpansionLimit(size),
+		seen:   map[string]bool{},
+	}
+	switch {
+	case n >= 4 && (bytes.Equal(magic[:], []byte("PK\x03\x04")) || bytes.Equal(magic[:], []byte("PK\x05\x06"))):
+		err = x.extractZip(r, size)
+	case n >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		err = x.extractTarGz(io.NewSectionReader(r, 0, size))
+	default:
+		return nil, fmt.Errorf("%w: archive must be a zip or tar.gz", ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(x.files) == 0 {
+		return nil, fmt.Errorf("%w: archive contains no files", ErrInvalidInput)
+	}
+	return x.files, nil
+}
+
+func archiveExpansionLimit(size int64) int64 {
+	limit := max(size*maxArchiveCompressionRatio, minArchiveRatioBytes)
+	return min(limit, maxArchiveExpandedBytes)
+}
+
+type archiveExtractor struct {
+	dir    string
+	budget int64
+	seen   map[string]bool
+	files  []PublishFileInput
+}
+
+func (x *archiveExtractor) extractZip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: invalid zip archive: %v", ErrInvalidInput, err)
+	}
+	for _, zf := range zr.File {
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir() || strings.HasSuffix(zf.Name, "/"):
+			continue
+		case mode&fs.ModeSymlink != 0:
+			return fmt.Errorf("%w: archive entry %q is a symlink", ErrInvalidInput, zf.Name)
+		case !mode.IsRegular():
+			return fmt.Errorf("%w: archive entry %q is not a regular file", ErrInvalidInput, zf.Name)
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return fmt.Errorf("%w: archive entry %q: %v", ErrInvalidInput, zf.Name, err)
+		}
+		err = x.add(zf.Name, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *archiveExtractor) extractTarGz(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: invalid gzip stream: %v", ErrInvalidInput, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: invalid tar archive: %v", ErrInvalidInput, err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			if err := x.add(hdr.Name, tr); err != nil {
+				return err
+			}
+		case tar.TypeDir, tar.TypeXGlobalHeader:
+			continue
+		case tar.TypeSymlink, tar.TypeLink:
+			return fmt.Errorf("%w: archive entry %q is a link", ErrInvalidInput, hdr.Name)
+		default:
+			return fmt.Errorf("%w: archive entry %q is not a regular file", ErrInvalidInput, hdr.Name)
+		}
+	}
+}
+
+// add spools one entry. Sizes are counted while copying rather than taken
+// from headers, which an archive can misstate.
+func (x *archiveExtractor) add(name string, r io.Reader) error {
+	safePath := sanitizeArchivePath(name)
+	if safePath == "" {
+		return fmt.Errorf("%w: invalid file path %q", ErrInvalidInput, name)
+	}
+	if x.seen[safePath] {
+		return fmt.Errorf("%w: duplicate file path %q", ErrInvalidInput, safePath)
+	}
+	if len(x.files) >= maxArchiveFiles {
+		return fmt.Errorf("%w: archive has more than %d files", ErrInvalidInput, maxArchiveFiles)
+	}
+	x.seen[safePath] = true
+
+	spoolPath := filepath.Join(x.dir, fmt.Sprintf("%05d", len(x.files)))
+	f, err := os.Create(spoolPath)
+	if err != nil {
+		return fmt.Errorf("create spool file: %w", err)
+	}
+	n, copyErr := io.Copy(f, io.LimitReader(r, x.budget+1))
+	closeErr := f.Close()
+	if copyErr != nil {
+		return fmt.Errorf("%w: archive entry %q: %v", ErrInvalidInput, name, copyErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("close spool file: %w", closeErr)
+	}
+	if n > x.budget {
+		return fmt.Errorf("%w: archive expands beyond the allowed size", ErrInvalidInput)
+	}
+	x.budget -= n
+
+	x.files = append(x.files, PublishFileInput{
+		Path:        safePath,
+		ContentType: mime.TypeByExtension(path.Ext(safePath)),
+		Open:        func() (io.ReadCloser, error) { return os.Open(spoolPath) },
+	})
+	return nil
+}
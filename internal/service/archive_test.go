@@ -0,0 +1,132 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+type archiveEntry struct {
+	name string
+	body string
+	mode fs.FileMode
+}
+
+func zipBytes(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.mode != 0 {
+			header.SetMode(e.mode)
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatalf("CreateHeader() error = %v", err)
+		}
+		if _, err := io.WriteString(w, e.body); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func tarGzBytes(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		switch {
+		case e.mode&fs.ModeSymlink != 0:
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, "/etc/passwd", 0
+		case e.mode.IsDir():
+			hdr.Typeflag, hdr.Size = tar.TypeDir, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader() error = %v", err)
+		}
+		if _, err := io.WriteString(tw, e.body); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractArchive(t *testing.T) {
+	t.Parallel()
+
+	entries := []archiveEntry{
+		{name: "scripts/", mode: fs.ModeDir | 0o755},
+		{name: "scripts/run.sh", body: "echo hi"},
+		{name: "./SKILL.md", body: "# Demo"},
+	}
+	for name, archive := range map[string][]byte{
+		"zip":    zipBytes(t, entries),
+		"tar.gz": tarGzBytes(t, entries),
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			files, err := extractArchive(bytes.NewReader(archive), int64(len(archive)), t.TempDir())
+			if err != nil {
+				t.Fatalf("extractArchive() error = %v", err)
+			}
+			got := map[string]string{}
+			for _, f := range files {
+				rc, err := f.open()
+				if err != nil {
+					t.Fatalf("open(%s) error = %v", f.Path, err)
+				}
+				body, _ := io.ReadAll(rc)
+				_ = rc.Close()
+				got[f.Path] = string(body)
+			}
+			if len(got) != 2 || got["SKILL.md"] != "# Demo" || got["scripts/run.sh"] != "echo hi" {
+				t.Fatalf("extracted files = %v", got)
+			}
+		})
+	}
+}
+
+func TestExtractArchive_Rejects(t *testing.T) {
+	t.Parallel()
+
+	bomb := make([]byte, minArchiveRatioBytes+1)
+	tests := []struct {
+		name    string
+		archive []byte
+	}{
+		{name: "zip symlink", archive: zipBytes(t, []archiveEntry{{name: "SKILL.md", body: "/etc/passwd", mode: fs.ModeSymlink | 0o777}})},
+		{name: "tar symlink", archive: tarGzBytes(t, []archiveEntry{{name: "SKILL.md", mode: fs.ModeSymlink}})},
+		{name: "path traversal", archive: zipBytes(t, []archiveEntry{{name: "../SKILL.md", body: "x"}})},
+		{name: "duplicate path", archive: tarGzBytes(t, []archiveEntry{{name: "SKILL.md", body: "a"}, {name: "./SKILL.md", body: "b"}})},
+		{name: "zip bomb", archive: zipBytes(t, []archiveEntry{{name: "SKILL.md", body: string(bomb)}})},
+		{name: "empty", archive: zipBytes(t, nil)},
+		{name: "unknown format", archive: []byte("not an archive")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := extractArchive(bytes.NewReader(tt.archive), int64(len(tt.archive)), t.TempDir())
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("extractArchive() error = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
@@ -4,16 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 
 	"hermit/internal/extauth"
 	"hermit/internal/store"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
 const (
 	ProviderTypeLDAP = "ldap"
+	ProviderTypeOIDC = "oidc"
 )
 
 // AuthConfigView is the API representation with sensitive fields masked.
@@ -58,6 +61,13 @@ func (s *Service) SaveAuthConfig(ctx context.Context, providerType string, enabl
 		if err := json.Unmarshal(rawConfig, &cfg); err != nil {
 			return fmt.Errorf("%w: invalid LDAP config: %v", ErrInvalidInput, err)
 		}
+		if err := validateGroupRoles(cfg.GroupRoles); err != nil {
+			return err
+		}
+	case ProviderTypeOIDC:
+		if err := validateOIDCConfig(rawConfig); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("%w: unknown provider type %q", ErrInvalidInput, providerType)
 	}
@@ -72,6 +82,9 @@ func (s *Service) SaveAuthConfig(ctx context.Context, providerType string, enabl
 		ldapVersion++
 		ldapMu.Unlock()
 	}
+	if providerType == ProviderTypeOIDC {
+		resetOIDCAuthenticator()
+	}
 	return nil
 }
 
@@ -85,6 +98,9 @@ func (s *Service) DeleteAuthConfig(ctx context.Context, providerType string) err
 		ldapVersion++
 		ldapMu.Unlock()
 	}
+	if providerType == ProviderTypeOIDC {
+		resetOIDCAuthenticator()
+	}
 	return nil
 }
 
@@ -96,6 +112,7 @@ func (s *Service) GetLDAPAuthenticator(ctx context.Context) (*extauth.LDAPAuthen
 		defer ldapMu.Unlock()
 		return cachedLDAP, nil
 	}
+	version := ldapVersion
 	ldapMu.Unlock()
 
 	ac, err := s.store.GetAuthConfig(ctx, ProviderTypeLDAP)
@@ -116,11 +133,67 @@ func (s *Service) GetLDAPAuthenticator(ctx context.Context) (*extauth.LDAPAuthen
 
 	auth := extauth.NewLDAPAuthenticator(cfg)
 	ldapMu.Lock()
-	cachedLDAP = auth
+	if ldapVersion == version {
+		cachedLDAP = auth
+	}
 	ldapMu.Unlock()
 	return auth, nil
 }
 
+// SyncExternalRoles replaces the repository roles that an external provider
+// granted to the identity's subject with the grants of the current login.
+// Grants for repositories that no longer exist are ignored; roles assigned
+// manually by an admin are left untouched.
+func (s *Service) SyncExternalRoles(ctx context.Context, source string, identity *extauth.IdentityResult) error {
+	if identity == nil || !identity.ManagesRoles {
+		return nil
+	}
+	byRepo, err := mergeRoleGrants(identity.RoleGrants)
+	if err != nil {
+		return err
+	}
+
+	roles := make(map[uuid.UUID]string, len(byRepo))
+	for repoName, role := range byRepo {
+		repo, err := s.store.GetRepositoryByName(ctx, repoName)
+		if err != nil {
+			if store.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		roles[repo.ID] = role
+	}
+	return s.store.ReplaceSourceRepoMembers(ctx, identity.Subject, source, roles)
+}
+
+func validateGroupRoles(mappings []extauth.GroupRole) error {
+	for _, m := range mappings {
+		if strings.TrimSpace(m.Group) == "" || strings.TrimSpace(m.Repository) == "" {
+			return fmt.Errorf("%w: group role mapping requires group and repository", ErrInvalidInput)
+		}
+		if _, err := mapRoleToDB(m.Role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeRoleGrants collapses grants into the highest DB role per repository.
+func mergeRoleGrants(grants []extauth.RoleGrant) (map[string]string, error) {
+	out := make(map[string]string, len(grants))
+	for _, g := range grants {
+		role, err := mapRoleToDB(g.Role)
+		if err != nil {
+			return nil, err
+		}
+		if current, ok := out[g.Repository]; !ok || roleAllows(role, current) {
+			out[g.Repository] = role
+		}
+	}
+	return out, nil
+}
+
 // IsAuthProviderEnabled checks if a given provider type is enabled in the DB.
 func (s *Service) IsAuthProviderEnabled(ctx context.Context, providerType string) bool {
 	ac, err := s.store.GetAuthConfig(ctx, providerType)
@@ -148,7 +221,7 @@ func toAuthConfigView(ac store.AuthConfig) AuthConfigView {
 	_ = json.Unmarshal(ac.Config, &config)
 
 	if m, ok := config.(map[string]any); ok {
-		for _, key := range []string{"bind_password"} {
+		for _, key := range []string{"bind_password", "client_secret"} {
 			if v, exists := m[key]; exists {
 				if s, ok := v.(string); ok && len(s) > 0 {
 					m[key] = "••••••••"
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"hermit/internal/extauth"
+	"hermit/internal/store"
+)
+
+func TestMergeRoleGrants(t *testing.T) {
+	t.Parallel()
+	got, err := mergeRoleGrants([]extauth.RoleGrant{
+		{Repository: "hosted", Role: "viewer"},
+		{Repository: "hosted", Role: "admin"},
+		{Repository: "hosted", Role: "developer"},
+		{Repository: "proxy", Role: "read"},
+	})
+	if err != nil {
+		t.Fatalf("mergeRoleGrants() error = %v", err)
+	}
+	want := map[string]string{
+		"hosted": store.RoleAdmin,
+		"proxy":  store.RoleRead,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("mergeRoleGrants() = %#v, want %#v", got, want)
+	}
+}
+
+func TestMergeRoleGrants_InvalidRole(t *testing.T) {
+	t.Parallel()
+	_, err := mergeRoleGrants([]extauth.RoleGrant{{Repository: "hosted", Role: "owner"}})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("mergeRoleGrants() error = %v, want ErrInvalidInput", err)
+	}
+}
@@ -6,8 +6,13 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
+	"sort"
 	"strings"
+	"time"
 
+	"hermit/internal/auth"
+	"hermit/internal/extauth"
 	"hermit/internal/storage"
 	"hermit/internal/store"
 
@@ -100,8 +105,24 @@ func (s *Service) GetReadRepository(ctx context.Context) (store.Repository, erro
 	return s.GetRepository(ctx, s.defaults.GroupRepo)
 }
 
-func (s *Service) GetPublishRepository(ctx context.Context) (store.Repository, error) {
-	return s.GetRepository(ctx, s.defaults.HostedRepo)
+// GetPublishRepository returns the hosted repository writes go to. An empty
+// repoName selects the default hosted repository.
+func (s *Service) GetPublishRepository(ctx context.Context, repoName string) (store.Repository, error) {
+	repoName = strings.TrimSpace(repoName)
+	if repoName == "" {
+		repoName = s.defaults.HostedRepo
+	}
+	repo, err := s.GetRepository(ctx, repoName)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return store.Repository{}, fmt.Errorf("%w: repository %q", ErrNotFound, repoName)
+		}
+		return store.Repository{}, err
+	}
+	if repo.Type != store.RepoTypeHosted {
+		return store.Repository{}, fmt.Errorf("%w: repository %q is not a hosted repository", ErrInvalidInput, repoName)
+	}
+	return repo, nil
 }
 
 func (s *Service) GetRepository(ctx context.Context, repoName string) (store.Repository, error) {
@@ -132,14 +153,35 @@ func (s *Service) HasRepoPermission(ctx context.Context, repo store.Repository,
 	return roleAllows(role, requiredRole), nil
 }
 
+// TokenOptions restricts a personal access token. Nil Scopes leave the
+// token with the full power of its subject; Repositories limit publish
+// access and require the publish scope.
+type TokenOptions struct {
+	Scopes       []string
+	Repositories []string
+	ExpiresAt    *time.Time
+}
+
 // CreatePersonalToken creates a new personal access token for the given subject.
-func (s *Service) CreatePersonalToken(ctx context.Context, subject, name string, isAdmin bool) (string, store.APIToken, error) {
+func (s *Service) CreatePersonalToken(ctx context.Context, subject, name string, isAdmin bool, opts TokenOptions) (string, store.APIToken, error) {
 	if strings.TrimSpace(subject) == "" {
 		return "", store.APIToken{}, fmt.Errorf("%w: subject required", ErrInvalidInput)
 	}
 	if strings.TrimSpace(name) == "" {
 		return "", store.APIToken{}, fmt.Errorf("%w: token name required", ErrInvalidInput)
 	}
+	opts, err := normalizeTokenOptions(opts, isAdmin, time.Now())
+	if err != nil {
+		return "", store.APIToken{}, err
+	}
+	for _, repoName := range opts.Repositories {
+		if _, err := s.GetPublishRepository(ctx, repoName); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return "", store.APIToken{}, fmt.Errorf("%w: repository %q does not exist", ErrInvalidInput, repoName)
+			}
+			return "", store.APIToken{}, err
+		}
+	}
 
 	rawToken, err := generateToken(32)
 	if err != nil {
@@ -148,41 +190,72 @@ func (s *Service) CreatePersonalToken(ctx context.Context, subject, name string,
 	sum := sha256.Sum256([]byte(rawToken))
 	hash := hex.EncodeToString(sum[:])
 
-	id, err := s.store.CreatePersonalToken(ctx, subject, strings.TrimSpace(name), hash, isAdmin)
+	tok, err := s.store.CreatePersonalToken(ctx, subject, strings.TrimSpace(name), hash, isAdmin, opts.Scopes, opts.Repositories, opts.ExpiresAt)
 	if err != nil {
 		if errors.Is(err, store.ErrConflict) {
 			return "", store.APIToken{}, ErrConflict
 		}
 		return "", store.APIToken{}, err
 	}
-	tok := store.APIToken{
-		ID:        id,
-		Subject:   subject,
-		Name:      strings.TrimSpace(name),
-		TokenType: store.TokenTypePersonal,
-		IsAdmin:   isAdmin,
-	}
 	return rawToken, tok, nil
 }
 
-// IssueSessionToken creates or replaces the session token for a subject.
-// Used after successful LDAP authentication.
-func (s *Service) IssueSessionToken(ctx context.Context, subject string, isAdmin bool) (string, error) {
-	if strings.TrimSpace(subject) == "" {
-		return "", fmt.Errorf("%w: subject required", ErrInvalidInput)
+// normalizeTokenOptions validates, de-duplicates and sorts the scopes and
+// repositories of a new token.
+func normalizeTokenOptions(opts TokenOptions, isAdmin bool, now time.Time) (TokenOptions, error) {
+	out := TokenOptions{ExpiresAt: opts.ExpiresAt}
+	if opts.Scopes != nil {
+		seen := map[string]bool{}
+		out.Scopes = []string{}
+		for _, scope := range opts.Scopes {
+			scope = strings.ToLower(strings.TrimSpace(scope))
+			if !auth.ValidScope(scope) {
+				return TokenOptions{}, fmt.Errorf("%w: unknown scope %q (want read, publish or admin)", ErrInvalidInput, scope)
+			}
+			if !seen[scope] {
+				seen[scope] = true
+				out.Scopes = append(out.Scopes, scope)
+			}
+		}
+		if len(out.Scopes) == 0 {
+			return TokenOptions{}, fmt.Errorf("%w: at least one scope required", ErrInvalidInput)
+		}
+		sort.Strings(out.Scopes)
+	}
+	scoped := auth.Claims{Scopes: out.Scopes}
+	if out.Scopes != nil && scoped.HasScope(auth.ScopeAdmin) && !isAdmin {
+		return TokenOptions{}, fmt.Errorf("%w: the admin scope requires an administrator", ErrInvalidInput)
 	}
 
-	rawToken, err := generateToken(32)
-	if err != nil {
-		return "", err
+	seen := map[string]bool{}
+	for _, repo := range opts.Repositories {
+		repo = strings.TrimSpace(repo)
+		if repo == "" {
+			return TokenOptions{}, fmt.Errorf("%w: empty repository name", ErrInvalidInput)
+		}
+		if !seen[repo] {
+			seen[repo] = true
+			out.Repositories = append(out.Repositories, repo)
+		}
+	}
+	if len(out.Repositories) > 0 {
+		if out.Scopes == nil || !scoped.HasScope(auth.ScopePublish) {
+			return TokenOptions{}, fmt.Errorf("%w: repositories can only restrict a token with the publish scope", ErrInvalidInput)
+		}
+		if scoped.HasScope(auth.ScopeAdmin) {
+			return TokenOptions{}, fmt.Errorf("%w: an admin token cannot be restricted to repositories", ErrInvalidInput)
+		}
+		sort.Strings(out.Repositories)
 	}
-	sum := sha256.Sum256([]byte(rawToken))
-	hash := hex.EncodeToString(sum[:])
 
-	if err := s.store.UpsertSessionToken(ctx, subject, hash, isAdmin); err != nil {
-		return "", err
+	if out.ExpiresAt != nil {
+		if !out.ExpiresAt.After(now) {
+			return TokenOptions{}, fmt.Errorf("%w: expiry must be in the future", ErrInvalidInput)
+		}
+		expiresAt := out.ExpiresAt.UTC()
+		out.ExpiresAt = &expiresAt
 	}
-	return rawToken, nil
+	return out, nil
 }
 
 // ListMyTokens returns all personal access tokens for the given subject.
@@ -206,6 +279,12 @@ func (s *Service) RegisterUser(ctx context.Context, username, password, displayN
 	if strings.TrimSpace(username) == "" {
 		return store.User{}, fmt.Errorf("%w: username required", ErrInvalidInput)
 	}
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(username)), extauth.OIDCSubjectPrefix) {
+		return store.User{}, fmt.Errorf("%w: usernames starting with %q are reserved for OIDC users", ErrInvalidInput, extauth.OIDCSubjectPrefix)
+	}
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(username)), systemSubjectPrefix) {
+		return store.User{}, fmt.Errorf("%w: usernames starting with %q are reserved", ErrInvalidInput, systemSubjectPrefix)
+	}
 	if len(password) < 6 {
 		return store.User{}, fmt.Errorf("%w: password must be at least 6 characters", ErrInvalidInput)
 	}
@@ -224,28 +303,55 @@ func (s *Service) RegisterUser(ctx context.Context, username, password, displayN
 }
 
 // LocalLogin authenticates a local user by username and password, then issues a session token.
-func (s *Service) LocalLogin(ctx context.Context, username, password string) (string, store.User, error) {
+// Repeated failures delay and then lock out further attempts; see LoginPolicy.
+// Users with 2FA, or who are required to enroll, get a LoginChallenge instead
+// of a token.
+func (s *Service) LocalLogin(ctx context.Context, username, password string, client SessionClient) (LocalLoginResult, error) {
 	if strings.TrimSpace(username) == "" || password == "" {
-		return "", store.User{}, fmt.Errorf("%w: username and password required", ErrInvalidInput)
+		return LocalLoginResult{}, fmt.Errorf("%w: username and password required", ErrInvalidInput)
 	}
-	u, err := s.store.GetUserByUsername(ctx, strings.TrimSpace(username))
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return "", store.User{}, fmt.Errorf("invalid username or password")
+	var u store.User
+	err := s.guardLogin(ctx, ProviderTypeLocal, username, client, func() (bool, error) {
+		var err error
+		u, err = s.store.GetUserByUsername(ctx, strings.TrimSpace(username))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return true, errInvalidCredentials
+			}
+			return false, err
 		}
-		return "", store.User{}, err
-	}
-	if u.Disabled {
-		return "", store.User{}, fmt.Errorf("account disabled")
+		// The password is checked first so a disabled account is only
+		// revealed to someone who knows its password.
+		if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+			return true, errInvalidCredentials
+		}
+		if u.Disabled {
+			return false, fmt.Errorf("account disabled")
+		}
+		required, err := s.twoFactorRequired(ctx, u)
+		if err != nil {
+			return false, err
+		}
+		if u.TOTPEnabled || required {
+			return false, errSecondFactorRequired
+		}
+		return false, nil
+	})
+	if errors.Is(err, errSecondFactorRequired) {
+		challenge, err := s.startLoginChallenge(ctx, u)
+		if err != nil {
+			return LocalLoginResult{}, err
+		}
+		return LocalLoginResult{User: u, Challenge: challenge}, nil
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
-		return "", store.User{}, fmt.Errorf("invalid username or password")
+	if err != nil {
+		return LocalLoginResult{}, err
 	}
-	token, err := s.IssueSessionToken(ctx, u.Username, u.IsAdmin)
+	token, err := s.IssueSessionToken(ctx, u.Username, u.IsAdmin, client)
 	if err != nil {
-		return "", store.User{}, err
+		return LocalLoginResult{}, err
 	}
-	return token, u, nil
+	return LocalLoginResult{Token: token, User: u}, nil
 }
 
 // ListUsers returns all local user accounts (admin only).
@@ -254,6 +360,7 @@ func (s *Service) ListUsers(ctx context.Context) ([]store.User, error) {
 }
 
 // UpdateUser updates a local user's profile and role (admin only).
+// Disabling a user also ends their sessions.
 func (s *Service) UpdateUser(ctx context.Context, userID string, displayName, email string, isAdmin, disabled bool) (store.User, error) {
 	id, err := uuid.Parse(userID)
 	if err != nil {
@@ -266,10 +373,16 @@ func (s *Service) UpdateUser(ctx context.Context, userID string, displayName, em
 		}
 		return store.User{}, err
 	}
+	if disabled {
+		if err := s.revokeAllSessions(ctx, u.Username); err != nil {
+			return store.User{}, err
+		}
+	}
 	return u, nil
 }
 
-// ResetUserPassword resets a user's password (admin only).
+// ResetUserPassword resets a user's password, ends their sessions and
+// lifts any login lockout (admin only).
 func (s *Service) ResetUserPassword(ctx context.Context, userID, newPassword string) error {
 	if len(newPassword) < 6 {
 		return fmt.Errorf("%w: password must be at least 6 characters", ErrInvalidInput)
@@ -288,11 +401,18 @@ func (s *Service) ResetUserPassword(ctx context.Context, userID, newPassword str
 		}
 		return err
 	}
-	return nil
+	// A new password also ends a lockout.
+	u, _, err := s.UnlockUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.revokeAllSessions(ctx, u.Username)
 }
 
-// ChangePassword verifies the caller's current password, then sets a new one.
-func (s *Service) ChangePassword(ctx context.Context, username, oldPassword, newPassword string) error {
+// ChangePassword verifies the caller's current password, then sets a new
+// one and ends every other session of the caller. currentSessionID is the
+// session making the change, if any; it stays signed in.
+func (s *Service) ChangePassword(ctx context.Context, username, oldPassword, newPassword, currentSessionID string) error {
 	if len(newPassword) < 6 {
 		return fmt.Errorf("%w: password must be at least 6 characters", ErrInvalidInput)
 	}
@@ -310,7 +430,24 @@ func (s *Service) ChangePassword(ctx context.Context, username, oldPassword, new
 	if err != nil {
 		return fmt.Errorf("hash password: %w", err)
 	}
-	return s.store.UpdateUserPassword(ctx, u.ID, string(hash))
+	if err := s.store.UpdateUserPassword(ctx, u.ID, string(hash)); err != nil {
+		return err
+	}
+	_, err = s.RevokeOtherSessions(ctx, u.Username, currentSessionID)
+	return err
+}
+
+// revokeAllSessions ends every session of subject, for when an admin
+// changes the account under it.
+func (s *Service) revokeAllSessions(ctx context.Context, subject string) error {
+	n, err := s.store.RevokeOtherSessions(ctx, subject, uuid.Nil)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("[auth] ended %d session(s) of %s", n, subject)
+	}
+	return nil
 }
 
 // DeleteUser removes a local user account (admin only).
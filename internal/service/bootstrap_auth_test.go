@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeTokenOptions(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		opts      TokenOptions
+		isAdmin   bool
+		wantErr   bool
+		wantScope []string
+		wantRepos []string
+	}{
+		{name: "unrestricted", opts: TokenOptions{}},
+		{name: "dedupes and sorts", opts: TokenOptions{Scopes: []string{" Publish", "read", "publish"}}, wantScope: []string{"publish", "read"}},
+		{name: "publish to one repo", opts: TokenOptions{Scopes: []string{"publish"}, Repositories: []string{"b", "a", "b"}}, wantScope: []string{"publish"}, wantRepos: []string{"a", "b"}},
+		{name: "admin for admins", opts: TokenOptions{Scopes: []string{"admin"}}, isAdmin: true, wantScope: []string{"admin"}},
+		{name: "admin for users", opts: TokenOptions{Scopes: []string{"admin"}}, wantErr: true},
+		{name: "unknown scope", opts: TokenOptions{Scopes: []string{"write"}}, wantErr: true},
+		{name: "empty scopes", opts: TokenOptions{Scopes: []string{}}, wantErr: true},
+		{name: "repos without scopes", opts: TokenOptions{Repositories: []string{"a"}}, wantErr: true},
+		{name: "repos on read token", opts: TokenOptions{Scopes: []string{"read"}, Repositories: []string{"a"}}, wantErr: true},
+		{name: "repos on admin token", opts: TokenOptions{Scopes: []string{"admin"}, Repositories: []string{"a"}}, isAdmin: true, wantErr: true},
+		{name: "empty repo", opts: TokenOptions{Scopes: []string{"publish"}, Repositories: []string{" "}}, wantErr: true},
+		{name: "future expiry", opts: TokenOptions{ExpiresAt: &future}},
+		{name: "past expiry", opts: TokenOptions{ExpiresAt: &past}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := normalizeTokenOptions(tt.opts, tt.isAdmin, now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Fatalf("normalizeTokenOptions() error = %v, want ErrInvalidInput", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeTokenOptions() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Scopes, tt.wantScope) {
+				t.Fatalf("Scopes = %#v, want %#v", got.Scopes, tt.wantScope)
+			}
+			if !reflect.DeepEqual(got.Repositories, tt.wantRepos) {
+				t.Fatalf("Repositories = %#v, want %#v", got.Repositories, tt.wantRepos)
+			}
+		})
+	}
+}
@@ -6,11 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"sort"
 	"strings"
-	"time"
 
 	"hermit/internal/auth"
+	"hermit/internal/semver"
 	"hermit/internal/store"
 
 	"github.com/google/uuid"
@@ -25,8 +24,13 @@ func (s *Service) SearchSkills(ctx context.Context, repo store.Repository, actor
 	if limit <= 0 {
 		limit = 20
 	}
+	tsquery := buildSearchQuery(query)
+	if tsquery == "" {
+		return []store.SkillSearchResult{}, nil
+	}
+	exactSlug := normalizeSlug(query)
 	if repo.Type != store.RepoTypeGroup {
-		return s.store.SearchSkills(ctx, repo.ID, query, limit)
+		return s.store.SearchSkills(ctx, repo.ID, tsquery, exactSlug, limit)
 	}
 
 	members, err := s.getGroupMembers(ctx, repo.ID, actor)
@@ -35,7 +39,7 @@ func (s *Service) SearchSkills(ctx context.Context, repo store.Repository, actor
 	}
 	merged := make(map[string]store.SkillSearchResult)
 	for _, member := range members {
-		items, err := s.store.SearchSkills(ctx, member.ID, query, limit)
+		items, err := s.store.SearchSkills(ctx, member.ID, tsquery, exactSlug, limit)
 		if err != nil {
 			return nil, err
 		}
@@ -54,20 +58,7 @@ func (s *Service) SearchSkills(ctx context.Context, repo store.Repository, actor
 	for _, item := range merged {
 		out = append(out, item)
 	}
-	sort.SliceStable(out, func(i, j int) bool {
-		if out[i].Score == out[j].Score {
-			ti := time.Time{}
-			tj := time.Time{}
-			if out[i].UpdatedAt != nil {
-				ti = *out[i].UpdatedAt
-			}
-			if out[j].UpdatedAt != nil {
-				tj = *out[j].UpdatedAt
-			}
-			return ti.After(tj)
-		}
-		return out[i].Score > out[j].Score
-	})
+	sortSearchResults(out)
 	if len(out) > limit {
 		out = out[:limit]
 	}
@@ -145,17 +136,27 @@ func (s *Service) GetSkill(ctx context.Context, repo store.Repository, actor aut
 	latestVersion, err := s.store.GetLatestVersionForSkill(ctx, skill.ID)
 	if err == nil {
 		latest = &store.SkillVersionSummary{
-			Version:   latestVersion.Version,
-			CreatedAt: latestVersion.CreatedAt,
-			Changelog: latestVersion.Changelog,
+			Version:     latestVersion.Version,
+			CreatedAt:   latestVersion.CreatedAt,
+			Changelog:   latestVersion.Changelog,
+			Deprecation: latestVersion.Deprecation,
 		}
 	} else if !store.IsNotFound(err) {
 		return SkillView{}, err
 	}
 
+	starred := false
+	if !actor.Anonymous && actor.Subject != "" {
+		starred, err = s.store.IsSkillStarred(ctx, skill.ID, actor.Subject)
+		if err != nil {
+			return SkillView{}, err
+		}
+	}
+
 	return SkillView{
 		Skill:         skill,
 		LatestVersion: latest,
+		Starred:       starred,
 	}, nil
 }
 
@@ -195,13 +196,27 @@ func (s *Service) GetSkillVersion(
 	return SkillVersionView{Skill: skill, Version: sv}, nil
 }
 
+// ResolveSkillVersion reports the latest version of slug, the version whose
+// content matches hash and, when versionRange is set, the highest version
+// satisfying it (an exact version or a range such as ^1.2). A partial
+// version such as 1.0 matches a version of that exact name first.
 func (s *Service) ResolveSkillVersion(
 	ctx context.Context,
 	repo store.Repository,
 	actor auth.Actor,
 	slug string,
 	hash string,
+	versionRange string,
 ) (ResolveView, error) {
+	var constraint *semver.Range
+	if versionRange = strings.TrimSpace(versionRange); versionRange != "" {
+		rng, err := semver.ParseRange(versionRange)
+		if err != nil {
+			return ResolveView{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		constraint = &rng
+	}
+
 	targetRepo, err := s.findSkillRepository(ctx, repo, actor, slug)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
@@ -221,27 +236,49 @@ func (s *Service) ResolveSkillVersion(
 	if err != nil && !store.IsNotFound(err) {
 		return ResolveView{}, err
 	}
-	var latestVersion *string
+	view := ResolveView{SkillDeprecation: skill.Deprecation}
 	if err == nil {
-		latestVersion = &latest.Version
+		view.LatestVersion = &latest.Version
+		view.Latest = versionNotice(latest)
+	}
+
+	if constraint != nil {
+		a, err := store.Artifact{}, ErrNotFound
+		if isPartialVersion(versionRange) {
+			a, err = s.resolveInRepo(ctx, repo, actor, slug, versionRange, map[uuid.UUID]struct{}{})
+		}
+		if errors.Is(err, ErrNotFound) {
+			a, err = s.resolveLatestArtifactInRepo(ctx, repo, actor, slug, constraint, map[uuid.UUID]struct{}{})
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return ResolveView{}, err
+		}
+		if err == nil {
+			view.RangeVersion = &a.Version
+			view.Range = artifactNotice(a)
+		}
 	}
 
 	hash = strings.TrimSpace(hash)
 	if hash == "" {
-		return ResolveView{
-			MatchVersion:  nil,
-			LatestVersion: latestVersion,
-		}, nil
+		return view, nil
 	}
 
 	match, err := s.store.ResolveVersionByHash(ctx, targetRepo.ID, slug, hash)
 	if err != nil {
 		return ResolveView{}, err
 	}
-	return ResolveView{
-		MatchVersion:  match,
-		LatestVersion: latestVersion,
-	}, nil
+	if match != nil {
+		// A yanked version still matches by content, so clients can warn
+		// that what they have installed was pulled.
+		_, mv, err := s.store.GetSkillVersion(ctx, targetRepo.ID, slug, *match)
+		if err != nil && !store.IsNotFound(err) {
+			return ResolveView{}, err
+		}
+		view.MatchVersion = match
+		view.Match = versionNotice(mv)
+	}
+	return view, nil
 }
 
 func (s *Service) ReadSkillFile(
@@ -370,11 +407,18 @@ func (s *Service) resolveInRepo(
 	}
 }
 
+// resolveLatestArtifactInRepo returns the highest version of slug, or the
+// highest version satisfying constraint when it is set. Without a
+// constraint, group members are tried in priority order and the first
+// member with the skill wins; with one, the highest match across all
+// members wins, and priority only breaks ties. For proxies only versions
+// that are already cached locally are considered.
 func (s *Service) resolveLatestArtifactInRepo(
 	ctx context.Context,
 	repo store.Repository,
 	actor auth.Actor,
 	slug string,
+	constraint *semver.Range,
 	visited map[uuid.UUID]struct{},
 ) (store.Artifact, error) {
 	if !repo.Enabled {
@@ -382,6 +426,9 @@ func (s *Service) resolveLatestArtifactInRepo(
 	}
 	switch repo.Type {
 	case store.RepoTypeHosted, store.RepoTypeProxy:
+		if constraint != nil {
+			return s.resolveRangeArtifact(ctx, repo, slug, *constraint)
+		}
 		a, err := s.store.GetLatestArtifact(ctx, repo.ID, slug)
 		if err != nil {
 			if store.IsNotFound(err) {
@@ -400,22 +447,49 @@ func (s *Service) resolveLatestArtifactInRepo(
 		if err != nil {
 			return store.Artifact{}, err
 		}
+		var best store.Artifact
+		var bestVersion semver.Version
+		found := false
 		for _, member := range members {
-			a, err := s.resolveLatestArtifactInRepo(ctx, member, actor, slug, visited)
-			if err == nil {
+			a, err := s.resolveLatestArtifactInRepo(ctx, member, actor, slug, constraint, visited)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return store.Artifact{}, err
+			}
+			if constraint == nil {
 				return a, nil
 			}
-			if errors.Is(err, ErrNotFound) {
+			v, err := semver.Parse(a.Version)
+			if err != nil {
 				continue
 			}
-			return store.Artifact{}, err
+			if !found || semver.Compare(v, bestVersion) > 0 {
+				best, bestVersion, found = a, v, true
+			}
 		}
-		return store.Artifact{}, ErrNotFound
+		if !found {
+			return store.Artifact{}, ErrNotFound
+		}
+		return best, nil
 	default:
 		return store.Artifact{}, ErrNotFound
 	}
 }
 
+func (s *Service) resolveRangeArtifact(ctx context.Context, repo store.Repository, slug string, constraint semver.Range) (store.Artifact, error) {
+	versions, err := s.store.ListVersionNames(ctx, repo.ID, slug)
+	if err != nil {
+		return store.Artifact{}, err
+	}
+	best, ok := constraint.Best(versions)
+	if !ok {
+		return store.Artifact{}, ErrNotFound
+	}
+	return s.resolveHosted(ctx, repo, slug, best)
+}
+
 func (s *Service) resolveTagVersionInRepo(
 	ctx context.Context,
 	repo store.Repository,
@@ -455,3 +529,53 @@ func (s *Service) resolveTagVersionInRepo(
 		return nil, nil
 	}
 }
+
+// BackfillVersionSortKeys computes semver sort keys for versions stored
+// before they existed. It returns the number of versions updated.
+func (s *Service) BackfillVersionSortKeys(ctx context.Context) (int, error) {
+	const batchSize = 500
+	total := 0
+	for {
+		n, err := s.store.BackfillVersionSortKeys(ctx, batchSize)
+		total += n
+		if err != nil || n < batchSize {
+			return total, err
+		}
+	}
+}
+
+// BackfillSkillReadmes extracts SKILL.md from the latest archive of skills
+// indexed before search covered it. Unreadable archives are recorded as
+// having no SKILL.md so they are not retried on every start.
+func (s *Service) BackfillSkillReadmes(ctx context.Context) (int, error) {
+	const batchSize = 100
+	total := 0
+	for {
+		pending, err := s.store.ListPendingReadmes(ctx, batchSize)
+		if err != nil {
+			return total, err
+		}
+		for _, p := range pending {
+			readme := ""
+			if p.BlobPath != "" {
+				readme, _ = s.readArchiveManifest(ctx, p.BlobPath)
+			}
+			if err := s.store.SetPackageReadme(ctx, p.PackageID, readme); err != nil {
+				return total, err
+			}
+			total++
+		}
+		if len(pending) < batchSize {
+			return total, nil
+		}
+	}
+}
+
+func (s *Service) readArchiveManifest(ctx context.Context, blobPath string) (string, error) {
+	f, err := s.blobs.Open(ctx, blobPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return readSkillManifest(f, f.Size())
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"hermit/internal/auth"
+	"hermit/internal/storage"
+	"hermit/internal/store"
+	"hermit/internal/textdiff"
+)
+
+const (
+	// maxDiffFileBytes is the largest file a line diff is computed for.
+	maxDiffFileBytes = 512 << 10
+	// maxDiffTotalBytes bounds the rendered diffs of one response; files past
+	// it are listed without a diff.
+	maxDiffTotalBytes = 2 << 20
+	diffContextLines  = 3
+)
+
+// Reasons a file is listed without a line diff.
+const (
+	DiffOmittedBinary   = "binary"
+	DiffOmittedTooLarge = "too_large"
+	DiffOmittedLimit    = "limit"
+)
+
+type FileDiff struct {
+	Path      string `json:"path"`
+	Status    string `json:"status"` // added, removed or modified
+	OldSize   *int64 `json:"oldSize,omitempty"`
+	NewSize   *int64 `json:"newSize,omitempty"`
+	OldSHA256 string `json:"oldSha256,omitempty"`
+	NewSHA256 string `json:"newSha256,omitempty"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Diff      string `json:"diff,omitempty"`
+	Omitted   string `json:"omitted,omitempty"`
+}
+
+type VersionDiffView struct {
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Files     []FileDiff `json:"files"`
+	Added     int        `json:"added"`
+	Removed   int        `json:"removed"`
+	Modified  int        `json:"modified"`
+	Unchanged int        `json:"unchanged"`
+}
+
+type fileDescriptor struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// DiffSkillVersions compares two versions of slug file by file, using the
+// stored SHA-256 descriptors to find changes and the archives for line
+// diffs. An empty to compares against the latest version.
+func (s *Service) DiffSkillVersions(
+	ctx context.Context,
+	repo store.Repository,
+	actor auth.Actor,
+	slug string,
+	from string,
+	to string,
+) (VersionDiffView, error) {
+	slug = normalizeSlug(slug)
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if slug == "" {
+		return VersionDiffView{}, fmt.Errorf("%w: slug required", ErrInvalidInput)
+	}
+	if from == "" {
+		return VersionDiffView{}, fmt.Errorf("%w: from version required", ErrInvalidInput)
+	}
+
+	targetRepo, err := s.findSkillRepository(ctx, repo, actor, slug)
+	if err != nil {
+		return VersionDiffView{}, err
+	}
+	skill, oldVersion, err := s.store.GetSkillVersion(ctx, targetRepo.ID, slug, from)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return VersionDiffView{}, ErrNotFound
+		}
+		return VersionDiffView{}, err
+	}
+	var newVersion store.SkillVersion
+	if to == "" {
+		newVersion, err = s.store.GetLatestVersionForSkill(ctx, skill.ID)
+	} else {
+		_, newVersion, err = s.store.GetSkillVersion(ctx, targetRepo.ID, slug, to)
+	}
+	if err != nil {
+		if store.IsNotFound(err) {
+			return VersionDiffView{}, ErrNotFound
+		}
+		return VersionDiffView{}, err
+	}
+
+	oldFiles, err := decodeFileDescriptors(oldVersion.Files)
+	if err != nil {
+		return VersionDiffView{}, err
+	}
+	newFiles, err := decodeFileDescriptors(newVersion.Files)
+	if err != nil {
+		return VersionDiffView{}, err
+	}
+	view := diffFileSets(oldFiles, newFiles)
+	view.From, view.To = oldVersion.Version, newVersion.Version
+
+	if len(view.Files) == 0 {
+		return view, nil
+	}
+	oldArchive, err := s.openVersionArchive(ctx, targetRepo, slug, oldVersion.Version)
+	if err != nil {
+		return VersionDiffView{}, err
+	}
+	defer oldArchive.Close()
+	newArchive, err := s.openVersionArchive(ctx, targetRepo, slug, newVersion.Version)
+	if err != nil {
+		return VersionDiffView{}, err
+	}
+	defer newArchive.Close()
+
+	if err := renderFileDiffs(view.Files, oldArchive.read, newArchive.read, maxDiffTotalBytes); err != nil {
+		return VersionDiffView{}, err
+	}
+	return view, nil
+}
+
+func decodeFileDescriptors(raw json.RawMessage) ([]fileDescriptor, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var files []fileDescriptor
+	if err := json.Unmarshal(raw, &files); err != nil {
+		return nil, fmt.Errorf("decode file descriptors: %w", err)
+	}
+	return files, nil
+}
+
+// diffFileSets classifies files by path and SHA-256. Unchanged files are
+// only counted.
+func diffFileSets(oldFiles, newFiles []fileDescriptor) VersionDiffView {
+	oldByPath := make(map[string]fileDescriptor, len(oldFiles))
+	for _, f := range oldFiles {
+		oldByPath[f.Path] = f
+	}
+	newByPath := make(map[string]fileDescriptor, len(newFiles))
+	for _, f := range newFiles {
+		newByPath[f.Path] = f
+	}
+
+	view := VersionDiffView{Files: []FileDiff{}}
+	for _, nf := range newFiles {
+		of, existed := oldByPath[nf.Path]
+		switch {
+		case !existed:
+			view.Added++
+			view.Files = append(view.Files, FileDiff{Path: nf.Path, Status: "added", NewSize: &nf.Size, NewSHA256: nf.SHA256})
+		case of.SHA256 != nf.SHA256:
+			view.Modified++
+			view.Files = append(view.Files, FileDiff{
+				Path:      nf.Path,
+				Status:    "modified",
+				OldSize:   &of.Size,
+				NewSize:   &nf.Size,
+				OldSHA256: of.SHA256,
+				NewSHA256: nf.SHA256,
+			})
+		default:
+			view.Unchanged++
+		}
+	}
+	for _, of := range oldFiles {
+		if _, kept := newByPath[of.Path]; !kept {
+			view.Removed++
+			view.Files = append(view.Files, FileDiff{Path: of.Path, Status: "removed", OldSize: &of.Size, OldSHA256: of.SHA256})
+		}
+	}
+	sort.Slice(view.Files, func(i, j int) bool {
+		return view.Files[i].Path < view.Files[j].Path
+	})
+	return view
+}
+
+// fileReader returns the content of a file of a version. ok is false when
+// the file is missing or larger than maxDiffFileBytes.
+type fileReader func(path string) (body []byte, ok bool, err error)
+
+// renderFileDiffs fills in unified diffs for text files until budget bytes
+// of diff output have been produced. Files are read one at a time, and not
+// at all once the budget is spent.
+func renderFileDiffs(files []FileDiff, readOld, readNew fileReader, budget int) error {
+	for i := range files {
+		f := &files[i]
+		if (f.OldSize != nil && *f.OldSize > maxDiffFileBytes) || (f.NewSize != nil && *f.NewSize > maxDiffFileBytes) {
+			f.Omitted = DiffOmittedTooLarge
+			continue
+		}
+		if budget <= 0 {
+			f.Omitted = DiffOmittedLimit
+			continue
+		}
+		var oldBody, newBody []byte
+		hasOld, hasNew := true, true
+		var err error
+		if f.OldSize != nil {
+			if oldBody, hasOld, err = readOld(f.Path); err != nil {
+				return err
+			}
+		}
+		if f.NewSize != nil {
+			if newBody, hasNew, err = readNew(f.Path); err != nil {
+				return err
+			}
+		}
+		if !hasOld || !hasNew {
+			f.Omitted = DiffOmittedTooLarge
+			continue
+		}
+		if isBinaryContent(oldBody) || isBinaryContent(newBody) {
+			f.Omitted = DiffOmittedBinary
+			continue
+		}
+
+		oldName, newName := "a/"+f.Path, "b/"+f.Path
+		if f.OldSize == nil {
+			oldName = "/dev/null"
+		}
+		if f.NewSize == nil {
+			newName = "/dev/null"
+		}
+		diff, stats := textdiff.Unified(oldName, newName, string(oldBody), string(newBody), diffContextLines)
+		f.Additions, f.Deletions = stats.Additions, stats.Deletions
+		if len(diff) > budget {
+			f.Omitted = DiffOmittedLimit
+			budget = 0
+			continue
+		}
+		f.Diff = diff
+		budget -= len(diff)
+	}
+	return nil
+}
+
+func isBinaryContent(body []byte) bool {
+	return bytes.IndexByte(body, 0) >= 0 || !utf8.Valid(body)
+}
+
+// versionArchive reads files from the archive of a version.
+type versionArchive struct {
+	blob  *storage.BlobFile
+	files map[string]*zip.File
+}
+
+func (s *Service) openVersionArchive(ctx context.Context, repo store.Repository, slug, version string) (*versionArchive, error) {
+	artifact, err := s.store.GetArtifact(ctx, repo.ID, slug, version)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	f, err := s.blobs.Open(ctx, artifact.BlobPath)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(f, f.Size())
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%w: artifact is not a zip", ErrInvalidInput)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, zf := range zr.File {
+		files[sanitizeArchivePath(zf.Name)] = zf
+	}
+	return &versionArchive{blob: f, files: files}, nil
+}
+
+func (va *versionArchive) Close() error {
+	return va.blob.Close()
+}
+
+func (va *versionArchive) read(path string) ([]byte, bool, error) {
+	zf, ok := va.files[path]
+	if !ok {
+		return nil, false, nil
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, false, err
+	}
+	data, readErr := io.ReadAll(io.LimitReader(rc, maxDiffFileBytes+1))
+	closeErr := rc.Close()
+	if readErr != nil {
+		return nil, false, readErr
+	}
+	if closeErr != nil {
+		return nil, false, closeErr
+	}
+	return data, len(data) <= maxDiffFileBytes, nil
+}
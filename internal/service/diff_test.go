@@ -0,0 +1,113 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffFileSets(t *testing.T) {
+	t.Parallel()
+
+	oldFiles := []fileDescriptor{
+		{Path: "SKILL.md", Size: 10, SHA256: "aa"},
+		{Path: "gone.txt", Size: 3, SHA256: "bb"},
+		{Path: "same.txt", Size: 4, SHA256: "cc"},
+	}
+	newFiles := []fileDescriptor{
+		{Path: "SKILL.md", Size: 12, SHA256: "ab"},
+		{Path: "new.txt", Size: 5, SHA256: "dd"},
+		{Path: "same.txt", Size: 4, SHA256: "cc"},
+	}
+
+	view := diffFileSets(oldFiles, newFiles)
+	if view.Added != 1 || view.Removed != 1 || view.Modified != 1 || view.Unchanged != 1 {
+		t.Fatalf("counts = +%d -%d ~%d =%d, want 1 each", view.Added, view.Removed, view.Modified, view.Unchanged)
+	}
+	var got []string
+	for _, f := range view.Files {
+		got = append(got, f.Status+":"+f.Path)
+	}
+	want := "modified:SKILL.md removed:gone.txt added:new.txt"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("files = %v, want %s", got, want)
+	}
+}
+
+func TestRenderFileDiffs(t *testing.T) {
+	t.Parallel()
+
+	size := func(n int64) *int64 { return &n }
+	files := []FileDiff{
+		{Path: "SKILL.md", Status: "modified", OldSize: size(4), NewSize: size(4)},
+		{Path: "added.md", Status: "added", NewSize: size(6)},
+		{Path: "logo.png", Status: "modified", OldSize: size(2), NewSize: size(2)},
+		{Path: "huge.txt", Status: "added", NewSize: size(maxDiffFileBytes + 1)},
+	}
+	oldContent := map[string][]byte{
+		"SKILL.md": []byte("old\n"),
+		"logo.png": {0x89, 0x00},
+	}
+	newContent := map[string][]byte{
+		"SKILL.md": []byte("new\n"),
+		"added.md": []byte("hello\n"),
+		"logo.png": {0x89, 0x01},
+	}
+
+	if err := renderFileDiffs(files, mapReader(oldContent, nil), mapReader(newContent, nil), maxDiffTotalBytes); err != nil {
+		t.Fatalf("renderFileDiffs() error = %v", err)
+	}
+
+	if want := "--- a/SKILL.md\n+++ b/SKILL.md\n@@ -1 +1 @@\n-old\n+new\n"; files[0].Diff != want {
+		t.Fatalf("SKILL.md diff = %q, want %q", files[0].Diff, want)
+	}
+	if files[0].Additions != 1 || files[0].Deletions != 1 {
+		t.Fatalf("SKILL.md stats = +%d -%d, want +1 -1", files[0].Additions, files[0].Deletions)
+	}
+	if !strings.HasPrefix(files[1].Diff, "--- /dev/null\n+++ b/added.md\n") {
+		t.Fatalf("added.md diff = %q", files[1].Diff)
+	}
+	if files[2].Omitted != DiffOmittedBinary || files[2].Diff != "" {
+		t.Fatalf("logo.png omitted = %q, diff = %q; want binary", files[2].Omitted, files[2].Diff)
+	}
+	if files[3].Omitted != DiffOmittedTooLarge {
+		t.Fatalf("huge.txt omitted = %q, want too_large", files[3].Omitted)
+	}
+}
+
+func TestRenderFileDiffs_Budget(t *testing.T) {
+	t.Parallel()
+
+	size := func(n int64) *int64 { return &n }
+	files := []FileDiff{
+		{Path: "a.txt", Status: "added", NewSize: size(2)},
+		{Path: "b.txt", Status: "added", NewSize: size(2)},
+		{Path: "c.txt", Status: "added", NewSize: size(2)},
+	}
+	newContent := map[string][]byte{"a.txt": []byte("a\n"), "b.txt": []byte("b\n"), "c.txt": []byte("c\n")}
+	read := map[string]bool{}
+
+	if err := renderFileDiffs(files, mapReader(nil, nil), mapReader(newContent, read), 50); err != nil {
+		t.Fatalf("renderFileDiffs() error = %v", err)
+	}
+
+	if files[0].Diff == "" {
+		t.Fatal("a.txt diff is empty, want it within budget")
+	}
+	if files[1].Omitted != DiffOmittedLimit {
+		t.Fatalf("b.txt omitted = %q, want limit", files[1].Omitted)
+	}
+	if files[2].Omitted != DiffOmittedLimit || read["c.txt"] {
+		t.Fatalf("c.txt omitted = %q, read = %v; want limit without reading", files[2].Omitted, read["c.txt"])
+	}
+}
+
+// mapReader serves file contents from m and records the paths read.
+func mapReader(m map[string][]byte, read map[string]bool) fileReader {
+	return func(path string) ([]byte, bool, error) {
+		if read != nil {
+			read[path] = true
+		}
+		body, ok := m[path]
+		return body, ok && len(body) <= maxDiffFileBytes, nil
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"hermit/internal/storage"
+	"hermit/internal/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// DefaultBlobGCGracePeriod protects blobs written by a publish or proxy
+	// fetch whose asset row is not committed yet.
+	DefaultBlobGCGracePeriod = 24 * time.Hour
+	// maxReportedOrphans caps the keys listed in a GC report.
+	maxReportedOrphans = 100
+)
+
+type BlobGCOptions struct {
+	DryRun      bool
+	GracePeriod time.Duration
+}
+
+type BlobGCReport struct {
+	DryRun      bool     `json:"dryRun"`
+	GracePeriod string   `json:"gracePeriod"`
+	Scanned     int      `json:"scanned"`
+	Referenced  int      `json:"referenced"`
+	Recent      int      `json:"recent"`
+	Orphaned    int      `json:"orphaned"`
+	Deleted     int      `json:"deleted"`
+	FreedBytes  int64    `json:"freedBytes"`
+	Orphans     []string `json:"orphans"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// PurgeSkill hard-deletes a skill and everything recorded about it. Its
+// blobs are removed by the next blob GC.
+func (s *Service) PurgeSkill(ctx context.Context, repoID string, slug string) error {
+	repo, err := s.getRepositoryByID(ctx, repoID)
+	if err != nil {
+		return err
+	}
+	slug = normalizeSlug(slug)
+	if slug == "" {
+		return fmt.Errorf("%w: slug required", ErrInvalidInput)
+	}
+	if err := s.store.PurgeSkill(ctx, repo.ID, slug); err != nil {
+		if store.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// PurgeSkillVersion hard-deletes one version. latest moves to the next
+// highest version if it pointed at the purged one; other tags pointing at
+// it stop resolving.
+func (s *Service) PurgeSkillVersion(ctx context.Context, repoID string, slug string, version string) error {
+	repo, err := s.getRepositoryByID(ctx, repoID)
+	if err != nil {
+		return err
+	}
+	slug = normalizeSlug(slug)
+	version = strings.TrimSpace(version)
+	if slug == "" || version == "" {
+		return fmt.Errorf("%w: slug and version required", ErrInvalidInput)
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	packageID, err := s.store.PurgeVersionTx(ctx, tx, repo.ID, slug, version)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if err := s.store.RepointLatestTagTx(ctx, tx, packageID, &version); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// CollectBlobGarbage deletes blobs that no asset references (mark and
+// sweep). Blobs written within the grace period are kept. With DryRun set
+// it only reports what it would delete.
+func (s *Service) CollectBlobGarbage(ctx context.Context, opts BlobGCOptions) (BlobGCReport, error) {
+	if opts.GracePeriod < 0 {
+		return BlobGCReport{}, fmt.Errorf("%w: grace period must not be negative", ErrInvalidInput)
+	}
+	release, ok, err := s.store.TryLockBlobGC(ctx)
+	if err != nil {
+		return BlobGCReport{}, err
+	}
+	if !ok {
+		return BlobGCReport{}, fmt.Errorf("%w: blob GC is already running", ErrConflict)
+	}
+	defer release()
+
+	// Blobs are listed before references are loaded, so an asset committed
+	// in between is still seen as a reference.
+	var blobs []storage.BlobInfo
+	if err := s.blobs.List(ctx, func(b storage.BlobInfo) error {
+		blobs = append(blobs, b)
+		return nil
+	}); err != nil {
+		return BlobGCReport{}, fmt.Errorf("list blobs: %w", err)
+	}
+	referenced, err := s.store.ListReferencedBlobs(ctx)
+	if err != nil {
+		return BlobGCReport{}, err
+	}
+
+	report := BlobGCReport{
+		DryRun:      opts.DryRun,
+		GracePeriod: opts.GracePeriod.String(),
+		Scanned:     len(blobs),
+		Orphans:     []string{},
+	}
+	cutoff := time.Now().Add(-opts.GracePeriod)
+	orphans, recent := selectOrphanBlobs(blobs, referenced, cutoff)
+	report.Recent = recent
+	report.Referenced = len(blobs) - len(orphans) - recent
+	report.Orphaned = len(orphans)
+
+	for _, b := range orphans {
+		if len(report.Orphans) < maxReportedOrphans {
+			report.Orphans = append(report.Orphans, b.Key)
+		}
+		if opts.DryRun {
+			report.FreedBytes += b.Size
+			continue
+		}
+		deleted, err := s.deleteOrphanBlob(ctx, b.Key, cutoff)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", b.Key, err))
+			continue
+		}
+		if deleted {
+			report.Deleted++
+			report.FreedBytes += b.Size
+		}
+	}
+	return report, nil
+}
+
+// deleteOrphanBlob deletes key unless it was written after cutoff or an
+// asset references it. A publish may have rewritten or reused the blob
+// since it was listed, so both are checked again while writers are kept
+// from committing assets (see claimBlob).
+func (s *Service) deleteOrphanBlob(ctx context.Context, key string, cutoff time.Time) (bool, error) {
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.store.LockBlobWritesTx(ctx, tx); err != nil {
+		return false, err
+	}
+	info, err := s.blobs.Stat(ctx, key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if info.ModTime.After(cutoff) {
+		return false, nil
+	}
+	referenced, err := s.store.IsBlobReferencedTx(ctx, tx, key)
+	if err != nil || referenced {
+		return false, err
+	}
+	if err := s.blobs.Delete(ctx, key); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// claimBlob keeps blob GC from deleting key until tx ends, and checks that
+// it has not deleted the blob since it was written. Call it in the
+// transaction that inserts the asset pointing at key.
+func (s *Service) claimBlob(ctx context.Context, tx pgx.Tx, key string) error {
+	if err := s.store.LockBlobWriteSharedTx(ctx, tx); err != nil {
+		return err
+	}
+	if _, err := s.blobs.Stat(ctx, key); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: blob was removed by garbage collection, retry", ErrConflict)
+		}
+		return err
+	}
+	return nil
+}
+
+// selectOrphanBlobs returns the unreferenced blobs last written before
+// cutoff, sorted by key, and how many unreferenced blobs are newer.
+func selectOrphanBlobs(blobs []storage.BlobInfo, referenced map[string]struct{}, cutoff time.Time) ([]storage.BlobInfo, int) {
+	var orphans []storage.BlobInfo
+	recent := 0
+	for _, b := range blobs {
+		if _, ok := referenced[b.Key]; ok {
+			continue
+		}
+		if b.ModTime.After(cutoff) {
+			recent++
+			continue
+		}
+		orphans = append(orphans, b)
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Key < orphans[j].Key })
+	return orphans, recent
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"hermit/internal/storage"
+)
+
+func TestSelectOrphanBlobs(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	cutoff := now.Add(-time.Hour)
+	blobs := []storage.BlobInfo{
+		{Key: "sha256/cc/c", Size: 3, ModTime: now.Add(-2 * time.Hour)},
+		{Key: "sha256/aa/a", Size: 1, ModTime: now.Add(-2 * time.Hour)},
+		{Key: "sha256/bb/b", Size: 2, ModTime: now.Add(-2 * time.Hour)},
+		{Key: "sha256/dd/d", Size: 4, ModTime: now},
+		{Key: "sha256/ee/e", Size: 5, ModTime: cutoff},
+	}
+	referenced := map[string]struct{}{"sha256/bb/b": {}}
+
+	orphans, recent := selectOrphanBlobs(blobs, referenced, cutoff)
+	var keys []string
+	for _, b := range orphans {
+		keys = append(keys, b.Key)
+	}
+	want := []string{"sha256/aa/a", "sha256/cc/c", "sha256/ee/e"}
+	if len(keys) != len(want) {
+		t.Fatalf("selectOrphanBlobs() = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("selectOrphanBlobs() = %v, want %v", keys, want)
+		}
+	}
+	if recent != 1 {
+		t.Fatalf("recent = %d, want 1", recent)
+	}
+}
+
+func TestSelectOrphanBlobs_AllReferenced(t *testing.T) {
+	t.Parallel()
+	blobs := []storage.BlobInfo{{Key: "sha256/aa/a"}}
+	referenced := map[string]struct{}{"sha256/aa/a": {}}
+	orphans, recent := selectOrphanBlobs(blobs, referenced, time.Now())
+	if len(orphans) != 0 || recent != 0 {
+		t.Fatalf("selectOrphanBlobs() = %v, %d, want none", orphans, recent)
+	}
+}
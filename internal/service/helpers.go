@@ -2,7 +2,6 @@ package service
 
 import (
 	"archive/zip"
-	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
@@ -11,27 +10,31 @@ import (
 	"io"
 	"mime"
 	"path"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"hermit/internal/semver"
 	"hermit/internal/store"
 )
 
-func buildZipArchive(files []PublishFileInput) ([]byte, []map[string]any, error) {
-	var buf bytes.Buffer
-	zw := zip.NewWriter(&buf)
+// writeZipArchive streams files into a zip written to w and returns their
+// descriptors. Each file is read once, hashing it while it is compressed,
+// so memory use does not grow with the upload size.
+func writeZipArchive(w io.Writer, files []PublishFileInput) ([]map[string]any, error) {
+	zw := zip.NewWriter(w)
 	descriptors := make([]map[string]any, 0, len(files))
 
 	for _, f := range files {
 		safePath := sanitizeArchivePath(f.Path)
 		if safePath == "" {
 			_ = zw.Close()
-			return nil, nil, fmt.Errorf("%w: invalid file path %q", ErrInvalidInput, f.Path)
+			return nil, fmt.Errorf("%w: invalid file path %q", ErrInvalidInput, f.Path)
 		}
 
-		descriptors = append(descriptors, buildFileDescriptor(safePath, f.ContentType, f.Bytes))
-
 		header := &zip.FileHeader{
 			Name:   safePath,
 			Method: zip.Deflate,
@@ -40,18 +43,37 @@ func buildZipArchive(files []PublishFileInput) ([]byte, []map[string]any, error)
 		writer, err := zw.CreateHeader(header)
 		if err != nil {
 			_ = zw.Close()
-			return nil, nil, err
+			return nil, err
 		}
-		if _, err := writer.Write(f.Bytes); err != nil {
+		size, sum, err := copyPublishFile(writer, f)
+		if err != nil {
 			_ = zw.Close()
-			return nil, nil, err
+			return nil, err
 		}
+		descriptors = append(descriptors, buildFileDescriptor(safePath, f.ContentType, size, sum))
 	}
 
 	if err := zw.Close(); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	return buf.Bytes(), descriptors, nil
+	return descriptors, nil
+}
+
+// copyPublishFile copies the content of f to w and returns its size and
+// hex SHA-256.
+func copyPublishFile(w io.Writer, f PublishFileInput) (int64, string, error) {
+	rc, err := f.open()
+	if err != nil {
+		return 0, "", fmt.Errorf("open %s: %w", f.Path, err)
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, h), rc)
+	if err != nil {
+		return 0, "", fmt.Errorf("read %s: %w", f.Path, err)
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
 }
 
 func describeZipArchiveFiles(r io.ReaderAt, size int64) ([]map[string]any, error) {
@@ -71,16 +93,17 @@ func describeZipArchiveFiles(r io.ReaderAt, size int64) ([]map[string]any, error
 		if err != nil {
 			return nil, err
 		}
-		data, readErr := io.ReadAll(rc)
+		h := sha256.New()
+		n, copyErr := io.Copy(h, rc)
 		closeErr := rc.Close()
-		if readErr != nil {
-			return nil, readErr
+		if copyErr != nil {
+			return nil, copyErr
 		}
 		if closeErr != nil {
 			return nil, closeErr
 		}
 
-		descriptors = append(descriptors, buildFileDescriptor(safePath, mime.TypeByExtension(path.Ext(safePath)), data))
+		descriptors = append(descriptors, buildFileDescriptor(safePath, mime.TypeByExtension(path.Ext(safePath)), n, hex.EncodeToString(h.Sum(nil))))
 	}
 
 	sort.SliceStable(descriptors, func(i, j int) bool {
@@ -89,12 +112,11 @@ func describeZipArchiveFiles(r io.ReaderAt, size int64) ([]map[string]any, error
 	return descriptors, nil
 }
 
-func buildFileDescriptor(path string, contentType string, body []byte) map[string]any {
-	h := sha256.Sum256(body)
+func buildFileDescriptor(path string, contentType string, size int64, sha256Hex string) map[string]any {
 	desc := map[string]any{
 		"path":   path,
-		"size":   len(body),
-		"sha256": hex.EncodeToString(h[:]),
+		"size":   size,
+		"sha256": sha256Hex,
 	}
 	contentType = strings.TrimSpace(contentType)
 	if contentType != "" {
@@ -105,14 +127,111 @@ func buildFileDescriptor(path string, contentType string, body []byte) map[strin
 
 func hasSkillManifest(files []PublishFileInput) bool {
 	for _, f := range files {
-		lower := strings.ToLower(sanitizeArchivePath(f.Path))
-		if lower == "skill.md" || lower == "skills.md" {
+		if isSkillManifestPath(f.Path) {
 			return true
 		}
 	}
 	return false
 }
 
+// maxIndexedReadmeBytes caps how much of SKILL.md is stored for search.
+const maxIndexedReadmeBytes = 64 << 10
+
+func isSkillManifestPath(p string) bool {
+	lower := strings.ToLower(sanitizeArchivePath(p))
+	return lower == "skill.md" || lower == "skills.md"
+}
+
+// maxPublishManifestBytes caps the SKILL.md a publish may carry; it is
+// held in memory to parse its frontmatter.
+const maxPublishManifestBytes = 1 << 20
+
+// readPublishManifest returns the content of the SKILL.md among files, or
+// nil when there is none.
+func readPublishManifest(files []PublishFileInput) ([]byte, error) {
+	for _, f := range files {
+		if !isSkillManifestPath(f.Path) {
+			continue
+		}
+		rc, err := f.open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", f.Path, err)
+		}
+		data, readErr := io.ReadAll(io.LimitReader(rc, maxPublishManifestBytes+1))
+		_ = rc.Close()
+		if readErr != nil {
+			return nil, fmt.Errorf("read %s: %w", f.Path, readErr)
+		}
+		if len(data) > maxPublishManifestBytes {
+			return nil, fmt.Errorf("%w: SKILL.md exceeds %d bytes", ErrInvalidInput, maxPublishManifestBytes)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// readSkillManifest returns the (truncated) SKILL.md stored in a zip
+// archive, or "" when there is none.
+func readSkillManifest(r io.ReaderAt, size int64) (string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", fmt.Errorf("%w: artifact is not a zip", ErrInvalidInput)
+	}
+	for _, zf := range zr.File {
+		if !isSkillManifestPath(zf.Name) {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return "", err
+		}
+		data, readErr := io.ReadAll(io.LimitReader(rc, maxIndexedReadmeBytes+utf8.UTFMax))
+		closeErr := rc.Close()
+		if readErr != nil {
+			return "", readErr
+		}
+		if closeErr != nil {
+			return "", closeErr
+		}
+		return truncateUTF8(string(data), maxIndexedReadmeBytes), nil
+	}
+	return "", nil
+}
+
+// truncateUTF8 cuts s to at most n bytes. A rune split by the cut, invalid
+// sequences and NUL bytes are dropped, since Postgres text rejects them.
+func truncateUTF8(s string, n int) string {
+	if len(s) > n {
+		s = s[:n]
+	}
+	return strings.ToValidUTF8(strings.ReplaceAll(s, "\x00", ""), "")
+}
+
+// maxSearchTerms bounds the tsquery built from user input.
+const maxSearchTerms = 16
+
+// buildSearchQuery turns free text into a to_tsquery('simple', ...)
+// expression that requires every word, each as a prefix: "pdf conv" matches
+// "PDF converter". It returns "" when the text has no searchable words.
+func buildSearchQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]struct{}, len(words))
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		if _, dup := seen[w]; dup {
+			continue
+		}
+		seen[w] = struct{}{}
+		terms = append(terms, w+":*")
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return strings.Join(terms, " & ")
+}
+
 func sanitizeArchivePath(p string) string {
 	p = strings.TrimSpace(strings.ReplaceAll(p, "\\", "/"))
 	p = strings.TrimPrefix(p, "/")
@@ -161,6 +280,28 @@ func normalizeSlug(slug string) string {
 	return slug
 }
 
+var partialVersionPattern = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+)?$`)
+
+// isPartialVersion reports whether version is a partial version such as
+// "1.0". It reads as a range, but upstream registries may also use it as
+// the exact name of a version, which then takes precedence.
+func isPartialVersion(version string) bool {
+	return partialVersionPattern.MatchString(strings.TrimSpace(version))
+}
+
+// parseVersionRange returns the version range in version, or nil when
+// version is empty, one exact version or not a valid range.
+func parseVersionRange(version string) *semver.Range {
+	if !semver.IsRange(version) {
+		return nil
+	}
+	rng, err := semver.ParseRange(version)
+	if err != nil {
+		return nil
+	}
+	return &rng
+}
+
 func sortSkillItems(items []store.SkillListItem, sortBy string) {
 	sort.SliceStable(items, func(i, j int) bool {
 		a := items[i]
@@ -171,8 +312,9 @@ func sortSkillItems(items []store.SkillListItem, sortBy string) {
 				return a.Downloads > b.Downloads
 			}
 		case "stars":
-			if a.Stars != b.Stars {
-				return a.Stars > b.Stars
+			aStars, bStars := a.Stars+a.UpstreamStars, b.Stars+b.UpstreamStars
+			if aStars != bStars {
+				return aStars > bStars
 			}
 		case "installsCurrent":
 			if a.InstallsCurrent != b.InstallsCurrent {
@@ -183,6 +325,9 @@ func sortSkillItems(items []store.SkillListItem, sortBy string) {
 				return a.InstallsAllTime > b.InstallsAllTime
 			}
 		case "trending":
+			if a.TrendingScore != b.TrendingScore {
+				return a.TrendingScore > b.TrendingScore
+			}
 			if a.Downloads != b.Downloads {
 				return a.Downloads > b.Downloads
 			}
@@ -194,3 +339,33 @@ func sortSkillItems(items []store.SkillListItem, sortBy string) {
 		return a.UpdatedAt.After(b.UpdatedAt)
 	})
 }
+
+// sortSearchResults orders merged group results. Member scores come from the
+// same ts_rank normalization, so they compare directly; ties go to the most
+// recently updated skill, then by slug.
+func sortSearchResults(items []store.SkillSearchResult) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		var ta, tb time.Time
+		if a.UpdatedAt != nil {
+			ta = *a.UpdatedAt
+		}
+		if b.UpdatedAt != nil {
+			tb = *b.UpdatedAt
+		}
+		if !ta.Equal(tb) {
+			return ta.After(tb)
+		}
+		var sa, sb string
+		if a.Slug != nil {
+			sa = *a.Slug
+		}
+		if b.Slug != nil {
+			sb = *b.Slug
+		}
+		return sa < sb
+	})
+}
@@ -26,10 +26,7 @@ func TestDescribeZipArchiveFiles(t *testing.T) {
 		},
 	}
 
-	archiveBytes, _, err := buildZipArchive(files)
-	if err != nil {
-		t.Fatalf("buildZipArchive() error = %v", err)
-	}
+	archiveBytes := zipArchive(t, files)
 
 	descriptors, err := describeZipArchiveFiles(bytes.NewReader(archiveBytes), int64(len(archiveBytes)))
 	if err != nil {
@@ -81,10 +78,7 @@ func TestDescribeZipArchiveFiles_BinaryFile_NoInlineContent(t *testing.T) {
 		},
 	}
 
-	archiveBytes, _, err := buildZipArchive(files)
-	if err != nil {
-		t.Fatalf("buildZipArchive() error = %v", err)
-	}
+	archiveBytes := zipArchive(t, files)
 
 	descriptors, err := describeZipArchiveFiles(bytes.NewReader(archiveBytes), int64(len(archiveBytes)))
 	if err != nil {
@@ -100,6 +94,15 @@ func TestDescribeZipArchiveFiles_BinaryFile_NoInlineContent(t *testing.T) {
 	}
 }
 
+func zipArchive(t *testing.T, files []PublishFileInput) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := writeZipArchive(&buf, files); err != nil {
+		t.Fatalf("writeZipArchive() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
 func shaHex(data []byte) string {
 	sum := sha256.Sum256(data)
 	return hex.EncodeToString(sum[:])
@@ -217,15 +220,40 @@ func TestNormalizeSlug(t *testing.T) {
 	}
 }
 
+func TestIsPartialVersion(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"1", true},
+		{"1.0", true},
+		{" v2.3 ", true},
+		{"1.0.0", false},
+		{"^1.0", false},
+		{"1.x", false},
+		{">=1.0 <2", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+			if got := isPartialVersion(tt.input); got != tt.want {
+				t.Fatalf("isPartialVersion(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestBuildFileDescriptor(t *testing.T) {
 	t.Parallel()
 	body := []byte("hello world")
-	desc := buildFileDescriptor("test.txt", "text/plain", body)
+	desc := buildFileDescriptor("test.txt", "text/plain", int64(len(body)), shaHex(body))
 
 	if desc["path"] != "test.txt" {
 		t.Fatalf("path = %v, want test.txt", desc["path"])
 	}
-	if desc["size"] != len(body) {
+	if desc["size"] != int64(len(body)) {
 		t.Fatalf("size = %v, want %d", desc["size"], len(body))
 	}
 	if desc["sha256"] != shaHex(body) {
@@ -238,7 +266,7 @@ func TestBuildFileDescriptor(t *testing.T) {
 
 func TestBuildFileDescriptor_EmptyContentType(t *testing.T) {
 	t.Parallel()
-	desc := buildFileDescriptor("file.bin", "", []byte{0x00})
+	desc := buildFileDescriptor("file.bin", "", 1, shaHex([]byte{0x00}))
 	if _, ok := desc["contentType"]; ok {
 		t.Fatal("contentType should be absent for empty content type")
 	}
@@ -290,3 +318,99 @@ func TestSortSkillItems_DefaultSort(t *testing.T) {
 		t.Fatalf("third = %q, want same-time-b", items[2].Slug)
 	}
 }
+
+func TestSortSkillItems_Trending(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	items := []store.SkillListItem{
+		{Skill: store.Skill{Slug: "popular-stale", Downloads: 1000, TrendingScore: 0.5, UpdatedAt: now}},
+		{Skill: store.Skill{Slug: "hot", Downloads: 20, TrendingScore: 12.25, UpdatedAt: now}},
+		{Skill: store.Skill{Slug: "warm", Downloads: 40, TrendingScore: 3, UpdatedAt: now}},
+	}
+
+	sortSkillItems(items, "trending")
+
+	got := []string{items[0].Slug, items[1].Slug, items[2].Slug}
+	want := []string{"hot", "warm", "popular-stale"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestTrendingConfigNormalized(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		cfg          TrendingConfig
+		wantHalfLife float64
+		wantWindow   int
+	}{
+		{name: "defaults", cfg: TrendingConfig{}, wantHalfLife: 3, wantWindow: 30},
+		{name: "custom", cfg: TrendingConfig{HalfLife: 36 * time.Hour, Window: 7 * 24 * time.Hour}, wantHalfLife: 1.5, wantWindow: 7},
+		{name: "partial day window rounds up", cfg: TrendingConfig{Window: 36 * time.Hour}, wantHalfLife: 3, wantWindow: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			halfLife, window := tt.cfg.normalized()
+			if halfLife != tt.wantHalfLife || window != tt.wantWindow {
+				t.Fatalf("normalized() = (%v, %d), want (%v, %d)", halfLife, window, tt.wantHalfLife, tt.wantWindow)
+			}
+		})
+	}
+}
+
+func TestBuildSearchQuery(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "single word", input: "pdf", want: "pdf:*"},
+		{name: "multi word", input: "PDF  Converter", want: "pdf:* & converter:*"},
+		{name: "slug splits on hyphen", input: "pdf-tools", want: "pdf:* & tools:*"},
+		{name: "operators are stripped", input: "a & !b | (c):*", want: "a:* & b:* & c:*"},
+		{name: "duplicates dropped", input: "git git", want: "git:*"},
+		{name: "unicode letters", input: "übersetzung", want: "übersetzung:*"},
+		{name: "nothing searchable", input: " -&- ", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := buildSearchQuery(tt.input); got != tt.want {
+				t.Fatalf("buildSearchQuery(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSkillManifest(t *testing.T) {
+	t.Parallel()
+
+	archive := zipArchive(t, []PublishFileInput{
+		{Path: "SKILL.md", Bytes: []byte("# Demo\nConverts PDFs.")},
+		{Path: "scripts/run.sh", Bytes: []byte("echo hi")},
+	})
+	got, err := readSkillManifest(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("readSkillManifest() error = %v", err)
+	}
+	if got != "# Demo\nConverts PDFs." {
+		t.Fatalf("readSkillManifest() = %q", got)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	t.Parallel()
+	if got := truncateUTF8("héllo", 2); got != "h" {
+		t.Fatalf("truncateUTF8() = %q, want %q (split rune dropped)", got, "h")
+	}
+	if got := truncateUTF8("a\x00b", 10); got != "ab" {
+		t.Fatalf("truncateUTF8() = %q, want %q", got, "ab")
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"hermit/internal/auth"
+	"hermit/internal/store"
+)
+
+// maxClientIDLength bounds the client-supplied strings stored per install.
+const maxClientIDLength = 128
+
+// InstallReport is what a client sends when it installs or uninstalls a
+// skill. ClientID identifies the installation (e.g. a CLI's workdir id);
+// authenticated callers without one are identified by their subject.
+type InstallReport struct {
+	ClientID string
+	Version  string
+}
+
+type InstallView struct {
+	InstallsCurrent int64 `json:"installsCurrent"`
+	InstallsAllTime int64 `json:"installsAllTime"`
+}
+
+// ReportSkillInstall records that a client installed slug.
+func (s *Service) ReportSkillInstall(ctx context.Context, repo store.Repository, actor auth.Actor, slug string, report InstallReport) (InstallView, error) {
+	return s.reportInstall(ctx, repo, actor, slug, report, true)
+}
+
+// ReportSkillUninstall records that a client removed slug.
+func (s *Service) ReportSkillUninstall(ctx context.Context, repo store.Repository, actor auth.Actor, slug string, report InstallReport) (InstallView, error) {
+	return s.reportInstall(ctx, repo, actor, slug, report, false)
+}
+
+func (s *Service) reportInstall(
+	ctx context.Context,
+	repo store.Repository,
+	actor auth.Actor,
+	slug string,
+	report InstallReport,
+	installed bool,
+) (InstallView, error) {
+	clientID, err := installClientID(report.ClientID, actor)
+	if err != nil {
+		return InstallView{}, err
+	}
+	skill, err := s.lookupSkill(ctx, repo, actor, slug)
+	if err != nil {
+		return InstallView{}, err
+	}
+
+	var version *string
+	if v := strings.TrimSpace(report.Version); v != "" {
+		if len(v) > maxClientIDLength {
+			return InstallView{}, fmt.Errorf("%w: version must be at most %d characters", ErrInvalidInput, maxClientIDLength)
+		}
+		version = &v
+	}
+
+	var counts store.InstallCounts
+	if installed {
+		counts, err = s.store.RecordSkillInstall(ctx, skill.ID, clientID, version)
+	} else {
+		counts, err = s.store.RecordSkillUninstall(ctx, skill.ID, clientID)
+	}
+	if err != nil {
+		if store.IsNotFound(err) {
+			return InstallView{}, ErrNotFound
+		}
+		return InstallView{}, err
+	}
+	return InstallView{InstallsCurrent: counts.Current, InstallsAllTime: counts.AllTime}, nil
+}
+
+// lookupSkill finds slug in repo, or in the first group member carrying it.
+func (s *Service) lookupSkill(ctx context.Context, repo store.Repository, actor auth.Actor, slug string) (store.Skill, error) {
+	slug = normalizeSlug(slug)
+	if slug == "" {
+		return store.Skill{}, fmt.Errorf("%w: slug required", ErrInvalidInput)
+	}
+	targetRepo, err := s.findSkillRepository(ctx, repo, actor, slug)
+	if err != nil {
+		return store.Skill{}, err
+	}
+	skill, err := s.store.GetSkill(ctx, targetRepo.ID, slug)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return store.Skill{}, ErrNotFound
+		}
+		return store.Skill{}, err
+	}
+	return skill, nil
+}
+
+// installClientID returns the key installs are deduplicated by. A signed-in
+// caller's client ids are scoped to their subject, so nobody can report for
+// another account's client; only anonymous client ids share one namespace,
+// kept apart from subjects.
+func installClientID(raw string, actor auth.Actor) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) > maxClientIDLength {
+		return "", fmt.Errorf("%w: clientId must be at most %d characters", ErrInvalidInput, maxClientIDLength)
+	}
+	subject := strings.TrimSpace(actor.Subject)
+	if actor.Anonymous || subject == "" {
+		if raw == "" {
+			return "", fmt.Errorf("%w: clientId required", ErrInvalidInput)
+		}
+		return "client:" + raw, nil
+	}
+	if raw == "" {
+		return "user:" + subject, nil
+	}
+	return "user:" + subject + " client:" + raw, nil
+}
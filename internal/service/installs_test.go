@@ -0,0 +1,48 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"hermit/internal/auth"
+)
+
+func TestInstallClientID(t *testing.T) {
+	t.Parallel()
+
+	user := auth.Actor{Subject: "alice"}
+	anonymous := auth.Actor{Anonymous: true}
+
+	tests := []struct {
+		name    string
+		raw     string
+		actor   auth.Actor
+		want    string
+		wantErr bool
+	}{
+		{name: "explicit client id", raw: " cli-123 ", actor: anonymous, want: "client:cli-123"},
+		{name: "client id scoped to subject", raw: "cli-123", actor: user, want: "user:alice client:cli-123"},
+		{name: "subject fallback", raw: "", actor: user, want: "user:alice"},
+		{name: "anonymous without client id", raw: "  ", actor: anonymous, wantErr: true},
+		{name: "too long", raw: strings.Repeat("x", maxClientIDLength+1), actor: user, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := installClientID(tt.raw, tt.actor)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Fatalf("installClientID() error = %v, want ErrInvalidInput", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("installClientID() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("installClientID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
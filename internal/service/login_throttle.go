@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"hermit/internal/extauth"
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ProviderTypeLocal marks logins against the local users table in the
+	// login history.
+	ProviderTypeLocal = "local"
+
+	// loginFreeFailures is the number of failures allowed before retries are
+	// delayed. The delay then doubles with every failure up to
+	// maxLoginDelay.
+	loginFreeFailures = 3
+	maxLoginDelay     = time.Minute
+
+	// maxLoginAttemptReason bounds the failure reason stored per attempt.
+	maxLoginAttemptReason = 256
+)
+
+// errInvalidCredentials is the only local login failure that counts toward
+// a lockout.
+var errInvalidCredentials = errors.New("invalid username or password")
+
+// LoginPolicy configures brute-force protection for local and LDAP logins.
+// Failures are counted per username and per client IP; a counter whose
+// last failure is older than FailureWindow starts over. A username reaching
+// MaxFailures, or an IP reaching MaxIPFailures, is locked for
+// LockoutDuration; 0 disables either lockout.
+type LoginPolicy struct {
+	MaxFailures     int
+	MaxIPFailures   int
+	FailureWindow   time.Duration
+	LockoutDuration time.Duration
+}
+
+// LoginBlockedError is returned while a username or client IP must not try
+// to log in again, either because of a lockout or a progressive delay.
+type LoginBlockedError struct {
+	Until  time.Time
+	Locked bool
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Locked {
+		return "too many failed login attempts, login is temporarily locked"
+	}
+	return "too many failed login attempts, try again later"
+}
+
+// RetryAfter returns how long the caller has to wait.
+func (e *LoginBlockedError) RetryAfter(now time.Time) time.Duration {
+	return max(e.Until.Sub(now), 0)
+}
+
+// LoginAttemptQuery filters ListLoginAttempts.
+type LoginAttemptQuery struct {
+	Username string
+	ClientIP string
+	Outcome  string
+}
+
+// AuthenticateLDAP verifies username and password against LDAP under the
+// same brute-force protection as local logins.
+func (s *Service) AuthenticateLDAP(ctx context.Context, ldapAuth *extauth.LDAPAuthenticator, username, password string, client SessionClient) (*extauth.IdentityResult, error) {
+	var identity *extauth.IdentityResult
+	err := s.guardLogin(ctx, ProviderTypeLDAP, username, client, func() (bool, error) {
+		var err error
+		identity, err = ldapAuth.Authenticate(username, password)
+		return errors.Is(err, extauth.ErrInvalidCredentials), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// guardLogin runs authenticate unless the username or client IP is
+// blocked, and records the attempt. authenticate reports whether its error
+// is a wrong password or unknown user; only those count as failures, so
+// an unreachable directory does not lock anyone out.
+//
+// The attempt is counted as a failure before authenticate runs and given
+// back unless it fails, so parallel attempts cannot all pass the check
+// before any of them is counted.
+func (s *Service) guardLogin(ctx context.Context, provider, username string, client SessionClient, authenticate func() (bool, error)) error {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return fmt.Errorf("%w: username and password required", ErrInvalidInput)
+	}
+	userKey := strings.ToLower(username)
+	ip := strings.TrimSpace(client.IP)
+
+	// Postgres keeps microseconds; releaseLoginFailures matches blocks by
+	// exact time.
+	now := time.Now().Truncate(time.Microsecond)
+	reserved, blocked, err := s.reserveLoginFailure(ctx, userKey, ip, now)
+	if err != nil {
+		return err
+	}
+	if blocked != nil {
+		s.recordLoginAttempt(ctx, provider, username, client, store.LoginOutcomeBlocked, blocked.Error())
+		return blocked
+	}
+
+	counted, authErr := authenticate()
+	if errors.Is(authErr, errSecondFactorRequired) {
+		// The password was right, but the failures so far are kept until
+		// the second factor is too.
+		s.releaseLoginFailures(ctx, reserved)
+		s.recordLoginAttempt(ctx, provider, username, client, store.LoginOutcomeSecondFactor, "")
+		return authErr
+	}
+	if authErr == nil {
+		if _, err := s.store.ClearLoginThrottle(ctx, store.LoginThrottleUser, userKey); err != nil {
+			return err
+		}
+		s.releaseLoginFailures(ctx, slices.DeleteFunc(reserved, func(t store.LoginThrottle) bool {
+			return t.Kind == store.LoginThrottleUser
+		}))
+		s.recordLoginAttempt(ctx, provider, username, client, store.LoginOutcomeSuccess, "")
+		return nil
+	}
+
+	s.recordLoginAttempt(ctx, provider, username, client, store.LoginOutcomeFailed, authErr.Error())
+	if !counted {
+		s.releaseLoginFailures(ctx, reserved)
+		return authErr
+	}
+	for _, t := range reserved {
+		if t.Locked {
+			log.Printf("[auth] login locked for %s %q after %d failed attempts", t.Kind, t.Key, t.Failures)
+		}
+	}
+	return authErr
+}
+
+type loginThrottleKey struct {
+	kind, key   string
+	maxFailures int
+}
+
+// reserveLoginFailure counts a failure for the username and client IP and
+// sets the block that follows it, unless either is already blocked, in
+// which case it returns the block in effect, preferring a lockout over a
+// delay. Both counters stay locked until the decision is stored.
+func (s *Service) reserveLoginFailure(ctx context.Context, userKey, ip string, now time.Time) ([]store.LoginThrottle, *LoginBlockedError, error) {
+	// The username is always locked before the IP, so concurrent logins
+	// cannot deadlock.
+	keys := []loginThrottleKey{{store.LoginThrottleUser, userKey, s.login.MaxFailures}}
+	if ip != "" {
+		keys = append(keys, loginThrottleKey{store.LoginThrottleIP, ip, s.login.MaxIPFailures})
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	throttles := make([]store.LoginThrottle, 0, len(keys))
+	var blocked *LoginBlockedError
+	for _, k := range keys {
+		t, err := s.store.LockLoginThrottleTx(ctx, tx, k.kind, k.key)
+		if err != nil {
+			return nil, nil, err
+		}
+		throttles = append(throttles, t)
+		if t.BlockedUntil == nil || !now.Before(*t.BlockedUntil) {
+			continue
+		}
+		if blocked == nil || (t.Locked && !blocked.Locked) || (t.Locked == blocked.Locked && t.BlockedUntil.After(blocked.Until)) {
+			blocked = &LoginBlockedError{Until: *t.BlockedUntil, Locked: t.Locked}
+		}
+	}
+	if blocked != nil {
+		return nil, blocked, tx.Commit(ctx)
+	}
+
+	for i, k := range keys {
+		throttles[i] = nextLoginThrottle(throttles[i], now, s.login.FailureWindow, k.maxFailures, s.login.LockoutDuration)
+		if err := s.store.UpdateLoginThrottleTx(ctx, tx, throttles[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	return throttles, nil, nil
+}
+
+// releaseLoginFailures gives back failures reserved for an attempt that did
+// not fail. A failure that cannot be given back only delays the next login,
+// so it is logged rather than failing this one.
+func (s *Service) releaseLoginFailures(ctx context.Context, reserved []store.LoginThrottle) {
+	for _, t := range reserved {
+		if err := s.store.ReleaseLoginFailure(ctx, t.Kind, t.Key, t.BlockedUntil); err != nil {
+			log.Printf("[auth] release login failure for %s %q: %v", t.Kind, t.Key, err)
+		}
+	}
+}
+
+// nextLoginThrottle counts one more failure on a counter that is not
+// blocked and sets the block that follows it. A counter whose last failure
+// is older than window starts over.
+func nextLoginThrottle(t store.LoginThrottle, now time.Time, window time.Duration, maxFailures int, lockout time.Duration) store.LoginThrottle {
+	if t.LastFailedAt.Before(now.Add(-window)) {
+		t.Failures = 0
+	}
+	t.Failures++
+	t.LastFailedAt = now
+	t.BlockedUntil, t.Locked = nil, false
+	if delay, locked := loginDelay(t.Failures, maxFailures, lockout); delay > 0 {
+		until := now.Add(delay)
+		t.BlockedUntil, t.Locked = &until, locked
+	}
+	return t
+}
+
+// loginDelay returns how long to block further logins after the given
+// number of consecutive failures, and whether the block is a lockout.
+func loginDelay(failures, maxFailures int, lockout time.Duration) (time.Duration, bool) {
+	if maxFailures > 0 && lockout > 0 && failures >= maxFailures {
+		return lockout, true
+	}
+	if failures <= loginFreeFailures {
+		return 0, false
+	}
+	shift := failures - loginFreeFailures - 1
+	if shift >= 6 {
+		return maxLoginDelay, false
+	}
+	return min(time.Second<<shift, maxLoginDelay), false
+}
+
+// recordLoginAttempt stores a login attempt. Failing to record it does not
+// fail the login.
+func (s *Service) recordLoginAttempt(ctx context.Context, provider, username string, client SessionClient, outcome, reason string) {
+	err := s.store.InsertLoginAttempt(ctx, store.LoginAttempt{
+		Provider:  provider,
+		Username:  username,
+		ClientIP:  strings.TrimSpace(client.IP),
+		UserAgent: truncateUTF8(strings.TrimSpace(client.UserAgent), maxSessionUserAgent),
+		Outcome:   outcome,
+		Reason:    truncateUTF8(reason, maxLoginAttemptReason),
+	})
+	if err != nil {
+		log.Printf("[auth] record login attempt for %q: %v", username, err)
+	}
+}
+
+// UnlockUser lifts the lockout and forgets the failed logins of a local
+// user. It reports whether the user had any.
+func (s *Service) UnlockUser(ctx context.Context, userID string) (store.User, bool, error) {
+	id, err := uuid.Parse(strings.TrimSpace(userID))
+	if err != nil {
+		return store.User{}, false, fmt.Errorf("%w: invalid user ID", ErrInvalidInput)
+	}
+	u, err := s.store.GetUserByID(ctx, id)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return store.User{}, false, ErrNotFound
+		}
+		return store.User{}, false, err
+	}
+	cleared, err := s.store.ClearLoginThrottle(ctx, store.LoginThrottleUser, strings.ToLower(u.Username))
+	if err != nil {
+		return store.User{}, false, err
+	}
+	return u, cleared, nil
+}
+
+// ListLoginLockouts returns the usernames and client IPs currently locked
+// out.
+func (s *Service) ListLoginLockouts(ctx context.Context) ([]store.LoginThrottle, error) {
+	lockouts, err := s.store.ListLoginLockouts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if lockouts == nil {
+		lockouts = []store.LoginThrottle{}
+	}
+	return lockouts, nil
+}
+
+// ClearLoginLockout lifts the lockout of a username or client IP, such as
+// an LDAP account that has no local user to unlock.
+func (s *Service) ClearLoginLockout(ctx context.Context, kind, key string) error {
+	kind = strings.TrimSpace(kind)
+	key = strings.TrimSpace(key)
+	switch kind {
+	case store.LoginThrottleUser:
+		key = strings.ToLower(key)
+	case store.LoginThrottleIP:
+	default:
+		return fmt.Errorf("%w: kind must be %q or %q", ErrInvalidInput, store.LoginThrottleUser, store.LoginThrottleIP)
+	}
+	if key == "" {
+		return fmt.Errorf("%w: key required", ErrInvalidInput)
+	}
+	cleared, err := s.store.ClearLoginThrottle(ctx, kind, key)
+	if err != nil {
+		return err
+	}
+	if !cleared {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListLoginAttempts returns recorded login attempts, newest first.
+func (s *Service) ListLoginAttempts(ctx context.Context, q LoginAttemptQuery, limit int, offset int) ([]store.LoginAttempt, error) {
+	outcome := strings.ToLower(strings.TrimSpace(q.Outcome))
+	switch outcome {
+	case "", store.LoginOutcomeSuccess, store.LoginOutcomeSecondFactor, store.LoginOutcomeFailed, store.LoginOutcomeBlocked:
+	default:
+		return nil, fmt.Errorf("%w: invalid outcome %q", ErrInvalidInput, outcome)
+	}
+	attempts, err := s.store.ListLoginAttempts(ctx, store.LoginAttemptFilter{
+		Username: strings.TrimSpace(q.Username),
+		ClientIP: strings.TrimSpace(q.ClientIP),
+		Outcome:  outcome,
+	}, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if attempts == nil {
+		attempts = []store.LoginAttempt{}
+	}
+	return attempts, nil
+}
+
+// PruneLoginHistory deletes login attempts and stale failure counters
+// older than retention.
+func (s *Service) PruneLoginHistory(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.store.PruneLoginRecords(ctx, time.Now().Add(-retention))
+}
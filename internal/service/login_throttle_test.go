@@ -0,0 +1,102 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"hermit/internal/store"
+)
+
+func TestLoginDelay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		failures    int
+		maxFailures int
+		lockout     time.Duration
+		wantDelay   time.Duration
+		wantLocked  bool
+	}{
+		{name: "first failure", failures: 1, maxFailures: 10, lockout: 15 * time.Minute},
+		{name: "last free failure", failures: loginFreeFailures, maxFailures: 10, lockout: 15 * time.Minute},
+		{name: "first delay", failures: loginFreeFailures + 1, maxFailures: 10, lockout: 15 * time.Minute, wantDelay: time.Second},
+		{name: "delay doubles", failures: loginFreeFailures + 3, maxFailures: 10, lockout: 15 * time.Minute, wantDelay: 4 * time.Second},
+		{name: "lockout", failures: 10, maxFailures: 10, lockout: 15 * time.Minute, wantDelay: 15 * time.Minute, wantLocked: true},
+		{name: "past lockout threshold", failures: 12, maxFailures: 10, lockout: 15 * time.Minute, wantDelay: 15 * time.Minute, wantLocked: true},
+		{name: "lockout disabled caps delay", failures: 40, maxFailures: 0, lockout: 15 * time.Minute, wantDelay: maxLoginDelay},
+		{name: "zero lockout duration", failures: 10, maxFailures: 10, lockout: 0, wantDelay: maxLoginDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			delay, locked := loginDelay(tt.failures, tt.maxFailures, tt.lockout)
+			if delay != tt.wantDelay || locked != tt.wantLocked {
+				t.Fatalf("loginDelay(%d) = %v, %v, want %v, %v", tt.failures, delay, locked, tt.wantDelay, tt.wantLocked)
+			}
+		})
+	}
+}
+
+func TestLoginBlockedErrorRetryAfter(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	err := &LoginBlockedError{Until: now.Add(30 * time.Second)}
+	if got := err.RetryAfter(now); got != 30*time.Second {
+		t.Fatalf("RetryAfter() = %v, want 30s", got)
+	}
+	if got := err.RetryAfter(now.Add(time.Minute)); got != 0 {
+		t.Fatalf("RetryAfter() after block = %v, want 0", got)
+	}
+}
+
+func TestNextLoginThrottle(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	window := 15 * time.Minute
+	lockout := 15 * time.Minute
+
+	tests := []struct {
+		name         string
+		failures     int
+		lastFailedAt time.Time
+		wantFailures int
+		wantBlocked  time.Duration
+		wantLocked   bool
+	}{
+		{name: "new counter", lastFailedAt: now, wantFailures: 1},
+		{name: "within window", failures: 2, lastFailedAt: now.Add(-time.Minute), wantFailures: 3},
+		{name: "delay from reserved failure", failures: loginFreeFailures, lastFailedAt: now.Add(-time.Minute), wantFailures: loginFreeFailures + 1, wantBlocked: time.Second},
+		{name: "stale counter starts over", failures: 9, lastFailedAt: now.Add(-window - time.Second), wantFailures: 1},
+		{name: "lockout", failures: 9, lastFailedAt: now.Add(-time.Minute), wantFailures: 10, wantBlocked: lockout, wantLocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			expired := now.Add(-time.Hour)
+			got := nextLoginThrottle(store.LoginThrottle{
+				Kind:         store.LoginThrottleUser,
+				Key:          "alice",
+				Failures:     tt.failures,
+				LastFailedAt: tt.lastFailedAt,
+				BlockedUntil: &expired,
+				Locked:       true,
+			}, now, window, 10, lockout)
+			if got.Failures != tt.wantFailures || !got.LastFailedAt.Equal(now) || got.Locked != tt.wantLocked {
+				t.Fatalf("nextLoginThrottle() = %+v, want %d failures, locked %v", got, tt.wantFailures, tt.wantLocked)
+			}
+			if tt.wantBlocked == 0 {
+				if got.BlockedUntil != nil {
+					t.Fatalf("nextLoginThrottle().BlockedUntil = %v, want nil", got.BlockedUntil)
+				}
+				return
+			}
+			if got.BlockedUntil == nil || !got.BlockedUntil.Equal(now.Add(tt.wantBlocked)) {
+				t.Fatalf("nextLoginThrottle().BlockedUntil = %v, want %v", got.BlockedUntil, now.Add(tt.wantBlocked))
+			}
+		})
+	}
+}
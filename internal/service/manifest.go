@@ -0,0 +1,56 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"hermit/internal/skillmd"
+)
+
+// parsePublishManifest parses the SKILL.md frontmatter of a publish and
+// checks it against the slug and version being published. It returns the
+// frontmatter as JSON (nil without frontmatter) and the manifest itself.
+func parsePublishManifest(content []byte, slug, version string) (json.RawMessage, skillmd.Manifest, error) {
+	manifest, found, err := skillmd.Parse(content)
+	if err != nil {
+		return nil, skillmd.Manifest{}, fmt.Errorf("%w: SKILL.md: %v", ErrInvalidInput, err)
+	}
+	if !found {
+		return nil, skillmd.Manifest{}, nil
+	}
+	if err := checkManifest(manifest, slug, version); err != nil {
+		return nil, skillmd.Manifest{}, err
+	}
+	raw, err := json.Marshal(manifest.Fields)
+	if err != nil {
+		return nil, skillmd.Manifest{}, fmt.Errorf("%w: SKILL.md: %v", ErrInvalidInput, err)
+	}
+	return raw, manifest, nil
+}
+
+// checkManifest rejects frontmatter that contradicts the publish request.
+func checkManifest(m skillmd.Manifest, slug, version string) error {
+	if m.Name != "" && strings.ToLower(m.Name) != slug {
+		return fmt.Errorf("%w: SKILL.md name %q does not match slug %q", ErrInvalidInput, m.Name, slug)
+	}
+	if m.Version != "" && strings.TrimPrefix(m.Version, "v") != version {
+		return fmt.Errorf("%w: SKILL.md version %q does not match published version %q", ErrInvalidInput, m.Version, version)
+	}
+	return nil
+}
+
+// archiveManifest parses the frontmatter of a SKILL.md read from a proxied
+// archive. Upstream content is cached as-is, so an unparsable manifest is
+// dropped rather than rejected.
+func archiveManifest(readme string) json.RawMessage {
+	manifest, found, err := skillmd.Parse([]byte(readme))
+	if err != nil || !found {
+		return nil
+	}
+	raw, err := json.Marshal(manifest.Fields)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParsePublishManifest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		skillMD  string
+		wantErr  bool
+		wantJSON string
+	}{
+		{name: "no frontmatter", skillMD: "# Demo", wantJSON: ""},
+		{name: "matching", skillMD: "---\nname: Demo\nversion: v1.0.0\ndescription: Does things\n---\n", wantJSON: `{"description":"Does things","name":"Demo","version":"v1.0.0"}`},
+		{name: "name mismatch", skillMD: "---\nname: other\n---\n", wantErr: true},
+		{name: "version mismatch", skillMD: "---\nname: demo\nversion: 2.0.0\n---\n", wantErr: true},
+		{name: "malformed", skillMD: "---\nname: demo\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			raw, _, err := parsePublishManifest([]byte(tt.skillMD), "demo", "1.0.0")
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Fatalf("parsePublishManifest() error = %v, want ErrInvalidInput", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePublishManifest() error = %v", err)
+			}
+			if string(raw) != tt.wantJSON {
+				t.Fatalf("parsePublishManifest() = %s, want %s", raw, tt.wantJSON)
+			}
+		})
+	}
+}
+
+func TestArchiveManifest_DropsInvalid(t *testing.T) {
+	t.Parallel()
+	if got := archiveManifest("---\nname: [unclosed\n---\n"); got != nil {
+		t.Fatalf("archiveManifest() = %s, want nil", got)
+	}
+	got := archiveManifest("---\nname: upstream-name\n---\n")
+	var fields map[string]any
+	if err := json.Unmarshal(got, &fields); err != nil || fields["name"] != "upstream-name" {
+		t.Fatalf("archiveManifest() = %s, err = %v", got, err)
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"hermit/internal/extauth"
+	"hermit/internal/store"
+)
+
+// oidcLoginTTL bounds how long a user may take at the IdP.
+const oidcLoginTTL = 10 * time.Minute
+
+var (
+	oidcMu      sync.Mutex
+	cachedOIDC  *extauth.OIDCAuthenticator
+	oidcVersion int64
+)
+
+// GetOIDCAuthenticator returns a cached OIDC authenticator built from DB config.
+// Returns nil if OIDC is not configured or disabled.
+func (s *Service) GetOIDCAuthenticator(ctx context.Context) (*extauth.OIDCAuthenticator, error) {
+	oidcMu.Lock()
+	if cachedOIDC != nil {
+		defer oidcMu.Unlock()
+		return cachedOIDC, nil
+	}
+	version := oidcVersion
+	oidcMu.Unlock()
+
+	ac, err := s.store.GetAuthConfig(ctx, ProviderTypeOIDC)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !ac.Enabled {
+		return nil, nil
+	}
+
+	var cfg extauth.OIDCConfig
+	if err := json.Unmarshal(ac.Config, &cfg); err != nil {
+		return nil, fmt.Errorf("parse OIDC config: %w", err)
+	}
+
+	auth := extauth.NewOIDCAuthenticator(cfg, s.httpClient)
+	oidcMu.Lock()
+	// A config saved or deleted while this one was loading must not be
+	// overwritten by it.
+	if oidcVersion == version {
+		cachedOIDC = auth
+	}
+	oidcMu.Unlock()
+	return auth, nil
+}
+
+func resetOIDCAuthenticator() {
+	oidcMu.Lock()
+	cachedOIDC = nil
+	oidcVersion++
+	oidcMu.Unlock()
+}
+
+func validateOIDCConfig(raw json.RawMessage) error {
+	var cfg extauth.OIDCConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return fmt.Errorf("%w: invalid OIDC config: %v", ErrInvalidInput, err)
+	}
+	issuer := strings.TrimSpace(cfg.Issuer)
+	if !strings.HasPrefix(issuer, "https://") && !strings.HasPrefix(issuer, "http://") {
+		return fmt.Errorf("%w: OIDC issuer must be an http(s) URL", ErrInvalidInput)
+	}
+	if strings.TrimSpace(cfg.ClientID) == "" {
+		return fmt.Errorf("%w: OIDC client_id required", ErrInvalidInput)
+	}
+	redirect := strings.TrimSpace(cfg.RedirectURL)
+	if !strings.HasPrefix(redirect, "https://") && !strings.HasPrefix(redirect, "http://") {
+		return fmt.Errorf("%w: OIDC redirect_url must be the absolute http(s) URL of %s", ErrInvalidInput, OIDCCallbackPath)
+	}
+	if err := extauth.CheckOIDCSubjectClaim(cfg); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return validateGroupRoles(cfg.GroupRoles)
+}
+
+// OIDCCallbackPath is the path of the OIDC callback endpoint. The
+// configured redirect_url must point at it.
+const OIDCCallbackPath = "/api/v1/auth/oidc/callback"
+
+// BeginOIDCLogin prepares an authorization code + PKCE login and returns the
+// IdP URL to send the browser to together with the OAuth state. The login
+// is stored by the hash of the state until the callback or oidcLoginTTL.
+func (s *Service) BeginOIDCLogin(ctx context.Context, returnTo string) (string, string, error) {
+	auth, err := s.GetOIDCAuthenticator(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	if auth == nil {
+		return "", "", fmt.Errorf("%w: OIDC not enabled", ErrNotFound)
+	}
+
+	state, err := extauth.NewOIDCNonce()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := extauth.NewOIDCNonce()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := extauth.NewPKCEVerifier()
+
+	authURL, err := auth.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.store.CreateOIDCLogin(ctx, store.PendingOIDCLogin{
+		StateHash: hashChallengeToken(state),
+		Verifier:  verifier,
+		Nonce:     nonce,
+		ReturnTo:  returnTo,
+		ExpiresAt: time.Now().Add(oidcLoginTTL),
+	}); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// CompleteOIDCLogin redeems the authorization code of a login started with
+// BeginOIDCLogin, applies group role mappings and issues a session token.
+// It also returns the path the user started the login from.
+func (s *Service) CompleteOIDCLogin(ctx context.Context, state, code string, client SessionClient) (string, *extauth.IdentityResult, string, error) {
+	login, err := s.store.TakeOIDCLogin(ctx, hashChallengeToken(state))
+	if err != nil {
+		if store.IsNotFound(err) {
+			return "", nil, "", fmt.Errorf("%w: unknown or expired login state", ErrInvalidInput)
+		}
+		return "", nil, "", err
+	}
+
+	auth, err := s.GetOIDCAuthenticator(ctx)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if auth == nil {
+		return "", nil, "", fmt.Errorf("%w: OIDC not enabled", ErrNotFound)
+	}
+
+	identity, err := auth.Exchange(ctx, code, login.Verifier, login.Nonce)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if err := s.SyncExternalRoles(ctx, ProviderTypeOIDC, identity); err != nil {
+		return "", nil, "", err
+	}
+
+	token, err := s.IssueSessionToken(ctx, identity.Subject, identity.IsAdmin, client)
+	if err != nil {
+		return "", nil, "", err
+	}
+	return token, identity, login.ReturnTo, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestValidateOIDCConfig(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		cfg     map[string]any
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			cfg:  map[string]any{"issuer": "https://login.example.com", "client_id": "hermit", "redirect_url": "https://hermit.example.com/api/v1/auth/oidc/callback"},
+		},
+		{
+			name:    "missing redirect_url",
+			cfg:     map[string]any{"issuer": "https://login.example.com", "client_id": "hermit"},
+			wantErr: true,
+		},
+		{
+			name:    "relative redirect_url",
+			cfg:     map[string]any{"issuer": "https://login.example.com", "client_id": "hermit", "redirect_url": "/api/v1/auth/oidc/callback"},
+			wantErr: true,
+		},
+		{
+			name:    "missing client_id",
+			cfg:     map[string]any{"issuer": "https://login.example.com", "redirect_url": "https://hermit.example.com/api/v1/auth/oidc/callback"},
+			wantErr: true,
+		},
+		{
+			name:    "bad issuer",
+			cfg:     map[string]any{"issuer": "login.example.com", "client_id": "hermit", "redirect_url": "https://hermit.example.com/api/v1/auth/oidc/callback"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			raw, _ := json.Marshal(tt.cfg)
+			err := validateOIDCConfig(raw)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Fatalf("validateOIDCConfig() = %v, want ErrInvalidInput", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateOIDCConfig() = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -41,17 +41,31 @@ func (s *Service) DownloadArtifact(
 			artifactVersion = *resolved
 		}
 	}
-	if artifactVersion == "" {
-		latest, err := s.resolveLatestArtifactInRepo(ctx, repo, actor, slug, map[uuid.UUID]struct{}{})
-		if err != nil {
+	constraint := parseVersionRange(artifactVersion)
+	var artifact store.Artifact
+	resolved := false
+	if constraint != nil && isPartialVersion(artifactVersion) {
+		a, err := s.resolveInRepo(ctx, repo, actor, slug, artifactVersion, map[uuid.UUID]struct{}{})
+		switch {
+		case err == nil:
+			artifact, resolved = a, true
+		case !errors.Is(err, ErrNotFound):
 			return store.Artifact{}, err
 		}
-		artifactVersion = latest.Version
 	}
-
-	artifact, err := s.resolveInRepo(ctx, repo, actor, slug, artifactVersion, map[uuid.UUID]struct{}{})
-	if err != nil {
-		return store.Artifact{}, err
+	if !resolved {
+		if artifactVersion == "" || constraint != nil {
+			latest, err := s.resolveLatestArtifactInRepo(ctx, repo, actor, slug, constraint, map[uuid.UUID]struct{}{})
+			if err != nil {
+				return store.Artifact{}, err
+			}
+			artifactVersion = latest.Version
+		}
+		a, err := s.resolveInRepo(ctx, repo, actor, slug, artifactVersion, map[uuid.UUID]struct{}{})
+		if err != nil {
+			return store.Artifact{}, err
+		}
+		artifact = a
 	}
 	if countDownload {
 		_ = s.store.IncrementSkillDownloads(ctx, artifact.RepoID, slug)
@@ -146,6 +160,10 @@ func (s *Service) fetchAndCacheProxy(
 			return store.Artifact{}, err
 		}
 		fileDescriptors, err := describeZipArchiveFiles(blobFile, blobFile.Size())
+		var readme string
+		if err == nil {
+			readme, err = readSkillManifest(blobFile, blobFile.Size())
+		}
 		closeErr := blobFile.Close()
 		if err != nil {
 			return store.Artifact{}, err
@@ -178,6 +196,7 @@ func (s *Service) fetchAndCacheProxy(
 			"",
 			nil,
 			filesJSON,
+			archiveManifest(readme),
 			"proxy:"+repo.Name,
 		)
 		if err != nil {
@@ -189,14 +208,28 @@ func (s *Service) fetchAndCacheProxy(
 			}
 			return store.Artifact{}, ErrConflict
 		}
+		if err := s.claimBlob(ctx, tx, blobPath); err != nil {
+			return store.Artifact{}, err
+		}
 		if err := s.store.InsertAssetTx(ctx, tx, versionID, fileName, blobPath, sizeBytes, digest); err != nil {
 			return store.Artifact{}, err
 		}
 
-		tagPatch := json.RawMessage(fmt.Sprintf(`{"latest":%q}`, version))
-		if err := s.store.UpdatePackageMetaTx(ctx, tx, packageID, slug, nil, tagPatch); err != nil {
+		if err := s.store.UpdatePackageMetaTx(ctx, tx, packageID, slug, nil, nil); err != nil {
 			return store.Artifact{}, err
 		}
+		if err := s.store.RepointLatestTagTx(ctx, tx, packageID, nil); err != nil {
+			return store.Artifact{}, err
+		}
+		latest, err := s.store.GetLatestVersionNameTx(ctx, tx, packageID)
+		if err != nil {
+			return store.Artifact{}, err
+		}
+		if latest == version {
+			if err := s.store.SetPackageReadmeTx(ctx, tx, packageID, readme); err != nil {
+				return store.Artifact{}, err
+			}
+		}
 
 		if err := tx.Commit(ctx); err != nil {
 			return store.Artifact{}, err
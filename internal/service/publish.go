@@ -1,14 +1,15 @@
 package service
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 
+	"hermit/internal/semver"
 	"hermit/internal/store"
 )
 
@@ -27,9 +28,13 @@ func (s *Service) PublishSkill(
 	if slug == "" {
 		return PublishResult{}, fmt.Errorf("%w: slug required", ErrInvalidInput)
 	}
-	if strings.TrimSpace(payload.Version) == "" {
+	payload.Version = strings.TrimSpace(payload.Version)
+	if payload.Version == "" {
 		return PublishResult{}, fmt.Errorf("%w: version required", ErrInvalidInput)
 	}
+	if _, err := semver.Parse(payload.Version); err != nil {
+		return PublishResult{}, fmt.Errorf("%w: version must be a semantic version such as 1.2.3: %v", ErrInvalidInput, err)
+	}
 	if len(files) == 0 {
 		return PublishResult{}, fmt.Errorf("%w: at least one file is required", ErrInvalidInput)
 	}
@@ -46,29 +51,35 @@ func (s *Service) PublishSkill(
 		return PublishResult{}, fmt.Errorf("%w: SKILL.md required", ErrInvalidInput)
 	}
 
-	archiveBytes, fileDescriptors, err := buildZipArchive(files)
+	readme, err := readPublishManifest(files)
 	if err != nil {
 		return PublishResult{}, err
 	}
-	filesJSON, err := json.Marshal(fileDescriptors)
+	manifestJSON, manifest, err := parsePublishManifest(readme, slug, payload.Version)
 	if err != nil {
 		return PublishResult{}, err
 	}
+	summary := payload.Summary
+	if summary == nil && manifest.Description != "" {
+		summary = &manifest.Description
+	}
 
-	tagPatch := map[string]string{"latest": payload.Version}
+	// "latest" is managed below; it tracks the highest release unless it
+	// has been pinned.
+	tagPatch := map[string]string{}
 	for _, t := range payload.Tags {
 		tag := strings.TrimSpace(t)
-		if tag == "" {
+		if tag == "" || tag == latestTag {
 			continue
 		}
 		tagPatch[tag] = payload.Version
 	}
-	tagPatchJSON, err := json.Marshal(tagPatch)
+
+	digest, sizeBytes, blobPath, fileDescriptors, err := s.putZipArchive(ctx, files)
 	if err != nil {
 		return PublishResult{}, err
 	}
-
-	digest, sizeBytes, blobPath, err := s.blobs.PutStream(ctx, bytes.NewReader(archiveBytes))
+	filesJSON, err := json.Marshal(fileDescriptors)
 	if err != nil {
 		return PublishResult{}, err
 	}
@@ -93,6 +104,7 @@ func (s *Service) PublishSkill(
 		payload.Changelog,
 		nil,
 		filesJSON,
+		manifestJSON,
 		actor,
 	)
 	if err != nil {
@@ -103,12 +115,31 @@ func (s *Service) PublishSkill(
 	}
 
 	archiveName := fmt.Sprintf("%s-%s.zip", slug, payload.Version)
+	if err := s.claimBlob(ctx, tx, blobPath); err != nil {
+		return PublishResult{}, err
+	}
 	if err := s.store.InsertAssetTx(ctx, tx, versionID, archiveName, blobPath, sizeBytes, digest); err != nil {
 		return PublishResult{}, err
 	}
-	if err := s.store.UpdatePackageMetaTx(ctx, tx, packageID, displayName, payload.Summary, tagPatchJSON); err != nil {
+	tagPatchJSON, err := json.Marshal(tagPatch)
+	if err != nil {
+		return PublishResult{}, err
+	}
+	if err := s.store.UpdatePackageMetaTx(ctx, tx, packageID, displayName, summary, tagPatchJSON); err != nil {
+		return PublishResult{}, err
+	}
+	if err := s.store.RepointLatestTagTx(ctx, tx, packageID, nil); err != nil {
+		return PublishResult{}, err
+	}
+	latest, err := s.store.GetLatestVersionNameTx(ctx, tx, packageID)
+	if err != nil {
 		return PublishResult{}, err
 	}
+	if latest == payload.Version {
+		if err := s.store.SetPackageReadmeTx(ctx, tx, packageID, truncateUTF8(string(readme), maxIndexedReadmeBytes)); err != nil {
+			return PublishResult{}, err
+		}
+	}
 	if err := tx.Commit(ctx); err != nil {
 		return PublishResult{}, err
 	}
@@ -118,3 +149,36 @@ func (s *Service) PublishSkill(
 		VersionID: versionID.String(),
 	}, nil
 }
+
+// putZipArchive writes the zip of files straight into the blob store. The
+// archive is produced by a goroutine feeding a pipe, so it is never held in
+// memory as a whole.
+func (s *Service) putZipArchive(
+	ctx context.Context,
+	files []PublishFileInput,
+) (digest string, size int64, key string, descriptors []map[string]any, err error) {
+	pr, pw := io.Pipe()
+	type result struct {
+		descriptors []map[string]any
+		err         error
+	}
+	done := make(chan result, 1)
+	go func() {
+		d, werr := writeZipArchive(pw, files)
+		_ = pw.CloseWithError(werr)
+		done <- result{descriptors: d, err: werr}
+	}()
+
+	digest, size, key, err = s.blobs.PutStream(ctx, pr)
+	// Unblocks the writer if the store stopped reading early.
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	res := <-done
+	if res.err != nil {
+		// The writer's error is the cause of any store failure.
+		return "", 0, "", nil, res.err
+	}
+	if err != nil {
+		return "", 0, "", nil, err
+	}
+	return digest, size, key, res.descriptors, nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"hermit/internal/storage"
+)
+
+func TestPutZipArchive(t *testing.T) {
+	t.Parallel()
+
+	blobs, err := storage.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore() error = %v", err)
+	}
+	svc := &Service{blobs: blobs}
+	large := strings.Repeat("0123456789abcdef", 64<<10)
+	files := []PublishFileInput{
+		{Path: "SKILL.md", Bytes: []byte("# Demo")},
+		{
+			Path: "data/large.txt",
+			Open: func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(large)), nil },
+		},
+	}
+
+	digest, size, key, descriptors, err := svc.putZipArchive(context.Background(), files)
+	if err != nil {
+		t.Fatalf("putZipArchive() error = %v", err)
+	}
+	if !strings.HasPrefix(digest, "sha256:") || size <= 0 {
+		t.Fatalf("digest, size = %q, %d", digest, size)
+	}
+	if len(descriptors) != 2 || descriptors[1]["size"] != int64(len(large)) || descriptors[1]["sha256"] != shaHex([]byte(large)) {
+		t.Fatalf("descriptors = %v", descriptors)
+	}
+
+	f, err := blobs.Open(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+	zr, err := zip.NewReader(f, f.Size())
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	if len(zr.File) != 2 || zr.File[1].Name != "data/large.txt" || zr.File[1].UncompressedSize64 != uint64(len(large)) {
+		t.Fatalf("archive entries = %d, want SKILL.md and data/large.txt", len(zr.File))
+	}
+}
+
+func TestPutZipArchive_ReadError(t *testing.T) {
+	t.Parallel()
+
+	blobs, err := storage.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore() error = %v", err)
+	}
+	svc := &Service{blobs: blobs}
+	readErr := errors.New("spool file vanished")
+	files := []PublishFileInput{{
+		Path: "SKILL.md",
+		Open: func() (io.ReadCloser, error) { return nil, readErr },
+	}}
+
+	if _, _, _, _, err := svc.putZipArchive(context.Background(), files); !errors.Is(err, readErr) {
+		t.Fatalf("putZipArchive() error = %v, want %v", err, readErr)
+	}
+
+	files = []PublishFileInput{{Path: "../escape", Bytes: []byte("x")}}
+	if _, _, _, _, err := svc.putZipArchive(context.Background(), files); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("putZipArchive() error = %v, want ErrInvalidInput", err)
+	}
+}
@@ -0,0 +1,476 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// defaultGroupPriority matches the group_members.priority column default.
+const defaultGroupPriority = 100
+
+type RepositoryView struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	UpstreamURL *string           `json:"upstreamUrl"`
+	Enabled     bool              `json:"enabled"`
+	SkillCount  int64             `json:"skillCount"`
+	Members     []GroupMemberView `json:"members,omitempty"`
+}
+
+type GroupMemberView struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Enabled  bool   `json:"enabled"`
+	Priority int    `json:"priority"`
+}
+
+// RepositoryInput describes a repository to create. Public grants read
+// access to every authenticated subject.
+type RepositoryInput struct {
+	Name        string
+	Type        string
+	UpstreamURL string
+	Public      bool
+}
+
+// RepositoryPatch holds the optional fields of a repository update.
+type RepositoryPatch struct {
+	Name        *string
+	UpstreamURL *string
+	Enabled     *bool
+}
+
+func (s *Service) ListRepositories(ctx context.Context) ([]RepositoryView, error) {
+	repoStats, err := s.store.GetRepoStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]RepositoryView, 0, len(repoStats))
+	for _, rs := range repoStats {
+		view, err := s.buildRepositoryView(ctx, rs.Repository, rs.SkillCount)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+func (s *Service) GetRepositoryByID(ctx context.Context, id string) (RepositoryView, error) {
+	repo, err := s.getRepositoryByID(ctx, id)
+	if err != nil {
+		return RepositoryView{}, err
+	}
+	return s.repositoryViewWithCount(ctx, repo)
+}
+
+func (s *Service) CreateRepository(ctx context.Context, in RepositoryInput) (RepositoryView, error) {
+	name, err := validateRepoName(in.Name)
+	if err != nil {
+		return RepositoryView{}, err
+	}
+	repoType := strings.ToLower(strings.TrimSpace(in.Type))
+	if err := store.ValidateRepoType(repoType); err != nil {
+		return RepositoryView{}, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	upstream, err := validateUpstreamURL(repoType, in.UpstreamURL)
+	if err != nil {
+		return RepositoryView{}, err
+	}
+
+	repo, err := s.store.CreateRepository(ctx, name, repoType, upstream)
+	if err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return RepositoryView{}, fmt.Errorf("%w: repository name already exists", ErrConflict)
+		}
+		return RepositoryView{}, err
+	}
+	if in.Public {
+		if err := s.store.UpsertRepoMember(ctx, repo.ID, "*", store.RoleRead); err != nil {
+			return RepositoryView{}, err
+		}
+	}
+	return s.buildRepositoryView(ctx, repo, 0)
+}
+
+// UpdateRepository applies patch to a repository. The name can only be
+// repeated, not changed (see checkRepoRename).
+func (s *Service) UpdateRepository(ctx context.Context, id string, patch RepositoryPatch) (RepositoryView, error) {
+	repo, err := s.getRepositoryByID(ctx, id)
+	if err != nil {
+		return RepositoryView{}, err
+	}
+	if err := checkRepoRename(repo.Name, patch.Name); err != nil {
+		return RepositoryView{}, err
+	}
+
+	if patch.UpstreamURL != nil {
+		upstream, err := validateUpstreamURL(repo.Type, *patch.UpstreamURL)
+		if err != nil {
+			return RepositoryView{}, err
+		}
+		repo, err = s.store.UpdateRepositoryUpstream(ctx, repo.ID, upstream)
+		if err != nil {
+			if store.IsNotFound(err) {
+				return RepositoryView{}, ErrNotFound
+			}
+			return RepositoryView{}, err
+		}
+	}
+	if patch.Enabled != nil && *patch.Enabled != repo.Enabled {
+		if err := s.store.UpdateRepositoryEnabled(ctx, repo.ID, *patch.Enabled); err != nil {
+			if store.IsNotFound(err) {
+				return RepositoryView{}, ErrNotFound
+			}
+			return RepositoryView{}, err
+		}
+		repo.Enabled = *patch.Enabled
+	}
+	return s.repositoryViewWithCount(ctx, repo)
+}
+
+// DeleteRepository removes a repository with all of its skills, role
+// assignments and group memberships.
+func (s *Service) DeleteRepository(ctx context.Context, id string) error {
+	repo, err := s.getRepositoryByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if s.isDefaultRepo(repo.Name) {
+		return fmt.Errorf("%w: default repository %q cannot be deleted", ErrInvalidInput, repo.Name)
+	}
+	if err := s.store.DeleteRepository(ctx, repo.ID); err != nil {
+		if store.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if repo.Type == store.RepoTypeProxy {
+		return s.DeleteSyncSchedule(ctx, repo.ID.String())
+	}
+	return nil
+}
+
+// --- Group Membership ---
+
+func (s *Service) ListGroupMembers(ctx context.Context, groupID string) ([]GroupMemberView, error) {
+	group, err := s.getGroupRepository(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return s.listGroupMemberViews(ctx, group.ID)
+}
+
+// AddGroupMember adds memberID to a group, or updates its priority when it
+// is already a member. A nil priority uses the column default. Adding a
+// group that (transitively) contains the target group is rejected.
+func (s *Service) AddGroupMember(ctx context.Context, groupID, memberID string, priority *int) ([]GroupMemberView, error) {
+	group, err := s.getGroupRepository(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.getRepositoryByID(ctx, memberID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("%w: member repository", ErrNotFound)
+		}
+		return nil, err
+	}
+	prio := defaultGroupPriority
+	if priority != nil {
+		prio = *priority
+	}
+	if prio < 0 {
+		return nil, fmt.Errorf("%w: priority must not be negative", ErrInvalidInput)
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.store.LockGroupMembersTx(ctx, tx); err != nil {
+		return nil, err
+	}
+	edges, err := s.store.ListGroupEdgesTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if groupEdgeCreatesCycle(edges, group.ID, member.ID) {
+		return nil, fmt.Errorf("%w: adding %q to %q would create a group cycle", ErrInvalidInput, member.Name, group.Name)
+	}
+	if err := s.store.AddGroupMemberTx(ctx, tx, group.ID, member.ID, prio); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.listGroupMemberViews(ctx, group.ID)
+}
+
+// ReorderGroupMembers sets member priorities from their position in
+// memberIDs, which must list every current member exactly once.
+func (s *Service) ReorderGroupMembers(ctx context.Context, groupID string, memberIDs []string) ([]GroupMemberView, error) {
+	group, err := s.getGroupRepository(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.store.ListGroupMemberships(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]uuid.UUID, 0, len(memberIDs))
+	for _, raw := range memberIDs {
+		uid, err := uuid.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid member id %q", ErrInvalidInput, raw)
+		}
+		ordered = append(ordered, uid)
+	}
+	currentIDs := make([]uuid.UUID, 0, len(current))
+	for _, m := range current {
+		currentIDs = append(currentIDs, m.Repository.ID)
+	}
+	priorities, err := priorityOrder(currentIDs, ordered)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.SetGroupMemberPriorities(ctx, group.ID, priorities); err != nil {
+		if store.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: group members changed, reload and retry", ErrConflict)
+		}
+		return nil, err
+	}
+	return s.listGroupMemberViews(ctx, group.ID)
+}
+
+// SetGroupMemberPriority changes the priority of an existing member.
+func (s *Service) SetGroupMemberPriority(ctx context.Context, groupID, memberID string, priority int) ([]GroupMemberView, error) {
+	group, err := s.getGroupRepository(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := uuid.Parse(strings.TrimSpace(memberID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid member id", ErrInvalidInput)
+	}
+	if priority < 0 {
+		return nil, fmt.Errorf("%w: priority must not be negative", ErrInvalidInput)
+	}
+	if err := s.store.SetGroupMemberPriorities(ctx, group.ID, map[uuid.UUID]int{uid: priority}); err != nil {
+		if store.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: group member", ErrNotFound)
+		}
+		return nil, err
+	}
+	return s.listGroupMemberViews(ctx, group.ID)
+}
+
+func (s *Service) RemoveGroupMember(ctx context.Context, groupID, memberID string) error {
+	group, err := s.getGroupRepository(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	uid, err := uuid.Parse(strings.TrimSpace(memberID))
+	if err != nil {
+		return fmt.Errorf("%w: invalid member id", ErrInvalidInput)
+	}
+	if err := s.store.RemoveGroupMember(ctx, group.ID, uid); err != nil {
+		if store.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) getRepositoryByID(ctx context.Context, id string) (store.Repository, error) {
+	uid, err := uuid.Parse(strings.TrimSpace(id))
+	if err != nil {
+		return store.Repository{}, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+	repo, err := s.store.GetRepositoryByID(ctx, uid)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return store.Repository{}, ErrNotFound
+		}
+		return store.Repository{}, err
+	}
+	return repo, nil
+}
+
+func (s *Service) getGroupRepository(ctx context.Context, id string) (store.Repository, error) {
+	repo, err := s.getRepositoryByID(ctx, id)
+	if err != nil {
+		return store.Repository{}, err
+	}
+	if repo.Type != store.RepoTypeGroup {
+		return store.Repository{}, fmt.Errorf("%w: repository %q is not a group", ErrInvalidInput, repo.Name)
+	}
+	return repo, nil
+}
+
+func (s *Service) isDefaultRepo(name string) bool {
+	return name == s.defaults.HostedRepo || name == s.defaults.GroupRepo
+}
+
+func (s *Service) repositoryViewWithCount(ctx context.Context, repo store.Repository) (RepositoryView, error) {
+	repoStats, err := s.store.GetRepoStats(ctx)
+	if err != nil {
+		return RepositoryView{}, err
+	}
+	var count int64
+	for _, rs := range repoStats {
+		if rs.Repository.ID == repo.ID {
+			count = rs.SkillCount
+			break
+		}
+	}
+	return s.buildRepositoryView(ctx, repo, count)
+}
+
+func (s *Service) buildRepositoryView(ctx context.Context, repo store.Repository, skillCount int64) (RepositoryView, error) {
+	view := RepositoryView{
+		ID:          repo.ID.String(),
+		Name:        repo.Name,
+		Type:        repo.Type,
+		UpstreamURL: repo.UpstreamURL,
+		Enabled:     repo.Enabled,
+		SkillCount:  skillCount,
+	}
+	if repo.Type == store.RepoTypeGroup {
+		members, err := s.listGroupMemberViews(ctx, repo.ID)
+		if err != nil {
+			return RepositoryView{}, err
+		}
+		view.Members = members
+	}
+	return view, nil
+}
+
+func (s *Service) listGroupMemberViews(ctx context.Context, groupID uuid.UUID) ([]GroupMemberView, error) {
+	members, err := s.store.ListGroupMemberships(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]GroupMemberView, 0, len(members))
+	for _, m := range members {
+		views = append(views, GroupMemberView{
+			ID:       m.Repository.ID.String(),
+			Name:     m.Repository.Name,
+			Type:     m.Repository.Type,
+			Enabled:  m.Repository.Enabled,
+			Priority: m.Priority,
+		})
+	}
+	return views, nil
+}
+
+// validateRepoName trims name and checks that it is usable as a URL path
+// segment.
+func validateRepoName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name required", ErrInvalidInput)
+	}
+	if len(name) > 64 {
+		return "", fmt.Errorf("%w: name must be at most 64 characters", ErrInvalidInput)
+	}
+	if strings.ContainsAny(name, "/\\?#% \t") || strings.Contains(name, "..") {
+		return "", fmt.Errorf("%w: name %q contains invalid characters", ErrInvalidInput, name)
+	}
+	return name, nil
+}
+
+// checkRepoRename rejects a requested name other than current. Repository
+// names are immutable: LDAP and OIDC group_roles, token repository
+// restrictions and client URLs refer to repositories by name.
+func checkRepoRename(current string, requested *string) error {
+	if requested == nil {
+		return nil
+	}
+	name, err := validateRepoName(*requested)
+	if err != nil {
+		return err
+	}
+	if name != current {
+		return fmt.Errorf("%w: repository %q cannot be renamed", ErrInvalidInput, current)
+	}
+	return nil
+}
+
+// validateUpstreamURL enforces the repositories_type_upstream_ck constraint:
+// proxies need an http(s) upstream, hosted and group repositories have none.
+func validateUpstreamURL(repoType, raw string) (*string, error) {
+	raw = strings.TrimSpace(raw)
+	if repoType != store.RepoTypeProxy {
+		if raw != "" {
+			return nil, fmt.Errorf("%w: only proxy repositories have an upstream URL", ErrInvalidInput)
+		}
+		return nil, nil
+	}
+	if raw == "" {
+		return nil, fmt.Errorf("%w: upstream URL required", ErrInvalidInput)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: upstream URL must be an http(s) URL", ErrInvalidInput)
+	}
+	return &raw, nil
+}
+
+// groupEdgeCreatesCycle reports whether adding groupID → memberID to edges
+// would make groupID reachable from itself.
+func groupEdgeCreatesCycle(edges map[uuid.UUID][]uuid.UUID, groupID, memberID uuid.UUID) bool {
+	visited := make(map[uuid.UUID]bool)
+	stack := []uuid.UUID{memberID}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == groupID {
+			return true
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		stack = append(stack, edges[id]...)
+	}
+	return false
+}
+
+// priorityOrder maps each id in ordered to a priority that follows its
+// position. ordered must be a permutation of current.
+func priorityOrder(current, ordered []uuid.UUID) (map[uuid.UUID]int, error) {
+	if len(ordered) != len(current) {
+		return nil, fmt.Errorf("%w: order must list all %d members", ErrInvalidInput, len(current))
+	}
+	known := make(map[uuid.UUID]bool, len(current))
+	for _, id := range current {
+		known[id] = true
+	}
+	priorities := make(map[uuid.UUID]int, len(ordered))
+	for i, id := range ordered {
+		if !known[id] {
+			return nil, fmt.Errorf("%w: %s is not a member of the group", ErrInvalidInput, id)
+		}
+		if _, dup := priorities[id]; dup {
+			return nil, fmt.Errorf("%w: %s is listed more than once", ErrInvalidInput, id)
+		}
+		priorities[id] = (i + 1) * 10
+	}
+	return priorities, nil
+}
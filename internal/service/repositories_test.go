@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
+)
+
+func TestGroupEdgeCreatesCycle(t *testing.T) {
+	t.Parallel()
+	public, team, nested, hosted, proxy := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	edges := map[uuid.UUID][]uuid.UUID{
+		public: {hosted, team},
+		team:   {nested},
+		nested: {proxy},
+	}
+
+	tests := []struct {
+		name          string
+		group, member uuid.UUID
+		want          bool
+	}{
+		{"self", public, public, true},
+		{"direct back edge", team, public, true},
+		{"transitive back edge", nested, public, true},
+		{"leaf member", nested, hosted, false},
+		{"already present edge", public, team, false},
+		{"sibling groups", team, hosted, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := groupEdgeCreatesCycle(edges, tt.group, tt.member); got != tt.want {
+				t.Fatalf("groupEdgeCreatesCycle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupEdgeCreatesCycle_ToleratesExistingCycles(t *testing.T) {
+	t.Parallel()
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	edges := map[uuid.UUID][]uuid.UUID{a: {b}, b: {a}}
+	if groupEdgeCreatesCycle(edges, c, a) {
+		t.Fatalf("groupEdgeCreatesCycle() = true, want false")
+	}
+}
+
+func TestPriorityOrder(t *testing.T) {
+	t.Parallel()
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	current := []uuid.UUID{a, b, c}
+
+	got, err := priorityOrder(current, []uuid.UUID{c, a, b})
+	if err != nil {
+		t.Fatalf("priorityOrder() error = %v", err)
+	}
+	if got[c] != 10 || got[a] != 20 || got[b] != 30 {
+		t.Fatalf("priorityOrder() = %v", got)
+	}
+
+	bad := [][]uuid.UUID{
+		{a, b},
+		{a, b, b},
+		{a, b, uuid.New()},
+	}
+	for _, ordered := range bad {
+		if _, err := priorityOrder(current, ordered); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("priorityOrder(%v) error = %v, want ErrInvalidInput", ordered, err)
+		}
+	}
+}
+
+func TestValidateRepoName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{" team-skills ", "team-skills", false},
+		{"clawhub.proxy_2", "clawhub.proxy_2", false},
+		{"", "", true},
+		{"a/b", "", true},
+		{"has space", "", true},
+		{"..", "", true},
+	}
+	for _, tt := range tests {
+		got, err := validateRepoName(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("validateRepoName(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Fatalf("validateRepoName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCheckRepoRename(t *testing.T) {
+	t.Parallel()
+	name := func(s string) *string { return &s }
+	tests := []struct {
+		requested *string
+		wantErr   bool
+	}{
+		{nil, false},
+		{name("team-skills"), false},
+		{name(" team-skills "), false},
+		{name("other"), true},
+		{name(""), true},
+	}
+	for _, tt := range tests {
+		err := checkRepoRename("team-skills", tt.requested)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("checkRepoRename(%v) error = %v, wantErr %v", tt.requested, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("checkRepoRename(%v) error = %v, want ErrInvalidInput", tt.requested, err)
+		}
+	}
+}
+
+func TestValidateUpstreamURL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		repoType string
+		raw      string
+		wantNil  bool
+		wantErr  bool
+	}{
+		{store.RepoTypeProxy, "https://clawhub.ai", false, false},
+		{store.RepoTypeProxy, "", false, true},
+		{store.RepoTypeProxy, "ftp://mirror", false, true},
+		{store.RepoTypeHosted, "", true, false},
+		{store.RepoTypeGroup, "https://clawhub.ai", false, true},
+	}
+	for _, tt := range tests {
+		got, err := validateUpstreamURL(tt.repoType, tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("validateUpstreamURL(%q, %q) error = %v, wantErr %v", tt.repoType, tt.raw, err, tt.wantErr)
+		}
+		if !tt.wantErr && (got == nil) != tt.wantNil {
+			t.Fatalf("validateUpstreamURL(%q, %q) = %v", tt.repoType, tt.raw, got)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
+)
+
+const (
+	scrubPageSize = 200
+	// maxReportedScrubProblems caps the problems listed in a scrub report;
+	// the counters keep covering everything.
+	maxReportedScrubProblems = 100
+)
+
+// Kinds of scrub problems.
+const (
+	ScrubMissing    = "missing"
+	ScrubCorrupt    = "corrupt"
+	ScrubUnreadable = "unreadable"
+)
+
+// blobScrubMu keeps scrub runs from overlapping.
+var blobScrubMu sync.Mutex
+
+type BlobScrubOptions struct {
+	// RepositoryID restricts the scrub to one repository when set.
+	RepositoryID string
+	// Refetch re-downloads damaged proxy artifacts from upstream.
+	Refetch bool
+}
+
+type BlobScrubProblem struct {
+	Repository  string `json:"repository"`
+	Slug        string `json:"slug"`
+	Version     string `json:"version"`
+	BlobPath    string `json:"blobPath"`
+	Problem     string `json:"problem"`
+	Detail      string `json:"detail"`
+	Repaired    bool   `json:"repaired"`
+	RepairError string `json:"repairError,omitempty"`
+}
+
+type BlobScrubReport struct {
+	Scanned      int                `json:"scanned"`
+	ScannedBytes int64              `json:"scannedBytes"`
+	OK           int                `json:"ok"`
+	Missing      int                `json:"missing"`
+	Corrupt      int                `json:"corrupt"`
+	Unreadable   int                `json:"unreadable"`
+	Repaired     int                `json:"repaired"`
+	Problems     []BlobScrubProblem `json:"problems"`
+}
+
+// ScrubBlobs streams every stored archive and compares it with the digest
+// and size recorded for its asset. With Refetch set, damaged artifacts of
+// proxy repositories are downloaded again from upstream and re-checked.
+func (s *Service) ScrubBlobs(ctx context.Context, opts BlobScrubOptions) (BlobScrubReport, error) {
+	var repoID *uuid.UUID
+	if strings.TrimSpace(opts.RepositoryID) != "" {
+		repo, err := s.getRepositoryByID(ctx, opts.RepositoryID)
+		if err != nil {
+			return BlobScrubReport{}, err
+		}
+		repoID = &repo.ID
+	}
+	if !blobScrubMu.TryLock() {
+		return BlobScrubReport{}, fmt.Errorf("%w: blob scrub is already running", ErrConflict)
+	}
+	defer blobScrubMu.Unlock()
+
+	report := BlobScrubReport{Problems: []BlobScrubProblem{}}
+	after := uuid.Nil
+	for {
+		assets, err := s.store.ListScrubAssets(ctx, repoID, after, scrubPageSize)
+		if err != nil {
+			return report, err
+		}
+		for _, asset := range assets {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			report.Scanned++
+			problem, detail, n := s.checkStoredBlob(ctx, asset.BlobPath, asset.Digest, asset.SizeBytes)
+			report.ScannedBytes += n
+			if problem == "" {
+				report.OK++
+				continue
+			}
+			switch problem {
+			case ScrubMissing:
+				report.Missing++
+			case ScrubCorrupt:
+				report.Corrupt++
+			default:
+				report.Unreadable++
+			}
+
+			p := BlobScrubProblem{
+				Repository: asset.Repo.Name,
+				Slug:       asset.PackageName,
+				Version:    asset.Version,
+				BlobPath:   asset.BlobPath,
+				Problem:    problem,
+				Detail:     detail,
+			}
+			// An unreadable blob may be intact behind a transient error, so
+			// only missing or corrupt ones are replaced.
+			if opts.Refetch && problem != ScrubUnreadable && asset.Repo.Type == store.RepoTypeProxy {
+				if err := s.refetchProxyBlob(ctx, asset); err != nil {
+					p.RepairError = err.Error()
+				} else {
+					p.Repaired = true
+					report.Repaired++
+				}
+			}
+			if len(report.Problems) < maxReportedScrubProblems {
+				report.Problems = append(report.Problems, p)
+			}
+		}
+		if len(assets) < scrubPageSize {
+			return report, nil
+		}
+		after = assets[len(assets)-1].ID
+	}
+}
+
+// checkStoredBlob opens key and verifies it. It returns the problem kind (""
+// when the blob is intact), a description, and the bytes read.
+func (s *Service) checkStoredBlob(ctx context.Context, key, digest string, size int64) (string, string, int64) {
+	f, err := s.blobs.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ScrubMissing, "blob does not exist", 0
+		}
+		return ScrubUnreadable, err.Error(), 0
+	}
+	defer f.Close()
+	return verifyBlob(f, digest, size)
+}
+
+// verifyBlob hashes r and compares it with the recorded SHA-256 digest
+// ("sha256:<hex>") and size.
+func verifyBlob(r io.Reader, digest string, size int64) (string, string, int64) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return ScrubUnreadable, err.Error(), n
+	}
+	if n != size {
+		return ScrubCorrupt, fmt.Sprintf("size %d, recorded %d", n, size), n
+	}
+	got := hex.EncodeToString(h.Sum(nil))
+	want := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(digest), "sha256:"))
+	if got != want {
+		return ScrubCorrupt, fmt.Sprintf("digest sha256:%s, recorded %s", got, digest), n
+	}
+	return "", "", n
+}
+
+// refetchProxyBlob replaces a damaged proxy blob with a fresh upstream copy.
+// The blob is removed first because the local store keeps an existing file
+// on re-upload. Upstream content that no longer matches the recorded digest
+// lands under another key, so the asset still fails the re-check.
+func (s *Service) refetchProxyBlob(ctx context.Context, asset store.ScrubAsset) error {
+	if err := s.blobs.Delete(ctx, asset.BlobPath); err != nil {
+		return err
+	}
+	if _, err := s.fetchAndCacheProxy(ctx, asset.Repo, asset.PackageName, asset.Version, nil); err != nil {
+		return err
+	}
+	if problem, detail, _ := s.checkStoredBlob(ctx, asset.BlobPath, asset.Digest, asset.SizeBytes); problem != "" {
+		return fmt.Errorf("still %s after re-fetch: %s", problem, detail)
+	}
+	return nil
+}
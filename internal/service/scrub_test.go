@@ -0,0 +1,44 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func TestVerifyBlob(t *testing.T) {
+	t.Parallel()
+	body := "skill archive bytes"
+	sum := sha256.Sum256([]byte(body))
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name        string
+		r           io.Reader
+		digest      string
+		size        int64
+		wantProblem string
+	}{
+		{name: "intact", r: strings.NewReader(body), digest: digest, size: int64(len(body))},
+		{name: "uppercase digest", r: strings.NewReader(body), digest: strings.ToUpper(digest[len("sha256:"):]), size: int64(len(body))},
+		{name: "truncated", r: strings.NewReader(body[:5]), digest: digest, size: int64(len(body)), wantProblem: ScrubCorrupt},
+		{name: "flipped byte", r: strings.NewReader("Skill archive bytes"), digest: digest, size: int64(len(body)), wantProblem: ScrubCorrupt},
+		{name: "read error", r: failingReader{}, digest: digest, size: int64(len(body)), wantProblem: ScrubUnreadable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			problem, detail, _ := verifyBlob(tt.r, tt.digest, tt.size)
+			if problem != tt.wantProblem {
+				t.Fatalf("verifyBlob() = %q (%s), want %q", problem, detail, tt.wantProblem)
+			}
+		})
+	}
+}
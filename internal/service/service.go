@@ -1,7 +1,9 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"net/http"
 	"time"
 
@@ -33,10 +35,20 @@ type PublishPayload struct {
 	Tags        []string
 }
 
+// PublishFileInput is one uploaded file. Content comes from Open when it is
+// set, so large uploads can stay on disk, and from Bytes otherwise.
 type PublishFileInput struct {
 	Path        string
 	ContentType string
 	Bytes       []byte
+	Open        func() (io.ReadCloser, error)
+}
+
+func (f PublishFileInput) open() (io.ReadCloser, error) {
+	if f.Open != nil {
+		return f.Open()
+	}
+	return io.NopCloser(bytes.NewReader(f.Bytes)), nil
 }
 
 type PublishResult struct {
@@ -47,6 +59,8 @@ type PublishResult struct {
 type SkillView struct {
 	Skill         store.Skill
 	LatestVersion *store.SkillVersionSummary
+	// Starred reports whether the requesting subject starred the skill.
+	Starred bool
 }
 
 type SkillVersionView struct {
@@ -57,6 +71,14 @@ type SkillVersionView struct {
 type ResolveView struct {
 	MatchVersion  *string
 	LatestVersion *string
+	// RangeVersion is the highest version satisfying the requested range.
+	RangeVersion *string
+	// Yank and deprecation details of the versions above, and the skill's
+	// own deprecation message.
+	Match            VersionNotice
+	Latest           VersionNotice
+	Range            VersionNotice
+	SkillDeprecation *string
 }
 
 type Service struct {
@@ -67,6 +89,8 @@ type Service struct {
 	defaults         Defaults
 	fetchGroup       singleflight.Group
 	syncProxyVersion func(context.Context, store.Repository, string, string) error
+	sessions         SessionPolicy
+	login            LoginPolicy
 }
 
 func New(
@@ -75,6 +99,8 @@ func New(
 	proxyTimeout time.Duration,
 	proxyNegativeTTL time.Duration,
 	defaults Defaults,
+	sessions SessionPolicy,
+	login LoginPolicy,
 ) *Service {
 	svc := &Service{
 		store: st,
@@ -84,6 +110,8 @@ func New(
 		},
 		proxyNegativeTTL: proxyNegativeTTL,
 		defaults:         defaults,
+		sessions:         sessions,
+		login:            login,
 	}
 	svc.syncProxyVersion = func(ctx context.Context, repo store.Repository, slug string, version string) error {
 		_, err := svc.resolveProxy(ctx, repo, slug, version)
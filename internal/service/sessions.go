@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// maxSessionUserAgent bounds the user agent stored with a session.
+const maxSessionUserAgent = 256
+
+// SessionPolicy bounds the lifetime of login sessions. A session ends when
+// it has been unused for IdleTimeout or is older than MaxAge; 0 disables
+// either limit.
+type SessionPolicy struct {
+	IdleTimeout time.Duration
+	MaxAge      time.Duration
+}
+
+// SessionClient describes the device a session is issued to.
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+// SessionView is a live session as shown to its owner.
+type SessionView struct {
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	IdleExpiresAt *time.Time `json:"idle_expires_at,omitempty"`
+	UserAgent     string     `json:"user_agent"`
+	ClientIP      string     `json:"client_ip"`
+	Current       bool       `json:"current"`
+}
+
+// IssueSessionToken starts a new login session for a subject. Other
+// sessions of the subject stay valid; expired ones are cleaned up.
+func (s *Service) IssueSessionToken(ctx context.Context, subject string, isAdmin bool, client SessionClient) (string, error) {
+	if strings.TrimSpace(subject) == "" {
+		return "", fmt.Errorf("%w: subject required", ErrInvalidInput)
+	}
+
+	now := time.Now()
+	if _, err := s.store.DeleteExpiredSessions(ctx, subject, s.idleCutoff(now)); err != nil {
+		return "", err
+	}
+
+	rawToken, err := generateToken(32)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(rawToken))
+	hash := hex.EncodeToString(sum[:])
+
+	var expiresAt *time.Time
+	if s.sessions.MaxAge > 0 {
+		t := now.Add(s.sessions.MaxAge).UTC()
+		expiresAt = &t
+	}
+	userAgent := truncateUTF8(strings.TrimSpace(client.UserAgent), maxSessionUserAgent)
+	if _, err := s.store.CreateSessionToken(ctx, subject, hash, isAdmin, userAgent, strings.TrimSpace(client.IP), expiresAt); err != nil {
+		return "", err
+	}
+	return rawToken, nil
+}
+
+// ListMySessions returns the live sessions of subject. currentID marks the
+// session making the request.
+func (s *Service) ListMySessions(ctx context.Context, subject, currentID string) ([]SessionView, error) {
+	tokens, err := s.store.ListSessionsBySubject(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	views := []SessionView{}
+	for _, t := range tokens {
+		if v, ok := sessionView(t, s.sessions.IdleTimeout, now); ok {
+			v.Current = t.ID.String() == currentID
+			views = append(views, v)
+		}
+	}
+	return views, nil
+}
+
+// RevokeSession ends one session of subject.
+func (s *Service) RevokeSession(ctx context.Context, subject, sessionID string) error {
+	id, err := uuid.Parse(strings.TrimSpace(sessionID))
+	if err != nil {
+		return fmt.Errorf("%w: invalid session ID", ErrInvalidInput)
+	}
+	if err := s.store.RevokeSession(ctx, id, subject); err != nil {
+		if store.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// RevokeOtherSessions ends every session of subject except currentID and
+// returns how many were ended.
+func (s *Service) RevokeOtherSessions(ctx context.Context, subject, currentID string) (int64, error) {
+	keep, err := uuid.Parse(strings.TrimSpace(currentID))
+	if err != nil {
+		keep = uuid.Nil
+	}
+	return s.store.RevokeOtherSessions(ctx, subject, keep)
+}
+
+func (s *Service) idleCutoff(now time.Time) *time.Time {
+	if s.sessions.IdleTimeout <= 0 {
+		return nil
+	}
+	t := now.Add(-s.sessions.IdleTimeout)
+	return &t
+}
+
+// sessionView converts a session token, reporting false when it has
+// already expired.
+func sessionView(t store.APIToken, idleTimeout time.Duration, now time.Time) (SessionView, bool) {
+	if t.ExpiresAt != nil && !now.Before(*t.ExpiresAt) {
+		return SessionView{}, false
+	}
+	v := SessionView{
+		ID:         t.ID,
+		CreatedAt:  t.CreatedAt,
+		LastUsedAt: t.LastUsedAt,
+		ExpiresAt:  t.ExpiresAt,
+		UserAgent:  t.UserAgent,
+		ClientIP:   t.ClientIP,
+	}
+	if idleTimeout > 0 {
+		lastActivity := t.CreatedAt
+		if t.LastUsedAt != nil {
+			lastActivity = *t.LastUsedAt
+		}
+		idleExpiresAt := lastActivity.Add(idleTimeout)
+		if !now.Before(idleExpiresAt) {
+			return SessionView{}, false
+		}
+		v.IdleExpiresAt = &idleExpiresAt
+	}
+	return v, true
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
+)
+
+func TestSessionView(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	created := now.Add(-3 * time.Hour)
+	recent := now.Add(-10 * time.Minute)
+	expires := now.Add(24 * time.Hour)
+	expired := now.Add(-time.Second)
+
+	tests := []struct {
+		name     string
+		token    store.APIToken
+		idle     time.Duration
+		wantLive bool
+		wantIdle *time.Time
+	}{
+		{
+			name:     "recently used",
+			token:    store.APIToken{CreatedAt: created, LastUsedAt: &recent, ExpiresAt: &expires},
+			idle:     time.Hour,
+			wantLive: true,
+			wantIdle: ptrTime(recent.Add(time.Hour)),
+		},
+		{
+			name:  "idle since login",
+			token: store.APIToken{CreatedAt: created, ExpiresAt: &expires},
+			idle:  time.Hour,
+		},
+		{
+			name:     "idle timeout disabled",
+			token:    store.APIToken{CreatedAt: created},
+			wantLive: true,
+		},
+		{
+			name:  "past absolute expiry",
+			token: store.APIToken{CreatedAt: created, LastUsedAt: &recent, ExpiresAt: &expired},
+			idle:  time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.token.ID = uuid.New()
+			v, live := sessionView(tt.token, tt.idle, now)
+			if live != tt.wantLive {
+				t.Fatalf("sessionView() live = %v, want %v", live, tt.wantLive)
+			}
+			if !live {
+				return
+			}
+			if v.ID != tt.token.ID {
+				t.Fatalf("ID = %v, want %v", v.ID, tt.token.ID)
+			}
+			if (v.IdleExpiresAt == nil) != (tt.wantIdle == nil) ||
+				(tt.wantIdle != nil && !v.IdleExpiresAt.Equal(*tt.wantIdle)) {
+				t.Fatalf("IdleExpiresAt = %v, want %v", v.IdleExpiresAt, tt.wantIdle)
+			}
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time { return &t }
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"hermit/internal/auth"
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
+)
+
+type StarView struct {
+	Starred       bool  `json:"starred"`
+	Stars         int64 `json:"stars"`
+	UpstreamStars int64 `json:"upstreamStars"`
+}
+
+// SetSkillStar stars or unstars slug for the actor. Starring twice is a
+// no-op, as is unstarring a skill that is not starred.
+func (s *Service) SetSkillStar(ctx context.Context, repo store.Repository, actor auth.Actor, slug string, starred bool) (StarView, error) {
+	subject := strings.TrimSpace(actor.Subject)
+	if actor.Anonymous || subject == "" {
+		return StarView{}, fmt.Errorf("%w: sign in to star skills", ErrInvalidInput)
+	}
+	skill, err := s.lookupSkill(ctx, repo, actor, slug)
+	if err != nil {
+		return StarView{}, err
+	}
+
+	stars, err := s.store.SetSkillStar(ctx, skill.ID, subject, starred)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return StarView{}, ErrNotFound
+		}
+		return StarView{}, err
+	}
+	return StarView{Starred: starred, Stars: stars, UpstreamStars: skill.UpstreamStars}, nil
+}
+
+// ListStarredSkills returns the skills the actor starred that are still
+// readable through repo.
+func (s *Service) ListStarredSkills(
+	ctx context.Context,
+	repo store.Repository,
+	actor auth.Actor,
+	limit int,
+	offset int,
+) ([]store.StarredSkill, error) {
+	subject := strings.TrimSpace(actor.Subject)
+	if actor.Anonymous || subject == "" {
+		return []store.StarredSkill{}, nil
+	}
+
+	repoIDs := []uuid.UUID{repo.ID}
+	if repo.Type == store.RepoTypeGroup {
+		members, err := s.getGroupMembers(ctx, repo.ID, actor)
+		if err != nil {
+			return nil, err
+		}
+		repoIDs = repoIDs[:0]
+		for _, member := range members {
+			if member.Enabled {
+				repoIDs = append(repoIDs, member.ID)
+			}
+		}
+	}
+	if len(repoIDs) == 0 {
+		return []store.StarredSkill{}, nil
+	}
+	return s.store.ListStarredSkills(ctx, subject, repoIDs, limit, offset)
+}
+
+// SyncProxySkillStats records the upstream star count of a proxied skill.
+// Local stars are tracked separately and never overwritten.
+func (s *Service) SyncProxySkillStats(ctx context.Context, repo store.Repository, slug string, stars int64) error {
+	slug = normalizeSlug(slug)
+	if slug == "" || stars < 0 {
+		return nil
+	}
+	return s.store.SetUpstreamStars(ctx, repo.ID, slug, stars)
+}
@@ -4,7 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
+	"hermit/internal/proxysync"
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
@@ -68,3 +73,118 @@ func (s *Service) SeedProxySyncConfig(ctx context.Context, cfg ProxySyncConfig)
 	raw, _ := json.Marshal(cfg)
 	return s.store.UpsertSystemConfig(ctx, configKeyProxySync, raw)
 }
+
+const configKeyProxySyncSchedules = "proxy_sync_schedules"
+
+// SyncSchedule is the background sync schedule of a single sync source.
+// Spec is a five-field cron expression (UTC), a descriptor such as @daily,
+// or "@every <duration>".
+type SyncSchedule struct {
+	Spec    string `json:"spec"`
+	Enabled bool   `json:"enabled"`
+}
+
+// GetSyncSchedules returns all configured schedules keyed by repository ID.
+// They are stored as one JSON object whose fields are written one at a
+// time, see SaveSyncSchedule.
+func (s *Service) GetSyncSchedules(ctx context.Context) (map[string]SyncSchedule, error) {
+	raw, err := s.store.GetSystemConfig(ctx, configKeyProxySyncSchedules)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return map[string]SyncSchedule{}, nil
+		}
+		return nil, err
+	}
+	schedules := map[string]SyncSchedule{}
+	if err := json.Unmarshal(raw, &schedules); err != nil {
+		return nil, fmt.Errorf("parse proxy sync schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// SaveSyncSchedule validates and stores the schedule of a proxy sync source.
+func (s *Service) SaveSyncSchedule(ctx context.Context, id string, schedule SyncSchedule) error {
+	repo, err := s.getSyncSource(ctx, id)
+	if err != nil {
+		return err
+	}
+	schedule.Spec = strings.TrimSpace(schedule.Spec)
+	if _, err := proxysync.ParseSchedule(schedule.Spec); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	raw, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+	return s.store.SetSystemConfigEntry(ctx, configKeyProxySyncSchedules, repo.ID.String(), raw)
+}
+
+// DeleteSyncSchedule removes the schedule of a sync source, if any.
+func (s *Service) DeleteSyncSchedule(ctx context.Context, id string) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+	return s.store.DeleteSystemConfigEntry(ctx, configKeyProxySyncSchedules, uid.String())
+}
+
+// ListSyncSchedules returns the enabled schedules of all syncable proxy
+// repositories. Entries that no longer parse are skipped.
+func (s *Service) ListSyncSchedules(ctx context.Context) ([]proxysync.ScheduledRepository, error) {
+	schedules, err := s.GetSyncSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(schedules) == 0 {
+		return nil, nil
+	}
+	repos, err := s.ListProxyRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []proxysync.ScheduledRepository
+	for _, repo := range repos {
+		sched, ok := schedules[repo.ID.String()]
+		if !ok || !sched.Enabled {
+			continue
+		}
+		parsed, err := proxysync.ParseSchedule(sched.Spec)
+		if err != nil {
+			continue
+		}
+		out = append(out, proxysync.ScheduledRepository{
+			Repository: repo,
+			Spec:       sched.Spec,
+			Schedule:   parsed,
+		})
+	}
+	return out, nil
+}
+
+// TryLockScheduledSync keeps replicas from running the scheduled sync of a
+// repository at the same time, as every replica runs its own scheduler.
+// ok is false when another replica holds the lock; otherwise release must
+// be called when the sync ends.
+func (s *Service) TryLockScheduledSync(ctx context.Context, repoID uuid.UUID) (release func(), ok bool, err error) {
+	return s.store.TryLockProxySync(ctx, repoID)
+}
+
+func (s *Service) getSyncSource(ctx context.Context, id string) (store.Repository, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return store.Repository{}, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+	repo, err := s.store.GetRepositoryByID(ctx, uid)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return store.Repository{}, ErrNotFound
+		}
+		return store.Repository{}, err
+	}
+	if repo.Type != store.RepoTypeProxy {
+		return store.Repository{}, fmt.Errorf("%w: repository %q is not a sync source", ErrInvalidInput, repo.Name)
+	}
+	return repo, nil
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"hermit/internal/proxysync"
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SyncTriggerManual    = "manual"
+	SyncTriggerScheduled = "scheduled"
+
+	// syncRunRetention is the number of runs kept in the history.
+	syncRunRetention = 200
+
+	// SyncRunHeartbeat is how often a running sync refreshes its heartbeat.
+	SyncRunHeartbeat = time.Minute
+	// syncRunStaleAfter is how long a run may go without a heartbeat before
+	// it counts as interrupted.
+	syncRunStaleAfter = 5 * SyncRunHeartbeat
+)
+
+type SyncRunView struct {
+	ID           string          `json:"id"`
+	Trigger      string          `json:"trigger"`
+	Status       string          `json:"status"`
+	Repositories int             `json:"repositories"`
+	Skills       int             `json:"skills"`
+	Versions     int             `json:"versions"`
+	Cached       int             `json:"cached"`
+	Failed       int             `json:"failed"`
+	Skipped      int             `json:"skipped"`
+	ByRepository json.RawMessage `json:"byRepository"`
+	Error        *string         `json:"error"`
+	StartedAt    time.Time       `json:"startedAt"`
+	FinishedAt   *time.Time      `json:"finishedAt"`
+}
+
+type SyncRunItemView struct {
+	Repository string  `json:"repository"`
+	Slug       string  `json:"slug"`
+	Outcome    string  `json:"outcome"`
+	Versions   int     `json:"versions"`
+	Cached     int     `json:"cached"`
+	Failed     int     `json:"failed"`
+	Error      *string `json:"error"`
+}
+
+// StartSyncRun records the start of a sync run and returns its ID.
+func (s *Service) StartSyncRun(ctx context.Context, trigger string) (uuid.UUID, error) {
+	run, err := s.store.CreateSyncRun(ctx, trigger)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return run.ID, nil
+}
+
+// FinishSyncRun stores the outcome of a sync run and trims the history to
+// the retention limit.
+func (s *Service) FinishSyncRun(ctx context.Context, runID uuid.UUID, summary proxysync.Summary, runErr error) error {
+	run, items, err := buildSyncRunRecord(runID, summary, runErr)
+	if err != nil {
+		return err
+	}
+	if err := s.store.FinishSyncRun(ctx, run, items); err != nil {
+		return err
+	}
+	return s.store.PruneSyncRuns(ctx, syncRunRetention)
+}
+
+// HeartbeatSyncRun tells other replicas that a run is still in progress.
+// It is called every SyncRunHeartbeat while the run lasts.
+func (s *Service) HeartbeatSyncRun(ctx context.Context, runID uuid.UUID) error {
+	return s.store.TouchSyncRun(ctx, runID)
+}
+
+// InterruptStaleSyncRuns marks runs that were still running when their
+// server stopped as interrupted. Runs whose heartbeat is recent belong to
+// a live replica and are left alone. It is meant to be called at startup.
+func (s *Service) InterruptStaleSyncRuns(ctx context.Context) (int64, error) {
+	return s.store.MarkInterruptedSyncRuns(ctx, time.Now().Add(-syncRunStaleAfter))
+}
+
+func (s *Service) ListSyncRuns(ctx context.Context, limit int, offset int) ([]SyncRunView, error) {
+	runs, err := s.store.ListSyncRuns(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SyncRunView, 0, len(runs))
+	for _, run := range runs {
+		out = append(out, toSyncRunView(run))
+	}
+	return out, nil
+}
+
+func (s *Service) GetSyncRun(ctx context.Context, id string) (SyncRunView, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return SyncRunView{}, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+	run, err := s.store.GetSyncRun(ctx, uid)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return SyncRunView{}, ErrNotFound
+		}
+		return SyncRunView{}, err
+	}
+	return toSyncRunView(run), nil
+}
+
+// GetLatestSyncRun returns the most recent run, or nil when none was recorded.
+func (s *Service) GetLatestSyncRun(ctx context.Context) (*SyncRunView, error) {
+	runs, err := s.store.ListSyncRuns(ctx, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, nil
+	}
+	view := toSyncRunView(runs[0])
+	return &view, nil
+}
+
+func (s *Service) ListSyncRunItems(ctx context.Context, id string, outcome string, limit int, offset int) ([]SyncRunItemView, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid id", ErrInvalidInput)
+	}
+	outcome = strings.ToLower(strings.TrimSpace(outcome))
+	switch outcome {
+	case "", proxysync.SkillOutcomeSkipped, proxysync.SkillOutcomeSynced, proxysync.SkillOutcomeFailed:
+	default:
+		return nil, fmt.Errorf("%w: invalid outcome %q", ErrInvalidInput, outcome)
+	}
+	if _, err := s.store.GetSyncRun(ctx, uid); err != nil {
+		if store.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	items, err := s.store.ListSyncRunItems(ctx, uid, outcome, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SyncRunItemView, 0, len(items))
+	for _, item := range items {
+		out = append(out, SyncRunItemView{
+			Repository: item.Repository,
+			Slug:       item.Slug,
+			Outcome:    item.Outcome,
+			Versions:   item.Versions,
+			Cached:     item.Cached,
+			Failed:     item.Failed,
+			Error:      item.Error,
+		})
+	}
+	return out, nil
+}
+
+func buildSyncRunRecord(runID uuid.UUID, summary proxysync.Summary, runErr error) (store.SyncRun, []store.SyncRunItem, error) {
+	byRepo := summary.ByRepository
+	if byRepo == nil {
+		byRepo = []proxysync.RepoStats{}
+	}
+	rawByRepo, err := json.Marshal(byRepo)
+	if err != nil {
+		return store.SyncRun{}, nil, fmt.Errorf("encode sync run repositories: %w", err)
+	}
+
+	run := store.SyncRun{
+		ID:           runID,
+		Status:       store.SyncRunCompleted,
+		Repositories: summary.Repositories,
+		Skills:       summary.Skills,
+		Versions:     summary.Versions,
+		Cached:       summary.Cached,
+		Failed:       summary.Failed,
+		Skipped:      summary.Skipped,
+		ByRepository: rawByRepo,
+	}
+	if runErr != nil {
+		msg := runErr.Error()
+		run.Status = store.SyncRunFailed
+		run.Error = &msg
+	}
+
+	var items []store.SyncRunItem
+	for _, repoStats := range summary.ByRepository {
+		for _, outcome := range repoStats.Outcomes {
+			item := store.SyncRunItem{
+				RunID:      runID,
+				Repository: repoStats.Repository,
+				Slug:       outcome.Slug,
+				Outcome:    outcome.Outcome,
+				Versions:   outcome.Versions,
+				Cached:     outcome.Cached,
+				Failed:     outcome.Failed,
+			}
+			if outcome.Error != "" {
+				msg := outcome.Error
+				item.Error = &msg
+			}
+			items = append(items, item)
+		}
+	}
+	return run, items, nil
+}
+
+func toSyncRunView(run store.SyncRun) SyncRunView {
+	byRepo := run.ByRepository
+	if len(byRepo) == 0 {
+		byRepo = json.RawMessage("[]")
+	}
+	return SyncRunView{
+		ID:           run.ID.String(),
+		Trigger:      run.Trigger,
+		Status:       run.Status,
+		Repositories: run.Repositories,
+		Skills:       run.Skills,
+		Versions:     run.Versions,
+		Cached:       run.Cached,
+		Failed:       run.Failed,
+		Skipped:      run.Skipped,
+		ByRepository: byRepo,
+		Error:        run.Error,
+		StartedAt:    run.StartedAt,
+		FinishedAt:   run.FinishedAt,
+	}
+}
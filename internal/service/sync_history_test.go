@@ -0,0 +1,91 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"hermit/internal/proxysync"
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
+)
+
+func TestBuildSyncRunRecord(t *testing.T) {
+	t.Parallel()
+
+	runID := uuid.New()
+	summary := proxysync.Summary{
+		Repositories: 1,
+		Skills:       2,
+		Versions:     3,
+		Cached:       2,
+		Failed:       1,
+		Skipped:      1,
+		ByRepository: []proxysync.RepoStats{{
+			Repository: "proxy",
+			Skills:     2,
+			Versions:   3,
+			Cached:     2,
+			Failed:     1,
+			Skipped:    1,
+			Outcomes: []proxysync.SkillOutcome{
+				{Slug: "alpha", Outcome: proxysync.SkillOutcomeSkipped},
+				{Slug: "beta", Outcome: proxysync.SkillOutcomeFailed, Versions: 3, Cached: 2, Failed: 1, Error: "version 1.0.0: boom"},
+			},
+		}},
+	}
+
+	run, items, err := buildSyncRunRecord(runID, summary, nil)
+	if err != nil {
+		t.Fatalf("buildSyncRunRecord() error = %v", err)
+	}
+	if run.ID != runID || run.Status != store.SyncRunCompleted || run.Error != nil {
+		t.Fatalf("run = %#v", run)
+	}
+	if run.Skills != 2 || run.Versions != 3 || run.Cached != 2 || run.Failed != 1 || run.Skipped != 1 {
+		t.Fatalf("run counters = %#v", run)
+	}
+
+	var byRepo []map[string]any
+	if err := json.Unmarshal(run.ByRepository, &byRepo); err != nil {
+		t.Fatalf("unmarshal ByRepository: %v", err)
+	}
+	if len(byRepo) != 1 || byRepo[0]["Repository"] != "proxy" {
+		t.Fatalf("ByRepository = %s", run.ByRepository)
+	}
+	if _, ok := byRepo[0]["Outcomes"]; ok {
+		t.Fatalf("ByRepository should not embed outcomes: %s", run.ByRepository)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("items len = %d, want 2", len(items))
+	}
+	if items[0].Repository != "proxy" || items[0].Slug != "alpha" || items[0].Error != nil {
+		t.Fatalf("items[0] = %#v", items[0])
+	}
+	if items[1].Slug != "beta" || items[1].Outcome != proxysync.SkillOutcomeFailed || items[1].Error == nil || *items[1].Error != "version 1.0.0: boom" {
+		t.Fatalf("items[1] = %#v", items[1])
+	}
+}
+
+func TestBuildSyncRunRecord_Failed(t *testing.T) {
+	t.Parallel()
+
+	run, items, err := buildSyncRunRecord(uuid.New(), proxysync.Summary{}, errors.New("list repositories: timeout"))
+	if err != nil {
+		t.Fatalf("buildSyncRunRecord() error = %v", err)
+	}
+	if run.Status != store.SyncRunFailed {
+		t.Fatalf("run.Status = %q, want %q", run.Status, store.SyncRunFailed)
+	}
+	if run.Error == nil || *run.Error != "list repositories: timeout" {
+		t.Fatalf("run.Error = %v", run.Error)
+	}
+	if string(run.ByRepository) != "[]" {
+		t.Fatalf("run.ByRepository = %s, want []", run.ByRepository)
+	}
+	if len(items) != 0 {
+		t.Fatalf("items = %#v, want none", items)
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"hermit/internal/auth"
+	"hermit/internal/semver"
+	"hermit/internal/store"
+)
+
+// latestTag tracks the highest release. Setting it pins it to a version
+// until it is deleted, which hands it back to the highest release.
+const latestTag = "latest"
+
+var tagNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._-]{0,63}$`)
+
+// normalizeTag validates a dist-tag name. Tags that read as a version or a
+// range would be ambiguous wherever a tag and a version are accepted.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if !tagNamePattern.MatchString(tag) {
+		return "", fmt.Errorf("%w: tag must start with a letter and contain only letters, digits, '.', '_' or '-' (max 64)", ErrInvalidInput)
+	}
+	if semver.IsRange(tag) {
+		return "", fmt.Errorf("%w: tag %q looks like a version range", ErrInvalidInput, tag)
+	}
+	return tag, nil
+}
+
+// ListSkillTags returns the dist-tags of slug as tag -> version.
+func (s *Service) ListSkillTags(ctx context.Context, repo store.Repository, actor auth.Actor, slug string) (map[string]string, error) {
+	skill, err := s.lookupSkill(ctx, repo, actor, slug)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSkillTags(skill.Tags)
+}
+
+// GetSkillTag returns the version tag points at.
+func (s *Service) GetSkillTag(ctx context.Context, repo store.Repository, actor auth.Actor, slug, tag string) (string, error) {
+	tags, err := s.ListSkillTags(ctx, repo, actor, slug)
+	if err != nil {
+		return "", err
+	}
+	version, ok := tags[strings.TrimSpace(tag)]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return version, nil
+}
+
+// SetSkillTag points tag at an existing version of slug in a hosted
+// repository. Setting latest pins it: downloads and installs without a
+// version resolve to it and publishes leave it alone.
+func (s *Service) SetSkillTag(ctx context.Context, repo store.Repository, slug, tag, version string) error {
+	slug = normalizeSlug(slug)
+	if slug == "" {
+		return fmt.Errorf("%w: slug required", ErrInvalidInput)
+	}
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return fmt.Errorf("%w: version required", ErrInvalidInput)
+	}
+	_, v, err := s.store.GetSkillVersion(ctx, repo.ID, slug, version)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return fmt.Errorf("%w: version %s of %s does not exist", ErrNotFound, version, slug)
+		}
+		return err
+	}
+	if tag == latestTag {
+		if v.YankedAt != nil {
+			return fmt.Errorf("%w: version %s of %s is yanked", ErrInvalidInput, version, slug)
+		}
+		err = s.store.PinLatestTag(ctx, repo.ID, slug, version)
+	} else {
+		err = s.store.SetSkillTag(ctx, repo.ID, slug, tag, version)
+	}
+	if err != nil {
+		if store.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteSkillTag removes tag from slug in a hosted repository. Deleting
+// latest unpins it and points it back at the highest release.
+func (s *Service) DeleteSkillTag(ctx context.Context, repo store.Repository, slug, tag string) error {
+	slug = normalizeSlug(slug)
+	if slug == "" {
+		return fmt.Errorf("%w: slug required", ErrInvalidInput)
+	}
+	tag = strings.TrimSpace(tag)
+	if tag == latestTag {
+		return s.unpinLatestTag(ctx, repo, slug)
+	}
+	if err := s.store.DeleteSkillTag(ctx, repo.ID, slug, tag); err != nil {
+		if store.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) unpinLatestTag(ctx context.Context, repo store.Repository, slug string) error {
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	packageID, err := s.store.UnpinLatestTagTx(ctx, tx, repo.ID, slug)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if err := s.store.RepointLatestTagTx(ctx, tx, packageID, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func decodeSkillTags(raw json.RawMessage) (map[string]string, error) {
+	tags := map[string]string{}
+	if len(raw) == 0 {
+		return tags, nil
+	}
+	var values map[string]any
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, fmt.Errorf("decode tags: %w", err)
+	}
+	for tag, v := range values {
+		if version, ok := v.(string); ok {
+			tags[tag] = version
+		}
+	}
+	return tags, nil
+}
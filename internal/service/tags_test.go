@@ -0,0 +1,58 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "stable", want: "stable"},
+		{input: " beta ", want: "beta"},
+		{input: "latest", want: "latest"},
+		{input: "next-2.x_rc.1", want: "next-2.x_rc.1"},
+		{input: "", wantErr: true},
+		{input: "1.2.3", wantErr: true},
+		{input: "-dash", wantErr: true},
+		{input: "has space", wantErr: true},
+		{input: "x", wantErr: true},
+		{input: "v1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+			got, err := normalizeTag(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidInput) {
+					t.Fatalf("normalizeTag(%q) error = %v, want ErrInvalidInput", tt.input, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("normalizeTag(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeSkillTags(t *testing.T) {
+	t.Parallel()
+	got, err := decodeSkillTags(json.RawMessage(`{"latest":"2.0.0","stable":"1.4.2","bogus":3}`))
+	if err != nil {
+		t.Fatalf("decodeSkillTags() error = %v", err)
+	}
+	want := map[string]string{"latest": "2.0.0", "stable": "1.4.2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("decodeSkillTags() = %v, want %v", got, want)
+	}
+	if got, err := decodeSkillTags(nil); err != nil || len(got) != 0 {
+		t.Fatalf("decodeSkillTags(nil) = %v, %v, want empty", got, err)
+	}
+}
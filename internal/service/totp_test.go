@@ -0,0 +1,130 @@
+package service
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Key is the SHA-1 test key of RFC 6238, appendix B.
+var rfc6238Key = []byte("12345678901234567890")
+
+func TestHOTPRFC6238Vectors(t *testing.T) {
+	t.Parallel()
+
+	// The RFC lists 8-digit codes; the 6-digit code is their last 6 digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+	for _, tt := range tests {
+		if got := hotp(rfc6238Key, uint64(tt.unix/totpPeriod)); got != tt.want {
+			t.Fatalf("hotp(T=%d) = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / totpPeriod
+	current := hotp(rfc6238Key, uint64(step))
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", code: current, wantStep: step, wantOK: true},
+		{name: "with spaces", code: current[:3] + " " + current[3:], wantStep: step, wantOK: true},
+		{name: "previous step", code: hotp(rfc6238Key, uint64(step-1)), wantStep: step - 1, wantOK: true},
+		{name: "next step", code: hotp(rfc6238Key, uint64(step+1)), wantStep: step + 1, wantOK: true},
+		{name: "too old", code: hotp(rfc6238Key, uint64(step-2))},
+		{name: "already used", code: current, lastStep: step},
+		{name: "wrong length", code: current[:5]},
+		{name: "wrong code", code: "000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			gotStep, ok := matchTOTP(rfc6238Key, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Fatalf("matchTOTP() = %d, %v, want %d, %v", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTOTPSecretRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		t.Fatalf("newTOTPSecret() error = %v", err)
+	}
+	if strings.Contains(secret, "=") {
+		t.Fatalf("newTOTPSecret() = %q, want no padding", secret)
+	}
+	key, err := decodeTOTPSecret(strings.ToLower(secret[:8]) + " " + secret[8:])
+	if err != nil {
+		t.Fatalf("decodeTOTPSecret() error = %v", err)
+	}
+	if got := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key); got != secret {
+		t.Fatalf("decodeTOTPSecret() = %q, want %q", got, secret)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	t.Parallel()
+
+	u, err := url.Parse(totpURI("alice", "JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatalf("totpURI() is not a URL: %v", err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/hermit:alice" {
+		t.Fatalf("totpURI() = %s, want otpauth://totp/hermit:alice", u)
+	}
+	q := u.Query()
+	if q.Get("secret") != "JBSWY3DPEHPK3PXP" || q.Get("issuer") != "hermit" {
+		t.Fatalf("totpURI() query = %v", q)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	t.Parallel()
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatalf("newRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("newRecoveryCodes() returned %d codes, %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Fatalf("code %q, want xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate code %q", code)
+		}
+		seen[code] = true
+		if hashRecoveryCode(code) != hashes[i] {
+			t.Fatalf("hash of %q does not match", code)
+		}
+		loose := " " + strings.ToUpper(strings.ReplaceAll(code, "-", "")) + " "
+		if hashRecoveryCode(loose) != hashes[i] {
+			t.Fatalf("hashRecoveryCode(%q) does not match %q", loose, code)
+		}
+	}
+}
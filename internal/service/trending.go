@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+const (
+	defaultTrendingHalfLife = 72 * time.Hour
+	defaultTrendingWindow   = 30 * 24 * time.Hour
+)
+
+// TrendingConfig controls how quickly downloads stop counting towards the
+// trending score. A download loses half its weight every HalfLife and is
+// ignored entirely once older than Window.
+type TrendingConfig struct {
+	HalfLife time.Duration
+	Window   time.Duration
+}
+
+// normalized fills in defaults and converts to whole days, the granularity
+// of the download buckets.
+func (c TrendingConfig) normalized() (halfLifeDays float64, windowDays int) {
+	halfLife := c.HalfLife
+	if halfLife <= 0 {
+		halfLife = defaultTrendingHalfLife
+	}
+	window := c.Window
+	if window <= 0 {
+		window = defaultTrendingWindow
+	}
+	halfLifeDays = halfLife.Hours() / 24
+	windowDays = int(math.Ceil(window.Hours() / 24))
+	if windowDays < 1 {
+		windowDays = 1
+	}
+	return halfLifeDays, windowDays
+}
+
+// RecomputeTrendingScores rebuilds the decayed trending score of every skill
+// from its daily download buckets and returns how many skills changed.
+func (s *Service) RecomputeTrendingScores(ctx context.Context, cfg TrendingConfig) (int64, error) {
+	halfLifeDays, windowDays := cfg.normalized()
+	return s.store.RecomputeTrendingScores(ctx, halfLifeDays, windowDays)
+}
@@ -0,0 +1,475 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"hermit/internal/store"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	configKeyTwoFactorPolicy = "two_factor_policy"
+
+	// loginChallengeTTL bounds the time between the password and the second
+	// factor of a login.
+	loginChallengeTTL = 5 * time.Minute
+	// maxLoginChallengeAttempts is the number of wrong codes a challenge
+	// takes before the password has to be entered again.
+	maxLoginChallengeAttempts = 5
+)
+
+var (
+	// errSecondFactorRequired ends the password step of a login that has to
+	// continue with a second factor.
+	errSecondFactorRequired = errors.New("second factor required")
+	errInvalidSecondFactor  = errors.New("invalid two-factor code")
+	errIncorrectPassword    = errors.New("password is incorrect")
+	errLoginChallenge       = errors.New("login challenge expired or invalid, log in again")
+)
+
+// TwoFactorPolicy is the site-wide 2FA policy. With RequireAdmins set, local
+// administrators without 2FA must enroll before their next login completes.
+type TwoFactorPolicy struct {
+	RequireAdmins bool `json:"require_admins"`
+}
+
+// TwoFactorStatus is the 2FA state of a local account as shown to its owner.
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// TOTPSetup is what an authenticator app needs to enroll. Secret is only
+// returned here.
+type TOTPSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// LoginChallenge asks the client to finish a password login with a second
+// factor, using Token within the TTL.
+type LoginChallenge struct {
+	Token string `json:"mfa_token"`
+	// Setup is set when the user has to enroll in 2FA before the login
+	// completes.
+	Setup     bool      `json:"mfa_setup_required"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LocalLoginResult is the outcome of a local login step. Token is set once
+// the login is complete; otherwise Challenge says how to continue.
+type LocalLoginResult struct {
+	Token     string
+	User      store.User
+	Challenge *LoginChallenge
+}
+
+func (s *Service) GetTwoFactorPolicy(ctx context.Context) (TwoFactorPolicy, error) {
+	raw, err := s.store.GetSystemConfig(ctx, configKeyTwoFactorPolicy)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return TwoFactorPolicy{}, nil
+		}
+		return TwoFactorPolicy{}, err
+	}
+	var p TwoFactorPolicy
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return TwoFactorPolicy{}, fmt.Errorf("decode two-factor policy: %w", err)
+	}
+	return p, nil
+}
+
+// SetTwoFactorPolicy stores the policy. It applies from each user's next
+// login; existing sessions stay valid.
+func (s *Service) SetTwoFactorPolicy(ctx context.Context, p TwoFactorPolicy) (TwoFactorPolicy, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return TwoFactorPolicy{}, err
+	}
+	if err := s.store.UpsertSystemConfig(ctx, configKeyTwoFactorPolicy, raw); err != nil {
+		return TwoFactorPolicy{}, err
+	}
+	return p, nil
+}
+
+// twoFactorRequired reports whether u must have 2FA under the policy.
+func (s *Service) twoFactorRequired(ctx context.Context, u store.User) (bool, error) {
+	if !u.IsAdmin {
+		return false, nil
+	}
+	p, err := s.GetTwoFactorPolicy(ctx)
+	if err != nil {
+		return false, err
+	}
+	return p.RequireAdmins, nil
+}
+
+// startLoginChallenge records that u passed the password step.
+func (s *Service) startLoginChallenge(ctx context.Context, u store.User) (*LoginChallenge, error) {
+	token, err := generateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	ch := store.PendingLoginChallenge{
+		TokenHash: hashChallengeToken(token),
+		UserID:    u.ID,
+		Username:  u.Username,
+		Setup:     !u.TOTPEnabled,
+		ExpiresAt: time.Now().Add(loginChallengeTTL).UTC(),
+	}
+	if err := s.store.CreateLoginChallenge(ctx, ch); err != nil {
+		return nil, err
+	}
+	return &LoginChallenge{Token: token, Setup: ch.Setup, ExpiresAt: ch.ExpiresAt}, nil
+}
+
+// loginChallenge returns the pending challenge for a challenge token.
+func (s *Service) loginChallenge(ctx context.Context, token string) (store.PendingLoginChallenge, error) {
+	ch, err := s.store.GetLoginChallenge(ctx, hashChallengeToken(token))
+	if err != nil {
+		if store.IsNotFound(err) {
+			return store.PendingLoginChallenge{}, errLoginChallenge
+		}
+		return store.PendingLoginChallenge{}, err
+	}
+	if ch.Attempts >= maxLoginChallengeAttempts {
+		return store.PendingLoginChallenge{}, errLoginChallenge
+	}
+	return ch, nil
+}
+
+func hashChallengeToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+// CompleteTwoFactorLogin finishes a local login with a TOTP or recovery
+// code. Wrong codes count toward the login lockout like wrong passwords.
+func (s *Service) CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string, client SessionClient) (LocalLoginResult, error) {
+	ch, err := s.loginChallenge(ctx, challengeToken)
+	if err != nil {
+		return LocalLoginResult{}, err
+	}
+	if ch.Setup {
+		return LocalLoginResult{}, fmt.Errorf("%w: two-factor enrollment required", ErrInvalidInput)
+	}
+	return s.finishLoginChallenge(ctx, ch, client, func(u store.User) (bool, error) {
+		return s.verifySecondFactor(ctx, u.ID, code)
+	})
+}
+
+// BeginLoginTOTPSetup starts 2FA enrollment for a login that requires it.
+func (s *Service) BeginLoginTOTPSetup(ctx context.Context, challengeToken string) (TOTPSetup, error) {
+	ch, err := s.loginChallenge(ctx, challengeToken)
+	if err != nil {
+		return TOTPSetup{}, err
+	}
+	if !ch.Setup {
+		return TOTPSetup{}, fmt.Errorf("%w: two-factor authentication is already enabled", ErrConflict)
+	}
+	return s.beginTOTPSetup(ctx, ch.UserID, ch.Username)
+}
+
+// CompleteLoginTOTPSetup enables 2FA with the first code from the
+// authenticator app and completes the login. It returns the recovery codes,
+// which are not shown again.
+func (s *Service) CompleteLoginTOTPSetup(ctx context.Context, challengeToken, code string, client SessionClient) (LocalLoginResult, []string, error) {
+	ch, err := s.loginChallenge(ctx, challengeToken)
+	if err != nil {
+		return LocalLoginResult{}, nil, err
+	}
+	if !ch.Setup {
+		return LocalLoginResult{}, nil, fmt.Errorf("%w: two-factor authentication is already enabled", ErrConflict)
+	}
+	var codes []string
+	res, err := s.finishLoginChallenge(ctx, ch, client, func(u store.User) (bool, error) {
+		var err error
+		codes, err = s.enableTOTP(ctx, u.ID, code)
+		if errors.Is(err, errInvalidSecondFactor) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return LocalLoginResult{}, nil, err
+	}
+	return res, codes, nil
+}
+
+// finishLoginChallenge runs verify as the second login step under the login
+// throttle and issues the session once it succeeds.
+func (s *Service) finishLoginChallenge(ctx context.Context, ch store.PendingLoginChallenge, client SessionClient, verify func(store.User) (bool, error)) (LocalLoginResult, error) {
+	var u store.User
+	err := s.guardLogin(ctx, ProviderTypeLocal, ch.Username, client, func() (bool, error) {
+		var err error
+		u, err = s.store.GetUserByID(ctx, ch.UserID)
+		if err != nil {
+			if store.IsNotFound(err) {
+				return false, errLoginChallenge
+			}
+			return false, err
+		}
+		if u.Disabled {
+			return false, fmt.Errorf("account disabled")
+		}
+		ok, err := verify(u)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			if err := s.store.FailLoginChallenge(ctx, ch.TokenHash, maxLoginChallengeAttempts); err != nil {
+				return false, err
+			}
+			return true, errInvalidSecondFactor
+		}
+		return false, nil
+	})
+	if err != nil {
+		return LocalLoginResult{}, err
+	}
+	if err := s.store.DeleteLoginChallenge(ctx, ch.TokenHash); err != nil {
+		return LocalLoginResult{}, err
+	}
+
+	sessionToken, err := s.IssueSessionToken(ctx, u.Username, u.IsAdmin, client)
+	if err != nil {
+		return LocalLoginResult{}, err
+	}
+	return LocalLoginResult{Token: sessionToken, User: u}, nil
+}
+
+// verifySecondFactor checks a TOTP code or an unused recovery code of a
+// user with 2FA enabled. Each code is accepted once.
+func (s *Service) verifySecondFactor(ctx context.Context, userID uuid.UUID, code string) (bool, error) {
+	t, err := s.store.GetUserTOTP(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if !t.Enabled {
+		return false, nil
+	}
+	code = strings.TrimSpace(code)
+	if len(strings.ReplaceAll(code, " ", "")) == totpDigits {
+		key, err := decodeTOTPSecret(t.Secret)
+		if err != nil {
+			return false, err
+		}
+		step, ok := matchTOTP(key, code, time.Now(), t.LastStep)
+		if !ok {
+			return false, nil
+		}
+		return s.store.AdvanceTOTPStep(ctx, userID, step)
+	}
+	return s.store.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+}
+
+func (s *Service) beginTOTPSetup(ctx context.Context, userID uuid.UUID, username string) (TOTPSetup, error) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return TOTPSetup{}, err
+	}
+	if err := s.store.SetPendingTOTPSecret(ctx, userID, secret); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return TOTPSetup{}, fmt.Errorf("%w: two-factor authentication is already enabled", ErrConflict)
+		}
+		if store.IsNotFound(err) {
+			return TOTPSetup{}, ErrNotFound
+		}
+		return TOTPSetup{}, err
+	}
+	return TOTPSetup{Secret: secret, URI: totpURI(username, secret)}, nil
+}
+
+// enableTOTP checks code against the pending secret and turns 2FA on. It
+// returns errInvalidSecondFactor when the code does not match.
+func (s *Service) enableTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	t, err := s.store.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if t.Enabled {
+		return nil, fmt.Errorf("%w: two-factor authentication is already enabled", ErrConflict)
+	}
+	if t.Secret == "" {
+		return nil, fmt.Errorf("%w: start two-factor setup first", ErrInvalidInput)
+	}
+	key, err := decodeTOTPSecret(t.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := matchTOTP(key, code, time.Now(), t.LastStep)
+	if !ok {
+		return nil, errInvalidSecondFactor
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return nil, fmt.Errorf("%w: two-factor authentication is already enabled", ErrConflict)
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// localUser returns the local account of a logged-in subject.
+func (s *Service) localUser(ctx context.Context, username string) (store.User, error) {
+	u, err := s.store.GetUserByUsername(ctx, strings.TrimSpace(username))
+	if err != nil {
+		if store.IsNotFound(err) {
+			return store.User{}, fmt.Errorf("%w: two-factor authentication is only available for local accounts", ErrNotFound)
+		}
+		return store.User{}, err
+	}
+	return u, nil
+}
+
+func (s *Service) GetTwoFactorStatus(ctx context.Context, username string) (TwoFactorStatus, error) {
+	u, err := s.localUser(ctx, username)
+	if err != nil {
+		return TwoFactorStatus{}, err
+	}
+	required, err := s.twoFactorRequired(ctx, u)
+	if err != nil {
+		return TwoFactorStatus{}, err
+	}
+	status := TwoFactorStatus{Enabled: u.TOTPEnabled, Required: required}
+	if u.TOTPEnabled {
+		if status.RecoveryCodesRemaining, err = s.store.CountRecoveryCodes(ctx, u.ID); err != nil {
+			return TwoFactorStatus{}, err
+		}
+	}
+	return status, nil
+}
+
+// BeginTOTPSetup starts 2FA enrollment for a logged-in local user. Calling
+// it again before EnableTOTP replaces the secret.
+func (s *Service) BeginTOTPSetup(ctx context.Context, username string) (TOTPSetup, error) {
+	u, err := s.localUser(ctx, username)
+	if err != nil {
+		return TOTPSetup{}, err
+	}
+	return s.beginTOTPSetup(ctx, u.ID, u.Username)
+}
+
+// EnableTOTP finishes enrollment with the first code from the
+// authenticator app and returns the recovery codes.
+func (s *Service) EnableTOTP(ctx context.Context, username, code string) ([]string, error) {
+	u, err := s.localUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.enableTOTP(ctx, u.ID, code)
+	if errors.Is(err, errInvalidSecondFactor) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return codes, err
+}
+
+// DisableTOTP turns 2FA off after checking the password and a current
+// code. Administrators cannot while the policy requires 2FA for them.
+func (s *Service) DisableTOTP(ctx context.Context, username, password, code string, client SessionClient) error {
+	u, err := s.localUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	if !u.TOTPEnabled {
+		return fmt.Errorf("%w: two-factor authentication is not enabled", ErrInvalidInput)
+	}
+	required, err := s.twoFactorRequired(ctx, u)
+	if err != nil {
+		return err
+	}
+	if required {
+		return fmt.Errorf("%w: two-factor authentication is required for administrators", ErrInvalidInput)
+	}
+	if err := s.verifyAccountFactors(ctx, u, password, code, client); err != nil {
+		return err
+	}
+	return s.store.DisableTOTP(ctx, u.ID)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after checking the
+// password and a current code.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, username, password, code string, client SessionClient) ([]string, error) {
+	u, err := s.localUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if !u.TOTPEnabled {
+		return nil, fmt.Errorf("%w: two-factor authentication is not enabled", ErrInvalidInput)
+	}
+	if err := s.verifyAccountFactors(ctx, u, password, code, client); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.ReplaceRecoveryCodes(ctx, u.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifyAccountFactors checks the password and a current code of a
+// logged-in user before a 2FA change. Wrong guesses count toward the login
+// lockout, so a stolen session cannot brute-force the second factor.
+func (s *Service) verifyAccountFactors(ctx context.Context, u store.User, password, code string, client SessionClient) error {
+	err := s.guardLogin(ctx, ProviderTypeLocal, u.Username, client, func() (bool, error) {
+		if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+			return true, errIncorrectPassword
+		}
+		ok, err := s.verifySecondFactor(ctx, u.ID, code)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return true, errInvalidSecondFactor
+		}
+		return false, nil
+	})
+	if errors.Is(err, errIncorrectPassword) || errors.Is(err, errInvalidSecondFactor) {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return err
+}
+
+// ResetUserTwoFactor turns off 2FA for a user who lost their device (admin
+// only). Under a policy requiring it, the user enrolls again at next login.
+func (s *Service) ResetUserTwoFactor(ctx context.Context, userID string) (store.User, error) {
+	id, err := uuid.Parse(strings.TrimSpace(userID))
+	if err != nil {
+		return store.User{}, fmt.Errorf("%w: invalid user ID", ErrInvalidInput)
+	}
+	u, err := s.store.GetUserByID(ctx, id)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return store.User{}, ErrNotFound
+		}
+		return store.User{}, err
+	}
+	if err := s.store.DisableTOTP(ctx, id); err != nil {
+		if store.IsNotFound(err) {
+			return store.User{}, ErrNotFound
+		}
+		return store.User{}, err
+	}
+	u.TOTPEnabled = false
+	return u, nil
+}
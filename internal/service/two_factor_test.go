@@ -0,0 +1,18 @@
+package service
+
+import "testing"
+
+func TestHashChallengeToken(t *testing.T) {
+	t.Parallel()
+
+	a := hashChallengeToken("token-a")
+	if a == "token-a" || len(a) != 64 {
+		t.Fatalf("hashChallengeToken() = %q, want hex sha256", a)
+	}
+	if got := hashChallengeToken("  token-a\n"); got != a {
+		t.Fatalf("hashChallengeToken() with spaces = %q, want %q", got, a)
+	}
+	if got := hashChallengeToken("token-b"); got == a {
+		t.Fatalf("hashChallengeToken() of different tokens are equal")
+	}
+}
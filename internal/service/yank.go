@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"hermit/internal/store"
+)
+
+// maxNoticeLength bounds yank reasons and deprecation messages.
+const maxNoticeLength = 1024
+
+// VersionNotice tells clients about a yanked or deprecated version.
+type VersionNotice struct {
+	YankedAt    *time.Time
+	YankReason  *string
+	Deprecation *string
+}
+
+// normalizeNotice trims a yank reason or deprecation message. An empty
+// message becomes nil.
+func normalizeNotice(message string) (*string, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return nil, nil
+	}
+	if len(message) > maxNoticeLength {
+		return nil, fmt.Errorf("%w: message exceeds %d bytes", ErrInvalidInput, maxNoticeLength)
+	}
+	return &message, nil
+}
+
+// SetVersionYanked yanks or restores a version in a hosted repository. A
+// yanked version can still be downloaded by exact version but is skipped by
+// latest and range resolution. Yanking the version latest points at moves
+// the tag to the next highest version. Restoring one moves latest to it only
+// if it is now the highest version and latest is not pinned, or if latest
+// points at a yanked version.
+func (s *Service) SetVersionYanked(
+	ctx context.Context,
+	repo store.Repository,
+	slug string,
+	version string,
+	yanked bool,
+	reason string,
+) error {
+	slug = normalizeSlug(slug)
+	if slug == "" {
+		return fmt.Errorf("%w: slug required", ErrInvalidInput)
+	}
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return fmt.Errorf("%w: version required", ErrInvalidInput)
+	}
+	var reasonPtr *string
+	if yanked {
+		var err error
+		if reasonPtr, err = normalizeNotice(reason); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	packageID, err := s.store.SetVersionYankedTx(ctx, tx, repo.ID, slug, version, yanked, reasonPtr)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if yanked {
+		err = s.store.RepointLatestTagTx(ctx, tx, packageID, &version)
+	} else {
+		err = s.store.RepointLatestTagOnRestoreTx(ctx, tx, packageID, version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetSkillDeprecation sets the deprecation message of a skill in a hosted
+// repository; an empty message clears it.
+func (s *Service) SetSkillDeprecation(ctx context.Context, repo store.Repository, slug, message string) error {
+	slug = normalizeSlug(slug)
+	if slug == "" {
+		return fmt.Errorf("%w: slug required", ErrInvalidInput)
+	}
+	msg, err := normalizeNotice(message)
+	if err != nil {
+		return err
+	}
+	if err := s.store.SetSkillDeprecation(ctx, repo.ID, slug, msg); err != nil {
+		if store.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// SetVersionDeprecation sets the deprecation message of one version in a
+// hosted repository; an empty message clears it.
+func (s *Service) SetVersionDeprecation(ctx context.Context, repo store.Repository, slug, version, message string) error {
+	slug = normalizeSlug(slug)
+	if slug == "" {
+		return fmt.Errorf("%w: slug required", ErrInvalidInput)
+	}
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return fmt.Errorf("%w: version required", ErrInvalidInput)
+	}
+	msg, err := normalizeNotice(message)
+	if err != nil {
+		return err
+	}
+	if err := s.store.SetVersionDeprecation(ctx, repo.ID, slug, version, msg); err != nil {
+		if store.IsNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func versionNotice(v store.SkillVersion) VersionNotice {
+	return VersionNotice{YankedAt: v.YankedAt, YankReason: v.YankReason, Deprecation: v.Deprecation}
+}
+
+func artifactNotice(a store.Artifact) VersionNotice {
+	return VersionNotice{YankedAt: a.YankedAt, YankReason: a.YankReason, Deprecation: a.VersionDeprecation}
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeNotice(t *testing.T) {
+	t.Parallel()
+
+	got, err := normalizeNotice("  use v2 instead ")
+	if err != nil || got == nil || *got != "use v2 instead" {
+		t.Fatalf("normalizeNotice() = %v, %v, want trimmed message", got, err)
+	}
+	if got, err := normalizeNotice("   "); err != nil || got != nil {
+		t.Fatalf("normalizeNotice(blank) = %v, %v, want nil", got, err)
+	}
+	if _, err := normalizeNotice(strings.Repeat("x", maxNoticeLength+1)); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("normalizeNotice(long) error = %v, want ErrInvalidInput", err)
+	}
+}
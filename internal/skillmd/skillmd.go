@@ -0,0 +1,196 @@
+// Package skillmd parses the YAML frontmatter of SKILL.md manifests.
+//
+// The frontmatter is decoded with a full YAML parser. The result must be a
+// mapping and is converted to JSON-compatible values: mappings become
+// map[string]any, integers int64, dates stay strings, and block scalars
+// lose their trailing newlines. Anchors, aliases and custom tags are
+// rejected, as manifests are stored and served as JSON.
+package skillmd
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is the parsed frontmatter of a SKILL.md file.
+type Manifest struct {
+	Name         string
+	Description  string
+	Version      string
+	License      string
+	Homepage     string
+	AllowedTools []string
+	// Fields holds every frontmatter key, including the ones above.
+	Fields map[string]any
+}
+
+// Parse extracts and parses the frontmatter of a SKILL.md document. found is
+// false when the document has no frontmatter block.
+func Parse(content []byte) (m Manifest, found bool, err error) {
+	block, found, err := frontmatter(content)
+	if err != nil || !found {
+		return Manifest{}, found, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(block), &doc); err != nil {
+		return Manifest{}, true, fmt.Errorf("frontmatter: %w", err)
+	}
+	fields := map[string]any{}
+	if len(doc.Content) > 0 {
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return Manifest{}, true, fmt.Errorf("frontmatter line %d: expected \"key: value\" pairs", lineNum(root))
+		}
+		v, err := convert(root)
+		if err != nil {
+			return Manifest{}, true, err
+		}
+		fields = v.(map[string]any)
+	}
+
+	m = Manifest{
+		Name:         stringField(fields, "name"),
+		Description:  stringField(fields, "description"),
+		Version:      stringField(fields, "version"),
+		License:      stringField(fields, "license"),
+		Homepage:     stringField(fields, "homepage"),
+		AllowedTools: listField(fields, "allowed-tools"),
+		Fields:       fields,
+	}
+	if m.AllowedTools == nil {
+		m.AllowedTools = listField(fields, "tools")
+	}
+	return m, true, nil
+}
+
+// frontmatter returns the text between the opening and closing "---" lines.
+func frontmatter(content []byte) (string, bool, error) {
+	text := string(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	first, rest, _ := strings.Cut(text, "\n")
+	if strings.TrimRight(first, " \t") != "---" {
+		return "", false, nil
+	}
+	var block []string
+	for {
+		var line string
+		var more bool
+		line, rest, more = strings.Cut(rest, "\n")
+		trimmed := strings.TrimRight(line, " \t")
+		if trimmed == "---" || trimmed == "..." {
+			return strings.Join(block, "\n"), true, nil
+		}
+		if !more {
+			return "", true, fmt.Errorf("frontmatter is not terminated by ---")
+		}
+		block = append(block, line)
+	}
+}
+
+// lineNum returns the line of n in the SKILL.md file, counting the opening
+// --- as line 1.
+func lineNum(n *yaml.Node) int {
+	return n.Line + 1
+}
+
+// convert turns a decoded YAML node into JSON-compatible values.
+func convert(n *yaml.Node) (any, error) {
+	if n.Kind == yaml.AliasNode || n.Anchor != "" {
+		return nil, fmt.Errorf("frontmatter line %d: anchors and aliases are not supported", lineNum(n))
+	}
+	if n.Style&yaml.TaggedStyle != 0 && !strings.HasPrefix(n.Tag, "!!") {
+		return nil, fmt.Errorf("frontmatter line %d: tag %s is not supported", lineNum(n), n.Tag)
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		out := make(map[string]any, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("frontmatter line %d: keys must be scalars", lineNum(k))
+			}
+			if _, dup := out[k.Value]; dup {
+				return nil, fmt.Errorf("frontmatter line %d: duplicate key %q", lineNum(k), k.Value)
+			}
+			value, err := convert(v)
+			if err != nil {
+				return nil, err
+			}
+			out[k.Value] = value
+		}
+		return out, nil
+	case yaml.SequenceNode:
+		out := make([]any, 0, len(n.Content))
+		for _, item := range n.Content {
+			value, err := convert(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+		}
+		return out, nil
+	case yaml.ScalarNode:
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return nil, fmt.Errorf("frontmatter line %d: %w", lineNum(n), err)
+		}
+		switch v := v.(type) {
+		case int:
+			return int64(v), nil
+		case time.Time:
+			// Keep dates as written rather than as RFC 3339 timestamps.
+			return n.Value, nil
+		case string:
+			if n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+				return strings.TrimRight(v, "\n"), nil
+			}
+			return v, nil
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("frontmatter line %d: unsupported YAML node", lineNum(n))
+}
+
+func stringField(fields map[string]any, key string) string {
+	switch v := fields[key].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// listField accepts both a sequence and a comma- or space-separated string,
+// as "allowed-tools: Read, Grep" is common in the wild.
+func listField(fields map[string]any, key string) []string {
+	var out []string
+	switch v := fields[key].(type) {
+	case []any:
+		for _, item := range v {
+			if s := strings.TrimSpace(fmt.Sprint(item)); item != nil && s != "" {
+				out = append(out, s)
+			}
+		}
+	case string:
+		sep := func(r rune) bool { return r == ',' || r == ' ' }
+		if strings.Contains(v, ",") {
+			sep = func(r rune) bool { return r == ',' }
+		}
+		for _, item := range strings.FieldsFunc(v, sep) {
+			if s := strings.TrimSpace(item); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,166 @@
+package skillmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	doc := `---
+name: pdf-tools
+description: >
+  Extract text and tables
+  from PDF files.
+version: "1.2.0"
+license: MIT
+homepage: https://example.com/pdf-tools # project page
+allowed-tools: [Read, Grep, "Bash(pdftotext:*)"]
+metadata: {"openclaw": {"requires": {"bins": ["pdftotext"]}}}
+keywords:
+  - pdf
+  - documents
+examples:
+  - prompt: summarize this PDF
+    expect: a summary
+notes: |
+  Line one.
+  Line two.
+enabled: true
+retries: 3
+---
+# PDF Tools
+`
+	m, found, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !found {
+		t.Fatal("Parse() found = false, want true")
+	}
+
+	if m.Name != "pdf-tools" {
+		t.Fatalf("Name = %q, want pdf-tools", m.Name)
+	}
+	if m.Description != "Extract text and tables from PDF files." {
+		t.Fatalf("Description = %q", m.Description)
+	}
+	if m.Version != "1.2.0" || m.License != "MIT" || m.Homepage != "https://example.com/pdf-tools" {
+		t.Fatalf("Version, License, Homepage = %q, %q, %q", m.Version, m.License, m.Homepage)
+	}
+	wantTools := []string{"Read", "Grep", "Bash(pdftotext:*)"}
+	if !reflect.DeepEqual(m.AllowedTools, wantTools) {
+		t.Fatalf("AllowedTools = %#v, want %#v", m.AllowedTools, wantTools)
+	}
+
+	wantFields := map[string]any{
+		"keywords": []any{"pdf", "documents"},
+		"examples": []any{map[string]any{"prompt": "summarize this PDF", "expect": "a summary"}},
+		"notes":    "Line one.\nLine two.",
+		"enabled":  true,
+		"retries":  int64(3),
+		"metadata": map[string]any{"openclaw": map[string]any{"requires": map[string]any{"bins": []any{"pdftotext"}}}},
+	}
+	for key, want := range wantFields {
+		if got := m.Fields[key]; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Fields[%q] = %#v, want %#v", key, got, want)
+		}
+	}
+}
+
+func TestParse_NoFrontmatter(t *testing.T) {
+	t.Parallel()
+	for _, doc := range []string{"", "# Title\n\nBody", "text\n---\nname: x\n---\n"} {
+		_, found, err := Parse([]byte(doc))
+		if err != nil || found {
+			t.Fatalf("Parse(%q) = found %v, err %v; want not found", doc, found, err)
+		}
+	}
+}
+
+func TestParse_CRLFAndBOM(t *testing.T) {
+	t.Parallel()
+	m, found, err := Parse([]byte("\xef\xbb\xbf---\r\nname: demo\r\nallowed-tools: Read Grep\r\n---\r\nbody"))
+	if err != nil || !found {
+		t.Fatalf("Parse() found = %v, err = %v", found, err)
+	}
+	if m.Name != "demo" {
+		t.Fatalf("Name = %q, want demo", m.Name)
+	}
+	if want := []string{"Read", "Grep"}; !reflect.DeepEqual(m.AllowedTools, want) {
+		t.Fatalf("AllowedTools = %#v, want %#v", m.AllowedTools, want)
+	}
+}
+
+func TestParse_PlainScalarContinuation(t *testing.T) {
+	t.Parallel()
+	m, _, err := Parse([]byte("---\ndescription: Converts documents\n  between formats.\nname: conv\n---\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if m.Description != "Converts documents between formats." {
+		t.Fatalf("Description = %q", m.Description)
+	}
+	if m.Name != "conv" {
+		t.Fatalf("Name = %q, want conv", m.Name)
+	}
+}
+
+func TestParse_MultiLine(t *testing.T) {
+	t.Parallel()
+	doc := `---
+name: conv
+description: "Converts documents
+  between formats."
+allowed-tools: [
+  Read,
+  "Bash(pandoc:*)",
+]
+meta: {a: 1, b: [x, y]}
+released: 2024-05-01
+---
+`
+	m, _, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if m.Description != "Converts documents between formats." {
+		t.Fatalf("Description = %q", m.Description)
+	}
+	if want := []string{"Read", "Bash(pandoc:*)"}; !reflect.DeepEqual(m.AllowedTools, want) {
+		t.Fatalf("AllowedTools = %#v, want %#v", m.AllowedTools, want)
+	}
+	if want := map[string]any{"a": int64(1), "b": []any{"x", "y"}}; !reflect.DeepEqual(m.Fields["meta"], want) {
+		t.Fatalf("Fields[meta] = %#v, want %#v", m.Fields["meta"], want)
+	}
+	if got := m.Fields["released"]; got != "2024-05-01" {
+		t.Fatalf("Fields[released] = %#v, want \"2024-05-01\"", got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{name: "unterminated", doc: "---\nname: x\n"},
+		{name: "missing colon", doc: "---\njust text\n---\n"},
+		{name: "duplicate key", doc: "---\nname: a\nname: b\n---\n"},
+		{name: "bad indentation", doc: "---\nmeta:\n  a: 1\n b: 2\n---\n"},
+		{name: "unterminated quote", doc: "---\nname: \"abc\n---\n"},
+		{name: "anchor", doc: "---\nname: &x abc\n---\n"},
+		{name: "unclosed flow sequence", doc: "---\ntools: [Read, Grep\n---\n"},
+		{name: "custom tag", doc: "---\nname: !skill abc\n---\n"},
+		{name: "not a mapping", doc: "---\n- a\n- b\n---\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, _, err := Parse([]byte(tt.doc)); err == nil {
+				t.Fatalf("Parse(%q) error = nil, want error", tt.doc)
+			}
+		})
+	}
+}
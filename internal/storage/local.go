@@ -4,10 +4,13 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // LocalBlobStore stores artifacts by sha256 digest on local disk.
@@ -52,7 +55,7 @@ func (b *LocalBlobStore) PutStream(_ context.Context, r io.Reader) (digest strin
 	size = n
 
 	hexDigest := hex.EncodeToString(sum)
-	key = filepath.Join("sha256", hexDigest[:2], hexDigest)
+	key = filepath.Join(blobKeyPrefix, hexDigest[:2], hexDigest)
 	absPath := filepath.Join(b.root, key)
 
 	if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
@@ -60,6 +63,10 @@ func (b *LocalBlobStore) PutStream(_ context.Context, r io.Reader) (digest strin
 	}
 	if _, statErr := os.Stat(absPath); statErr == nil {
 		_ = os.Remove(tmpName)
+		// Refresh the modification time so garbage collection treats a
+		// re-uploaded blob as new until its asset row is committed.
+		now := time.Now()
+		_ = os.Chtimes(absPath, now, now)
 		return digest, size, key, nil
 	}
 
@@ -86,3 +93,46 @@ func (b *LocalBlobStore) Open(_ context.Context, key string) (*BlobFile, error)
 	}
 	return NewBlobFile(f, info.Size()), nil
 }
+
+func (b *LocalBlobStore) Stat(_ context.Context, key string) (BlobInfo, error) {
+	info, err := os.Stat(filepath.Join(b.root, key))
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	return BlobInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (b *LocalBlobStore) Delete(_ context.Context, key string) error {
+	if err := os.Remove(filepath.Join(b.root, key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (b *LocalBlobStore) List(ctx context.Context, fn func(BlobInfo) error) error {
+	dir := filepath.Join(b.root, blobKeyPrefix)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		key, err := filepath.Rel(b.root, p)
+		if err != nil {
+			return err
+		}
+		return fn(BlobInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
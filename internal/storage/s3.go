@@ -4,13 +4,17 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 // S3BlobStore stores artifacts in an S3-compatible bucket (AWS S3, MinIO, etc.).
@@ -65,7 +69,7 @@ func (s *S3BlobStore) PutStream(ctx context.Context, r io.Reader) (digest string
 	hexDigest := hex.EncodeToString(sum)
 	digest = "sha256:" + hexDigest
 	size = n
-	key = filepath.Join("sha256", hexDigest[:2], hexDigest)
+	key = filepath.Join(blobKeyPrefix, hexDigest[:2], hexDigest)
 
 	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
 		return "", 0, "", fmt.Errorf("seek tmp file: %w", err)
@@ -91,6 +95,10 @@ func (s *S3BlobStore) Open(ctx context.Context, key string) (*BlobFile, error) {
 		Key:    aws.String(s.objectKey(key)),
 	})
 	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, fmt.Errorf("s3 get %q: %w", key, fs.ErrNotExist)
+		}
 		return nil, fmt.Errorf("s3 get %q: %w", key, err)
 	}
 	defer resp.Body.Close()
@@ -116,3 +124,59 @@ func (s *S3BlobStore) Open(ctx context.Context, key string) (*BlobFile, error) {
 
 	return NewBlobFile(tmpFile, n), nil
 }
+
+func (s *S3BlobStore) Stat(ctx context.Context, key string) (BlobInfo, error) {
+	resp, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return BlobInfo{}, fmt.Errorf("s3 head %q: %w", key, fs.ErrNotExist)
+		}
+		return BlobInfo{}, fmt.Errorf("s3 head %q: %w", key, err)
+	}
+	info := BlobInfo{Key: key, Size: aws.ToInt64(resp.ContentLength)}
+	if resp.LastModified != nil {
+		info.ModTime = *resp.LastModified
+	}
+	return info, nil
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 delete %q: %w", key, err)
+	}
+	return nil
+}
+
+func (s *S3BlobStore) List(ctx context.Context, fn func(BlobInfo) error) error {
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.objectKey(blobKeyPrefix + "/")),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("s3 list: %w", err)
+		}
+		for _, obj := range page.Contents {
+			info := BlobInfo{
+				Key:  strings.TrimPrefix(aws.ToString(obj.Key), s.prefix),
+				Size: aws.ToInt64(obj.Size),
+			}
+			if obj.LastModified != nil {
+				info.ModTime = *obj.LastModified
+			}
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"os"
+	"time"
 )
 
 // BlobFile represents an opened blob that supports sequential read,
@@ -30,6 +31,29 @@ type BlobStorage interface {
 	PutStream(ctx context.Context, r io.Reader) (digest string, size int64, key string, err error)
 
 	// Open retrieves a previously stored blob by its key.
-	// The returned BlobFile must be closed by the caller.
+	// The returned BlobFile must be closed by the caller. A missing blob
+	// yields an error matching fs.ErrNotExist.
 	Open(ctx context.Context, key string) (*BlobFile, error)
+
+	// Stat describes a stored blob. A missing blob yields an error
+	// matching fs.ErrNotExist.
+	Stat(ctx context.Context, key string) (BlobInfo, error)
+
+	// Delete removes a blob. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// List calls fn for every stored blob, in no particular order, and
+	// stops at the first error fn returns.
+	List(ctx context.Context, fn func(BlobInfo) error) error
 }
+
+// BlobInfo describes a stored blob. ModTime is when it was last written,
+// which garbage collection compares against its grace period.
+type BlobInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// blobKeyPrefix is the directory (or key prefix) all blobs are stored under.
+const blobKeyPrefix = "sha256"
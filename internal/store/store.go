@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"time"
 
+	"hermit/internal/semver"
+
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -25,6 +27,20 @@ const (
 
 var ErrConflict = errors.New("conflict")
 
+// latestVersionOrder ranks the versions of a package for "latest": stable
+// releases before prereleases, then by semver precedence. Versions that are
+// not semantic versions come last, newest first. Queries for latest also
+// filter out yanked versions.
+const latestVersionOrder = `v.prerelease ASC, v.sort_key COLLATE "C" DESC NULLS LAST, v.created_at DESC`
+
+// taggedLatestOrder is latestVersionOrder with the version the latest tag
+// points at ranked first, so a pinned latest wins. The query must join the
+// package as p.
+const taggedLatestOrder = `(v.version = p.tags ->> 'latest') IS TRUE DESC, ` + latestVersionOrder
+
+// versionListOrder orders a version history by semver precedence.
+const versionListOrder = `v.sort_key COLLATE "C" DESC NULLS LAST, v.created_at DESC`
+
 type Repository struct {
 	ID          uuid.UUID
 	Name        string
@@ -42,6 +58,12 @@ type Artifact struct {
 	Digest      string
 	SizeBytes   int64
 	BlobPath    string
+	// YankedAt, YankReason and the deprecation messages let downloads warn
+	// about the version they serve.
+	YankedAt           *time.Time
+	YankReason         *string
+	VersionDeprecation *string
+	SkillDeprecation   *string
 }
 
 type ProxyCacheEntry struct {
@@ -60,10 +82,14 @@ type Skill struct {
 	Tags            json.RawMessage
 	Downloads       int64
 	Stars           int64
+	UpstreamStars   int64
+	TrendingScore   float64
 	InstallsCurrent int64
 	InstallsAllTime int64
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
+	// Deprecation is the skill's deprecation message, or nil.
+	Deprecation *string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
 
 type SkillVersion struct {
@@ -75,7 +101,13 @@ type SkillVersion struct {
 	Changelog       string
 	ChangelogSource *string
 	Files           json.RawMessage
-	CreatedAt       time.Time
+	// Manifest is the SKILL.md frontmatter as a JSON object, or nil.
+	Manifest json.RawMessage
+	// A yanked version is skipped by latest and range resolution.
+	YankedAt    *time.Time
+	YankReason  *string
+	Deprecation *string
+	CreatedAt   time.Time
 }
 
 type SkillListItem struct {
@@ -84,9 +116,10 @@ type SkillListItem struct {
 }
 
 type SkillVersionSummary struct {
-	Version   string
-	CreatedAt time.Time
-	Changelog string
+	Version     string
+	CreatedAt   time.Time
+	Changelog   string
+	Deprecation *string
 }
 
 type SkillSearchResult struct {
@@ -96,8 +129,27 @@ type SkillSearchResult struct {
 	Version     *string
 	Score       float64
 	UpdatedAt   *time.Time
+	// Highlighted fragments, with matches wrapped in HighlightStart and
+	// HighlightStop. ContentSnippet is an excerpt of SKILL.md.
+	DisplayNameHighlight string
+	SummaryHighlight     string
+	ContentSnippet       string
 }
 
+// Delimiters ts_headline puts around matched terms. Control characters never
+// appear in indexed text, so callers can escape the fragments safely and then
+// substitute their own markup.
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+const (
+	headlineFieldOptions   = "StartSel=" + HighlightStart + ", StopSel=" + HighlightStop + ", HighlightAll=true"
+	headlineContentOptions = "StartSel=" + HighlightStart + ", StopSel=" + HighlightStop +
+		", MaxWords=30, MinWords=12, ShortWord=2, MaxFragments=2, FragmentDelimiter=\" … \""
+)
+
 type Store struct {
 	db *pgxpool.Pool
 }
@@ -123,6 +175,19 @@ func (s *Store) GetRepositoryByName(ctx context.Context, name string) (Repositor
 	return repo, nil
 }
 
+func (s *Store) GetRepositoryByID(ctx context.Context, id uuid.UUID) (Repository, error) {
+	var repo Repository
+	err := s.db.QueryRow(ctx, `
+		SELECT id, name, type::text, upstream_url, enabled
+		FROM repositories
+		WHERE id = $1
+	`, id).Scan(&repo.ID, &repo.Name, &repo.Type, &repo.UpstreamURL, &repo.Enabled)
+	if err != nil {
+		return Repository{}, err
+	}
+	return repo, nil
+}
+
 func (s *Store) CreateRepository(ctx context.Context, name string, repoType string, upstreamURL *string) (Repository, error) {
 	var repo Repository
 	err := s.db.QueryRow(ctx, `
@@ -155,14 +220,50 @@ func (s *Store) EnsureRepository(ctx context.Context, name string, repoType stri
 
 func (s *Store) UpsertRepoMember(ctx context.Context, repoID uuid.UUID, subject string, role string) error {
 	_, err := s.db.Exec(ctx, `
-		INSERT INTO repo_members (repo_id, subject, role)
-		VALUES ($1, $2, $3::repo_role)
+		INSERT INTO repo_members (repo_id, subject, role, source)
+		VALUES ($1, $2, $3::repo_role, 'manual')
 		ON CONFLICT (repo_id, subject)
-		DO UPDATE SET role = EXCLUDED.role
+		DO UPDATE SET role = EXCLUDED.role, source = 'manual'
 	`, repoID, subject, role)
 	return err
 }
 
+// ReplaceSourceRepoMembers makes roles the complete set of memberships that
+// source grants to subject: memberships previously granted by source but not
+// in roles are removed. Manually assigned memberships are never touched.
+func (s *Store) ReplaceSourceRepoMembers(ctx context.Context, subject string, source string, roles map[uuid.UUID]string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	repoIDs := make([]uuid.UUID, 0, len(roles))
+	for repoID := range roles {
+		repoIDs = append(repoIDs, repoID)
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM repo_members
+		WHERE subject = $1 AND source = $2 AND NOT (repo_id = ANY($3))
+	`, subject, source, repoIDs); err != nil {
+		return err
+	}
+
+	for repoID, role := range roles {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO repo_members (repo_id, subject, role, source)
+			VALUES ($1, $2, $3::repo_role, $4)
+			ON CONFLICT (repo_id, subject)
+			DO UPDATE SET role = EXCLUDED.role
+			WHERE repo_members.source = EXCLUDED.source
+		`, repoID, subject, role, source); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
 func (s *Store) AddGroupMember(ctx context.Context, groupRepoID uuid.UUID, memberRepoID uuid.UUID, priority int) error {
 	_, err := s.db.Exec(ctx, `
 		INSERT INTO group_members (group_repo_id, member_repo_id, priority)
@@ -255,17 +356,127 @@ func (s *Store) ListAccessibleGroupMembers(ctx context.Context, groupRepoID uuid
 	return repos, rows.Err()
 }
 
+// GroupMember is a member repository of a group together with its
+// resolution priority (lower wins).
+type GroupMember struct {
+	GroupRepoID uuid.UUID
+	Repository  Repository
+	Priority    int
+}
+
+func (s *Store) ListGroupMemberships(ctx context.Context, groupRepoID uuid.UUID) ([]GroupMember, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT gm.group_repo_id, r.id, r.name, r.type::text, r.upstream_url, r.enabled, gm.priority
+		FROM group_members gm
+		JOIN repositories r ON r.id = gm.member_repo_id
+		WHERE gm.group_repo_id = $1
+		ORDER BY gm.priority ASC, r.name ASC
+	`, groupRepoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []GroupMember
+	for rows.Next() {
+		var m GroupMember
+		if err := rows.Scan(
+			&m.GroupRepoID, &m.Repository.ID, &m.Repository.Name, &m.Repository.Type,
+			&m.Repository.UpstreamURL, &m.Repository.Enabled, &m.Priority,
+		); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// LockGroupMembersTx serializes group membership changes so that cycle
+// checks see a stable graph until the transaction ends.
+func (s *Store) LockGroupMembersTx(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `LOCK TABLE group_members IN SHARE ROW EXCLUSIVE MODE`)
+	return err
+}
+
+// ListGroupEdgesTx returns every group → member edge, keyed by group id.
+func (s *Store) ListGroupEdgesTx(ctx context.Context, tx pgx.Tx) (map[uuid.UUID][]uuid.UUID, error) {
+	rows, err := tx.Query(ctx, `SELECT group_repo_id, member_repo_id FROM group_members`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edges := make(map[uuid.UUID][]uuid.UUID)
+	for rows.Next() {
+		var groupID, memberID uuid.UUID
+		if err := rows.Scan(&groupID, &memberID); err != nil {
+			return nil, err
+		}
+		edges[groupID] = append(edges[groupID], memberID)
+	}
+	return edges, rows.Err()
+}
+
+func (s *Store) AddGroupMemberTx(ctx context.Context, tx pgx.Tx, groupRepoID uuid.UUID, memberRepoID uuid.UUID, priority int) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO group_members (group_repo_id, member_repo_id, priority)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (group_repo_id, member_repo_id)
+		DO UPDATE SET priority = EXCLUDED.priority
+	`, groupRepoID, memberRepoID, priority)
+	return err
+}
+
+func (s *Store) RemoveGroupMember(ctx context.Context, groupRepoID uuid.UUID, memberRepoID uuid.UUID) error {
+	ct, err := s.db.Exec(ctx, `
+		DELETE FROM group_members
+		WHERE group_repo_id = $1 AND member_repo_id = $2
+	`, groupRepoID, memberRepoID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// SetGroupMemberPriorities updates the priority of existing members in one
+// transaction. It returns pgx.ErrNoRows if any id is not a member of the group.
+func (s *Store) SetGroupMemberPriorities(ctx context.Context, groupRepoID uuid.UUID, priorities map[uuid.UUID]int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for memberID, priority := range priorities {
+		ct, err := tx.Exec(ctx, `
+			UPDATE group_members SET priority = $3
+			WHERE group_repo_id = $1 AND member_repo_id = $2
+		`, groupRepoID, memberID, priority)
+		if err != nil {
+			return err
+		}
+		if ct.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+	}
+	return tx.Commit(ctx)
+}
+
 type RepoMember struct {
 	RepoID    uuid.UUID
 	RepoName  string
 	Subject   string
 	Role      string
+	Source    string
 	CreatedAt time.Time
 }
 
 func (s *Store) ListRepoMembers(ctx context.Context, repoID uuid.UUID) ([]RepoMember, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT rm.repo_id, r.name, rm.subject, rm.role::text, rm.created_at
+		SELECT rm.repo_id, r.name, rm.subject, rm.role::text, rm.source, rm.created_at
 		FROM repo_members rm
 		JOIN repositories r ON r.id = rm.repo_id
 		WHERE rm.repo_id = $1
@@ -279,7 +490,7 @@ func (s *Store) ListRepoMembers(ctx context.Context, repoID uuid.UUID) ([]RepoMe
 	var members []RepoMember
 	for rows.Next() {
 		var m RepoMember
-		if err := rows.Scan(&m.RepoID, &m.RepoName, &m.Subject, &m.Role, &m.CreatedAt); err != nil {
+		if err := rows.Scan(&m.RepoID, &m.RepoName, &m.Subject, &m.Role, &m.Source, &m.CreatedAt); err != nil {
 			return nil, err
 		}
 		members = append(members, m)
@@ -289,7 +500,7 @@ func (s *Store) ListRepoMembers(ctx context.Context, repoID uuid.UUID) ([]RepoMe
 
 func (s *Store) ListAllRepoMembers(ctx context.Context) ([]RepoMember, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT rm.repo_id, r.name, rm.subject, rm.role::text, rm.created_at
+		SELECT rm.repo_id, r.name, rm.subject, rm.role::text, rm.source, rm.created_at
 		FROM repo_members rm
 		JOIN repositories r ON r.id = rm.repo_id
 		ORDER BY r.name, rm.subject
@@ -302,7 +513,7 @@ func (s *Store) ListAllRepoMembers(ctx context.Context) ([]RepoMember, error) {
 	var members []RepoMember
 	for rows.Next() {
 		var m RepoMember
-		if err := rows.Scan(&m.RepoID, &m.RepoName, &m.Subject, &m.Role, &m.CreatedAt); err != nil {
+		if err := rows.Scan(&m.RepoID, &m.RepoName, &m.Subject, &m.Role, &m.Source, &m.CreatedAt); err != nil {
 			return nil, err
 		}
 		members = append(members, m)
@@ -399,18 +610,25 @@ func (s *Store) InsertVersionTx(
 	changelog string,
 	changelogSource *string,
 	files json.RawMessage,
+	manifest json.RawMessage,
 	createdBy string,
 ) (uuid.UUID, error) {
 	if len(files) == 0 {
 		files = json.RawMessage(`[]`)
 	}
+	var manifestArg any
+	if len(manifest) > 0 {
+		manifestArg = manifest
+	}
+
+	sortKey, prerelease, _ := semver.SortKey(version)
 
 	var versionID uuid.UUID
 	err := tx.QueryRow(ctx, `
-		INSERT INTO versions (package_id, version, digest, size_bytes, changelog, changelog_source, files, created_by)
-		VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb, $8)
+		INSERT INTO versions (package_id, version, digest, size_bytes, changelog, changelog_source, files, manifest, created_by, sort_key, prerelease)
+		VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb, $8::jsonb, $9, $10, $11)
 		RETURNING id
-	`, packageID, version, digest, sizeBytes, changelog, changelogSource, files, createdBy).Scan(&versionID)
+	`, packageID, version, digest, sizeBytes, changelog, changelogSource, files, manifestArg, createdBy, sortKey, prerelease).Scan(&versionID)
 	if err != nil {
 		if isUniqueViolation(err) {
 			return uuid.Nil, ErrConflict
@@ -488,7 +706,8 @@ func (s *Store) UpdateVersionMeta(
 func (s *Store) GetArtifact(ctx context.Context, repoID uuid.UUID, slug string, version string) (Artifact, error) {
 	var a Artifact
 	err := s.db.QueryRow(ctx, `
-		SELECT r.id, r.name, p.name, v.version, a.path, a.digest, a.size_bytes, a.blob_path
+		SELECT r.id, r.name, p.name, v.version, a.path, a.digest, a.size_bytes, a.blob_path,
+			v.yanked_at, v.yank_reason, v.deprecated, p.deprecated
 		FROM repositories r
 		JOIN packages p ON p.repo_id = r.id
 		JOIN versions v ON v.package_id = p.id
@@ -508,6 +727,10 @@ func (s *Store) GetArtifact(ctx context.Context, repoID uuid.UUID, slug string,
 		&a.Digest,
 		&a.SizeBytes,
 		&a.BlobPath,
+		&a.YankedAt,
+		&a.YankReason,
+		&a.VersionDeprecation,
+		&a.SkillDeprecation,
 	)
 	if err != nil {
 		return Artifact{}, err
@@ -518,7 +741,8 @@ func (s *Store) GetArtifact(ctx context.Context, repoID uuid.UUID, slug string,
 func (s *Store) GetLatestArtifact(ctx context.Context, repoID uuid.UUID, slug string) (Artifact, error) {
 	var a Artifact
 	err := s.db.QueryRow(ctx, `
-		SELECT r.id, r.name, p.name, v.version, a.path, a.digest, a.size_bytes, a.blob_path
+		SELECT r.id, r.name, p.name, v.version, a.path, a.digest, a.size_bytes, a.blob_path,
+			v.yanked_at, v.yank_reason, v.deprecated, p.deprecated
 		FROM repositories r
 		JOIN packages p ON p.repo_id = r.id
 		JOIN versions v ON v.package_id = p.id
@@ -526,7 +750,8 @@ func (s *Store) GetLatestArtifact(ctx context.Context, repoID uuid.UUID, slug st
 		WHERE r.id = $1
 		  AND p.name = $2
 		  AND p.deleted_at IS NULL
-		ORDER BY v.created_at DESC, a.created_at ASC
+		  AND v.yanked_at IS NULL
+		ORDER BY `+taggedLatestOrder+`, a.created_at ASC
 		LIMIT 1
 	`, repoID, slug).Scan(
 		&a.RepoID,
@@ -537,6 +762,10 @@ func (s *Store) GetLatestArtifact(ctx context.Context, repoID uuid.UUID, slug st
 		&a.Digest,
 		&a.SizeBytes,
 		&a.BlobPath,
+		&a.YankedAt,
+		&a.YankReason,
+		&a.VersionDeprecation,
+		&a.SkillDeprecation,
 	)
 	if err != nil {
 		return Artifact{}, err
@@ -589,39 +818,56 @@ func (s *Store) UpsertProxyCache(
 	return err
 }
 
-func (s *Store) SearchSkills(ctx context.Context, repoID uuid.UUID, query string, limit int) ([]SkillSearchResult, error) {
-	pattern := "%" + query + "%"
+// SearchSkills runs a full-text search over name, display name, summary and
+// SKILL.md. tsquery must be a valid to_tsquery('simple', ...) expression.
+// Scores are ts_rank normalized to [0, 1) plus 1 for an exact slug match, so
+// they are comparable across repositories.
+func (s *Store) SearchSkills(ctx context.Context, repoID uuid.UUID, tsquery string, exactSlug string, limit int) ([]SkillSearchResult, error) {
 	rows, err := s.db.Query(ctx, `
+		WITH ranked AS (
+			SELECT
+				p.id,
+				p.name,
+				p.display_name,
+				p.summary,
+				p.readme,
+				p.updated_at,
+				q.query,
+				CASE WHEN p.name = $3 THEN 1.0 ELSE 0.0 END
+					+ ts_rank(p.search_vector, q.query, 32) AS score
+			FROM packages p, to_tsquery('simple', $2) AS q(query)
+			WHERE p.repo_id = $1
+			  AND p.deleted_at IS NULL
+			  AND p.search_vector @@ q.query
+			ORDER BY score DESC, p.updated_at DESC, p.name ASC
+			LIMIT $4
+		)
 		SELECT
-			p.name,
-			p.display_name,
-			p.summary,
+			r.name,
+			r.display_name,
+			r.summary,
 			lv.version,
+			r.score,
+			r.updated_at,
+			ts_headline('simple', r.display_name, r.query, $5),
+			ts_headline('simple', COALESCE(r.summary, ''), r.query, $5),
 			CASE
-				WHEN lower(p.name) = lower($2) THEN 1.0
-				WHEN p.name ILIKE $3 THEN 0.9
-				WHEN p.display_name ILIKE $3 THEN 0.8
-				ELSE 0.6
-			END AS score,
-			p.updated_at
-		FROM packages p
+				WHEN to_tsvector('simple', COALESCE(r.readme, '')) @@ r.query
+				THEN ts_headline('simple', r.readme, r.query, $6)
+				ELSE ''
+			END
+		FROM ranked r
 		LEFT JOIN LATERAL (
 			SELECT v.version
 			FROM versions v
-			WHERE v.package_id = p.id
-			ORDER BY v.created_at DESC
+			JOIN packages p ON p.id = v.package_id
+			WHERE v.package_id = r.id
+			  AND v.yanked_at IS NULL
+			ORDER BY `+taggedLatestOrder+`
 			LIMIT 1
 		) lv ON true
-		WHERE p.repo_id = $1
-		  AND p.deleted_at IS NULL
-		  AND (
-			p.name ILIKE $4
-			OR p.display_name ILIKE $4
-			OR COALESCE(p.summary, '') ILIKE $4
-		  )
-		ORDER BY score DESC, p.updated_at DESC, p.name ASC
-		LIMIT $5
-	`, repoID, query, query+"%", pattern, limit)
+		ORDER BY r.score DESC, r.updated_at DESC, r.name ASC
+	`, repoID, tsquery, exactSlug, limit, headlineFieldOptions, headlineContentOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -630,7 +876,17 @@ func (s *Store) SearchSkills(ctx context.Context, repoID uuid.UUID, query string
 	var results []SkillSearchResult
 	for rows.Next() {
 		var r SkillSearchResult
-		if err := rows.Scan(&r.Slug, &r.DisplayName, &r.Summary, &r.Version, &r.Score, &r.UpdatedAt); err != nil {
+		if err := rows.Scan(
+			&r.Slug,
+			&r.DisplayName,
+			&r.Summary,
+			&r.Version,
+			&r.Score,
+			&r.UpdatedAt,
+			&r.DisplayNameHighlight,
+			&r.SummaryHighlight,
+			&r.ContentSnippet,
+		); err != nil {
 			return nil, err
 		}
 		results = append(results, r)
@@ -652,26 +908,31 @@ func (s *Store) ListSkills(ctx context.Context, repoID uuid.UUID, limit int, off
 			p.tags,
 			p.downloads,
 			p.stars,
+			p.upstream_stars,
+			p.trending_score,
 			p.installs_current,
 			p.installs_all_time,
+			p.deprecated,
 			p.created_at,
 			p.updated_at,
 			lv.version,
 			lv.created_at,
-			lv.changelog
+			lv.changelog,
+			lv.deprecated
 		FROM packages p
 		LEFT JOIN LATERAL (
-			SELECT v.version, v.created_at, v.changelog
+			SELECT v.version, v.created_at, v.changelog, v.deprecated
 			FROM versions v
 			WHERE v.package_id = p.id
-			ORDER BY v.created_at DESC
+			  AND v.yanked_at IS NULL
+			ORDER BY %s
 			LIMIT 1
 		) lv ON true
 		WHERE p.repo_id = $1
 		  AND p.deleted_at IS NULL
 		ORDER BY %s, p.name ASC
 		LIMIT $2 OFFSET $3
-	`, sortClause)
+	`, taggedLatestOrder, sortClause)
 
 	rows, err := s.db.Query(ctx, query, repoID, limit, offset)
 	if err != nil {
@@ -685,6 +946,7 @@ func (s *Store) ListSkills(ctx context.Context, repoID uuid.UUID, limit int, off
 		var latestVersion *string
 		var latestCreated *time.Time
 		var latestChangelog *string
+		var latestDeprecation *string
 
 		if err := rows.Scan(
 			&item.ID,
@@ -694,13 +956,17 @@ func (s *Store) ListSkills(ctx context.Context, repoID uuid.UUID, limit int, off
 			&item.Tags,
 			&item.Downloads,
 			&item.Stars,
+			&item.UpstreamStars,
+			&item.TrendingScore,
 			&item.InstallsCurrent,
 			&item.InstallsAllTime,
+			&item.Deprecation,
 			&item.CreatedAt,
 			&item.UpdatedAt,
 			&latestVersion,
 			&latestCreated,
 			&latestChangelog,
+			&latestDeprecation,
 		); err != nil {
 			return nil, err
 		}
@@ -710,9 +976,10 @@ func (s *Store) ListSkills(ctx context.Context, repoID uuid.UUID, limit int, off
 				changelog = *latestChangelog
 			}
 			item.LatestVersion = &SkillVersionSummary{
-				Version:   *latestVersion,
-				CreatedAt: *latestCreated,
-				Changelog: changelog,
+				Version:     *latestVersion,
+				CreatedAt:   *latestCreated,
+				Changelog:   changelog,
+				Deprecation: latestDeprecation,
 			}
 		}
 		items = append(items, item)
@@ -726,7 +993,7 @@ func (s *Store) ListSkills(ctx context.Context, repoID uuid.UUID, limit int, off
 func (s *Store) GetSkill(ctx context.Context, repoID uuid.UUID, slug string) (Skill, error) {
 	var skill Skill
 	err := s.db.QueryRow(ctx, `
-		SELECT id, name, display_name, summary, tags, downloads, stars, installs_current, installs_all_time, created_at, updated_at
+		SELECT id, name, display_name, summary, tags, downloads, stars, upstream_stars, trending_score, installs_current, installs_all_time, deprecated, created_at, updated_at
 		FROM packages
 		WHERE repo_id = $1
 		  AND name = $2
@@ -739,8 +1006,11 @@ func (s *Store) GetSkill(ctx context.Context, repoID uuid.UUID, slug string) (Sk
 		&skill.Tags,
 		&skill.Downloads,
 		&skill.Stars,
+		&skill.UpstreamStars,
+		&skill.TrendingScore,
 		&skill.InstallsCurrent,
 		&skill.InstallsAllTime,
+		&skill.Deprecation,
 		&skill.CreatedAt,
 		&skill.UpdatedAt,
 	)
@@ -753,10 +1023,12 @@ func (s *Store) GetSkill(ctx context.Context, repoID uuid.UUID, slug string) (Sk
 func (s *Store) GetLatestVersionForSkill(ctx context.Context, packageID uuid.UUID) (SkillVersion, error) {
 	var version SkillVersion
 	err := s.db.QueryRow(ctx, `
-		SELECT id, package_id, version, digest, size_bytes, changelog, changelog_source, files, created_at
-		FROM versions
-		WHERE package_id = $1
-		ORDER BY created_at DESC
+		SELECT v.id, v.package_id, v.version, v.digest, v.size_bytes, v.changelog, v.changelog_source, v.files, v.manifest, v.yanked_at, v.yank_reason, v.deprecated, v.created_at
+		FROM versions v
+		JOIN packages p ON p.id = v.package_id
+		WHERE v.package_id = $1
+		  AND v.yanked_at IS NULL
+		ORDER BY `+taggedLatestOrder+`
 		LIMIT 1
 	`, packageID).Scan(
 		&version.ID,
@@ -767,6 +1039,10 @@ func (s *Store) GetLatestVersionForSkill(ctx context.Context, packageID uuid.UUI
 		&version.Changelog,
 		&version.ChangelogSource,
 		&version.Files,
+		&version.Manifest,
+		&version.YankedAt,
+		&version.YankReason,
+		&version.Deprecation,
 		&version.CreatedAt,
 	)
 	if err != nil {
@@ -783,13 +1059,13 @@ func (s *Store) ListSkillVersions(
 	offset int,
 ) ([]SkillVersion, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT v.id, v.package_id, v.version, v.digest, v.size_bytes, v.changelog, v.changelog_source, v.files, v.created_at
+		SELECT v.id, v.package_id, v.version, v.digest, v.size_bytes, v.changelog, v.changelog_source, v.files, v.manifest, v.yanked_at, v.yank_reason, v.deprecated, v.created_at
 		FROM versions v
 		JOIN packages p ON p.id = v.package_id
 		WHERE p.repo_id = $1
 		  AND p.name = $2
 		  AND p.deleted_at IS NULL
-		ORDER BY v.created_at DESC
+		ORDER BY `+versionListOrder+`
 		LIMIT $3 OFFSET $4
 	`, repoID, slug, limit, offset)
 	if err != nil {
@@ -809,6 +1085,10 @@ func (s *Store) ListSkillVersions(
 			&version.Changelog,
 			&version.ChangelogSource,
 			&version.Files,
+			&version.Manifest,
+			&version.YankedAt,
+			&version.YankReason,
+			&version.Deprecation,
 			&version.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -821,6 +1101,134 @@ func (s *Store) ListSkillVersions(
 	return versions, nil
 }
 
+// ListVersionNames returns every version string of a skill in repoID that
+// range resolution may pick, leaving out yanked versions.
+func (s *Store) ListVersionNames(ctx context.Context, repoID uuid.UUID, slug string) ([]string, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT v.version
+		FROM versions v
+		JOIN packages p ON p.id = v.package_id
+		WHERE p.repo_id = $1
+		  AND p.name = $2
+		  AND p.deleted_at IS NULL
+		  AND v.yanked_at IS NULL
+	`, repoID, slug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// GetLatestVersionNameTx returns the version that the latest tag points at
+// for a package, as seen inside tx, or "" when the tag is not set.
+func (s *Store) GetLatestVersionNameTx(ctx context.Context, tx pgx.Tx, packageID uuid.UUID) (string, error) {
+	var version string
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(tags ->> 'latest', '')
+		FROM packages
+		WHERE id = $1
+	`, packageID).Scan(&version)
+	return version, err
+}
+
+// BackfillVersionSortKeys computes sort keys for up to limit versions that
+// were stored before sort keys existed, and returns how many it updated.
+func (s *Store) BackfillVersionSortKeys(ctx context.Context, limit int) (int, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, version FROM versions WHERE sort_key IS NULL LIMIT $1
+	`, limit)
+	if err != nil {
+		return 0, err
+	}
+	type pending struct {
+		id      uuid.UUID
+		version string
+	}
+	var batch []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.version); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range batch {
+		key, prerelease, _ := semver.SortKey(p.version)
+		if _, err := s.db.Exec(ctx, `
+			UPDATE versions SET sort_key = $2, prerelease = $3 WHERE id = $1
+		`, p.id, key, prerelease); err != nil {
+			return 0, err
+		}
+	}
+	return len(batch), nil
+}
+
+// PendingReadme is a package whose SKILL.md has not been extracted yet,
+// with the archive of its latest version (BlobPath is empty when the package
+// has no versions).
+type PendingReadme struct {
+	PackageID uuid.UUID
+	BlobPath  string
+}
+
+// ListPendingReadmes returns up to limit packages whose readme is still NULL.
+func (s *Store) ListPendingReadmes(ctx context.Context, limit int) ([]PendingReadme, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT p.id, COALESCE(la.blob_path, '')
+		FROM packages p
+		LEFT JOIN LATERAL (
+			SELECT a.blob_path
+			FROM versions v
+			JOIN assets a ON a.version_id = v.id
+			WHERE v.package_id = p.id
+			ORDER BY `+taggedLatestOrder+`, a.created_at ASC
+			LIMIT 1
+		) la ON true
+		WHERE p.readme IS NULL
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PendingReadme
+	for rows.Next() {
+		var p PendingReadme
+		if err := rows.Scan(&p.PackageID, &p.BlobPath); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) SetPackageReadme(ctx context.Context, packageID uuid.UUID, readme string) error {
+	_, err := s.db.Exec(ctx, `UPDATE packages SET readme = $2 WHERE id = $1`, packageID, readme)
+	return err
+}
+
+func (s *Store) SetPackageReadmeTx(ctx context.Context, tx pgx.Tx, packageID uuid.UUID, readme string) error {
+	_, err := tx.Exec(ctx, `UPDATE packages SET readme = $2 WHERE id = $1`, packageID, readme)
+	return err
+}
+
 func (s *Store) GetSkillVersion(ctx context.Context, repoID uuid.UUID, slug string, version string) (Skill, SkillVersion, error) {
 	var skill Skill
 	var sv SkillVersion
@@ -833,8 +1241,11 @@ func (s *Store) GetSkillVersion(ctx context.Context, repoID uuid.UUID, slug stri
 			p.tags,
 			p.downloads,
 			p.stars,
+			p.upstream_stars,
+			p.trending_score,
 			p.installs_current,
 			p.installs_all_time,
+			p.deprecated,
 			p.created_at,
 			p.updated_at,
 			v.id,
@@ -845,6 +1256,10 @@ func (s *Store) GetSkillVersion(ctx context.Context, repoID uuid.UUID, slug stri
 			v.changelog,
 			v.changelog_source,
 			v.files,
+			v.manifest,
+			v.yanked_at,
+			v.yank_reason,
+			v.deprecated,
 			v.created_at
 		FROM packages p
 		JOIN versions v ON v.package_id = p.id
@@ -861,8 +1276,11 @@ func (s *Store) GetSkillVersion(ctx context.Context, repoID uuid.UUID, slug stri
 		&skill.Tags,
 		&skill.Downloads,
 		&skill.Stars,
+		&skill.UpstreamStars,
+		&skill.TrendingScore,
 		&skill.InstallsCurrent,
 		&skill.InstallsAllTime,
+		&skill.Deprecation,
 		&skill.CreatedAt,
 		&skill.UpdatedAt,
 		&sv.ID,
@@ -873,6 +1291,10 @@ func (s *Store) GetSkillVersion(ctx context.Context, repoID uuid.UUID, slug stri
 		&sv.Changelog,
 		&sv.ChangelogSource,
 		&sv.Files,
+		&sv.Manifest,
+		&sv.YankedAt,
+		&sv.YankReason,
+		&sv.Deprecation,
 		&sv.CreatedAt,
 	)
 	if err != nil {
@@ -904,13 +1326,24 @@ func (s *Store) ResolveVersionByHash(ctx context.Context, repoID uuid.UUID, slug
 }
 
 func (s *Store) ResolveVersionByTag(ctx context.Context, repoID uuid.UUID, slug string, tag string) (*string, error) {
+	// Hosted tags only resolve to versions that exist; proxy tags may name
+	// upstream versions that are fetched on demand.
 	var version *string
 	err := s.db.QueryRow(ctx, `
-		SELECT tags ->> $3
-		FROM packages
-		WHERE repo_id = $1
-		  AND name = $2
-		  AND deleted_at IS NULL
+		SELECT p.tags ->> $3
+		FROM packages p
+		JOIN repositories r ON r.id = p.repo_id
+		WHERE p.repo_id = $1
+		  AND p.name = $2
+		  AND p.deleted_at IS NULL
+		  AND (
+			r.type = 'proxy'
+			OR EXISTS (
+				SELECT 1 FROM versions v
+				WHERE v.package_id = p.id
+				  AND v.version = p.tags ->> $3
+			)
+		  )
 	`, repoID, slug, tag).Scan(&version)
 	if err != nil {
 		if IsNotFound(err) {
@@ -921,22 +1354,28 @@ func (s *Store) ResolveVersionByTag(ctx context.Context, repoID uuid.UUID, slug
 	return version, nil
 }
 
+// IncrementSkillDownloads counts one download of a skill, both in the
+// package total and in today's download bucket. Installs are reported
+// separately through RecordSkillInstall. The trending score is bumped
+// by the weight of today's bucket so rankings react before the next
+// RecomputeTrendingScores run.
 func (s *Store) IncrementSkillDownloads(ctx context.Context, repoID uuid.UUID, slug string) error {
-	tagWeight := 1.0
-	cmdTag := "latest"
-	_, _ = tagWeight, cmdTag // placeholders for future weighting logic.
-
 	ct, err := s.db.Exec(ctx, `
-		UPDATE packages
-		SET
-			downloads = downloads + 1,
-			installs_current = installs_current + 1,
-			installs_all_time = installs_all_time + 1,
-			trending_score = trending_score + 1,
-			updated_at = updated_at
-		WHERE repo_id = $1
-		  AND name = $2
-		  AND deleted_at IS NULL
+		WITH pkg AS (
+			UPDATE packages
+			SET
+				downloads = downloads + 1,
+				trending_score = trending_score + 1,
+				updated_at = updated_at
+			WHERE repo_id = $1
+			  AND name = $2
+			  AND deleted_at IS NULL
+			RETURNING id
+		)
+		INSERT INTO skill_download_daily (package_id, day, downloads)
+		SELECT id, (now() AT TIME ZONE 'UTC')::date, 1 FROM pkg
+		ON CONFLICT (package_id, day)
+		DO UPDATE SET downloads = skill_download_daily.downloads + 1
 	`, repoID, slug)
 	if err != nil {
 		return err
@@ -977,72 +1416,104 @@ type APIToken struct {
 	Disabled   bool       `json:"disabled"`
 	CreatedAt  time.Time  `json:"created_at"`
 	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// Scopes is nil for an unrestricted token.
+	Scopes       []string   `json:"scopes"`
+	Repositories []string   `json:"repositories"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	// UserAgent and ClientIP identify the device of a session token.
+	UserAgent string `json:"user_agent,omitempty"`
+	ClientIP  string `json:"client_ip,omitempty"`
 }
 
 const (
 	TokenTypeSession  = "session"
 	TokenTypePersonal = "personal"
+	// TokenTypeBootstrap holds the hashed ADMIN_TOKEN until it is exchanged
+	// for a TokenTypeAdmin token.
+	TokenTypeBootstrap = "bootstrap"
+	TokenTypeAdmin     = "admin"
 )
 
-// CreatePersonalToken inserts a new personal access token.
-func (s *Store) CreatePersonalToken(ctx context.Context, subject, name, tokenHash string, isAdmin bool) (uuid.UUID, error) {
-	var id uuid.UUID
-	err := s.db.QueryRow(ctx, `
-		INSERT INTO api_tokens (token_hash, subject, name, token_type, is_admin)
-		VALUES ($1, $2, $3, 'personal', $4)
-		RETURNING id
-	`, tokenHash, subject, name, isAdmin).Scan(&id)
+// CreatePersonalToken inserts a new personal access token. Nil scopes
+// leave it unrestricted.
+func (s *Store) CreatePersonalToken(
+	ctx context.Context,
+	subject, name, tokenHash string,
+	isAdmin bool,
+	scopes, repositories []string,
+	expiresAt *time.Time,
+) (APIToken, error) {
+	if repositories == nil {
+		repositories = []string{}
+	}
+	t, err := scanAPIToken(s.db.QueryRow(ctx, `
+		INSERT INTO api_tokens (token_hash, subject, name, token_type, is_admin, scopes, repositories, expires_at)
+		VALUES ($1, $2, $3, 'personal', $4, $5, $6, $7)
+		RETURNING `+apiTokenColumns,
+		tokenHash, subject, name, isAdmin, scopes, repositories, expiresAt,
+	))
 	if err != nil {
 		if isUniqueViolation(err) {
-			return uuid.Nil, ErrConflict
+			return APIToken{}, ErrConflict
 		}
-		return uuid.Nil, err
+		return APIToken{}, err
 	}
-	return id, nil
+	return t, nil
 }
 
-// UpsertSessionToken creates or replaces the session token for a subject.
-// Each subject has at most one active session token.
-func (s *Store) UpsertSessionToken(ctx context.Context, subject, tokenHash string, isAdmin bool) error {
-	_, err := s.db.Exec(ctx, `
-		DELETE FROM api_tokens WHERE subject = $1 AND token_type = 'session'
-	`, subject)
-	if err != nil {
-		return err
-	}
-	_, err = s.db.Exec(ctx, `
-		INSERT INTO api_tokens (token_hash, subject, name, token_type, is_admin)
-		VALUES ($1, $2, '', 'session', $3)
-	`, tokenHash, subject, isAdmin)
-	return err
-}
+const apiTokenColumns = `id, subject, name, token_type, is_admin, disabled, created_at, last_used_at, scopes, repositories, expires_at,
+		user_agent, client_ip`
 
-// AuthenticateToken looks up a token by hash and returns its metadata.
-func (s *Store) AuthenticateToken(ctx context.Context, tokenHash string) (APIToken, error) {
+func scanAPIToken(row pgx.Row) (APIToken, error) {
 	var t APIToken
-	err := s.db.QueryRow(ctx, `
-		SELECT id, subject, name, token_type, is_admin, disabled, created_at, last_used_at
-		FROM api_tokens
-		WHERE token_hash = $1
-	`, tokenHash).Scan(&t.ID, &t.Subject, &t.Name, &t.TokenType, &t.IsAdmin, &t.Disabled, &t.CreatedAt, &t.LastUsedAt)
-	if err != nil {
-		return APIToken{}, err
-	}
-	return t, nil
+	err := row.Scan(
+		&t.ID, &t.Subject, &t.Name, &t.TokenType, &t.IsAdmin, &t.Disabled, &t.CreatedAt, &t.LastUsedAt,
+		&t.Scopes, &t.Repositories, &t.ExpiresAt, &t.UserAgent, &t.ClientIP,
+	)
+	return t, err
 }
 
-// TouchTokenLastUsed updates the last_used_at timestamp.
-func (s *Store) TouchTokenLastUsed(ctx context.Context, id uuid.UUID) {
-	_, _ = s.db.Exec(ctx, `UPDATE api_tokens SET last_used_at = now() WHERE id = $1`, id)
+// CreateSessionToken records a login session. A subject may hold any
+// number of sessions, one per device.
+func (s *Store) CreateSessionToken(
+	ctx context.Context,
+	subject, tokenHash string,
+	isAdmin bool,
+	userAgent, clientIP string,
+	expiresAt *time.Time,
+) (APIToken, error) {
+	return scanAPIToken(s.db.QueryRow(ctx, `
+		INSERT INTO api_tokens (token_hash, subject, name, token_type, is_admin, expires_at, user_agent, client_ip)
+		VALUES ($1, $2, '', 'session', $3, $4, $5, $6)
+		RETURNING `+apiTokenColumns,
+		tokenHash, subject, isAdmin, expiresAt, userAgent, clientIP,
+	))
+}
+
+// DeleteExpiredSessions removes the sessions of subject that passed their
+// absolute expiry or, when idleCutoff is set, were last active before it.
+func (s *Store) DeleteExpiredSessions(ctx context.Context, subject string, idleCutoff *time.Time) (int64, error) {
+	ct, err := s.db.Exec(ctx, `
+		DELETE FROM api_tokens
+		WHERE token_type = 'session'
+		  AND subject = $1
+		  AND (expires_at <= now()
+		       OR ($2::timestamptz IS NOT NULL AND COALESCE(last_used_at, created_at) <= $2))
+	`, subject, idleCutoff)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
 }
 
-// ListTokensBySubject returns all personal tokens for a given subject.
-func (s *Store) ListTokensBySubject(ctx context.Context, subject string) ([]APIToken, error) {
+// ListSessionsBySubject returns the session tokens of subject, most
+// recently used first.
+func (s *Store) ListSessionsBySubject(ctx context.Context, subject string) ([]APIToken, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT id, subject, name, token_type, is_admin, disabled, created_at, last_used_at
+		SELECT `+apiTokenColumns+`
 		FROM api_tokens
-		WHERE subject = $1 AND token_type = 'personal'
-		ORDER BY created_at DESC
+		WHERE subject = $1 AND token_type = 'session'
+		ORDER BY COALESCE(last_used_at, created_at) DESC
 	`, subject)
 	if err != nil {
 		return nil, err
@@ -1050,8 +1521,8 @@ func (s *Store) ListTokensBySubject(ctx context.Context, subject string) ([]APIT
 	defer rows.Close()
 	var tokens []APIToken
 	for rows.Next() {
-		var t APIToken
-		if err := rows.Scan(&t.ID, &t.Subject, &t.Name, &t.TokenType, &t.IsAdmin, &t.Disabled, &t.CreatedAt, &t.LastUsedAt); err != nil {
+		t, err := scanAPIToken(rows)
+		if err != nil {
 			return nil, err
 		}
 		tokens = append(tokens, t)
@@ -1059,15 +1530,13 @@ func (s *Store) ListTokensBySubject(ctx context.Context, subject string) ([]APIT
 	return tokens, rows.Err()
 }
 
-// RevokeToken deletes a token by id, scoped to a subject (unless allAccess).
-func (s *Store) RevokeToken(ctx context.Context, tokenID uuid.UUID, subject string, allAccess bool) error {
-	var ct pgconn.CommandTag
-	var err error
-	if allAccess {
-		ct, err = s.db.Exec(ctx, `DELETE FROM api_tokens WHERE id = $1`, tokenID)
-	} else {
-		ct, err = s.db.Exec(ctx, `DELETE FROM api_tokens WHERE id = $1 AND subject = $2`, tokenID, subject)
-	}
+// RevokeSession deletes one session of subject. It reports pgx.ErrNoRows
+// when there is no such session.
+func (s *Store) RevokeSession(ctx context.Context, sessionID uuid.UUID, subject string) error {
+	ct, err := s.db.Exec(ctx, `
+		DELETE FROM api_tokens
+		WHERE id = $1 AND subject = $2 AND token_type = 'session'
+	`, sessionID, subject)
 	if err != nil {
 		return err
 	}
@@ -1077,18 +1546,136 @@ func (s *Store) RevokeToken(ctx context.Context, tokenID uuid.UUID, subject stri
 	return nil
 }
 
-func skillSortClause(sort string) string {
-	switch sort {
-	case "downloads":
-		return "p.downloads DESC, p.updated_at DESC"
-	case "stars":
-		return "p.stars DESC, p.updated_at DESC"
-	case "installsCurrent":
-		return "p.installs_current DESC, p.updated_at DESC"
-	case "installsAllTime":
+// RevokeOtherSessions deletes every session of subject except keepID.
+func (s *Store) RevokeOtherSessions(ctx context.Context, subject string, keepID uuid.UUID) (int64, error) {
+	ct, err := s.db.Exec(ctx, `
+		DELETE FROM api_tokens
+		WHERE subject = $1 AND token_type = 'session' AND id <> $2
+	`, subject, keepID)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// ReplaceBootstrapToken stores tokenHash as the only bootstrap token, valid
+// until expiresAt.
+func (s *Store) ReplaceBootstrapToken(ctx context.Context, subject, tokenHash string, expiresAt time.Time) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM api_tokens WHERE token_type = $1`, TokenTypeBootstrap); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO api_tokens (token_hash, subject, name, token_type, is_admin, expires_at)
+		VALUES ($1, $2, $3, $3, TRUE, $4)
+	`, tokenHash, subject, TokenTypeBootstrap, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RotateAdminToken deletes the bootstrap and admin tokens and stores
+// tokenHash as the new admin token.
+func (s *Store) RotateAdminToken(ctx context.Context, subject, tokenHash string) (APIToken, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return APIToken{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM api_tokens WHERE token_type IN ($1, $2)
+	`, TokenTypeBootstrap, TokenTypeAdmin); err != nil {
+		return APIToken{}, err
+	}
+	t, err := scanAPIToken(tx.QueryRow(ctx, `
+		INSERT INTO api_tokens (token_hash, subject, name, token_type, is_admin)
+		VALUES ($1, $2, $3, $3, TRUE)
+		RETURNING `+apiTokenColumns,
+		tokenHash, subject, TokenTypeAdmin,
+	))
+	if err != nil {
+		return APIToken{}, err
+	}
+	return t, tx.Commit(ctx)
+}
+
+// AuthenticateToken looks up a token by hash and returns its metadata.
+func (s *Store) AuthenticateToken(ctx context.Context, tokenHash string) (APIToken, error) {
+	t, err := scanAPIToken(s.db.QueryRow(ctx, `
+		SELECT `+apiTokenColumns+`
+		FROM api_tokens
+		WHERE token_hash = $1
+	`, tokenHash))
+	if err != nil {
+		return APIToken{}, err
+	}
+	return t, nil
+}
+
+// TouchTokenLastUsed updates the last_used_at timestamp.
+func (s *Store) TouchTokenLastUsed(ctx context.Context, id uuid.UUID) {
+	_, _ = s.db.Exec(ctx, `UPDATE api_tokens SET last_used_at = now() WHERE id = $1`, id)
+}
+
+// ListTokensBySubject returns all personal tokens for a given subject.
+func (s *Store) ListTokensBySubject(ctx context.Context, subject string) ([]APIToken, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+apiTokenColumns+`
+		FROM api_tokens
+		WHERE subject = $1 AND token_type = 'personal'
+		ORDER BY created_at DESC
+	`, subject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tokens []APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeToken deletes a token by id, scoped to a subject (unless allAccess).
+func (s *Store) RevokeToken(ctx context.Context, tokenID uuid.UUID, subject string, allAccess bool) error {
+	var ct pgconn.CommandTag
+	var err error
+	if allAccess {
+		ct, err = s.db.Exec(ctx, `DELETE FROM api_tokens WHERE id = $1`, tokenID)
+	} else {
+		ct, err = s.db.Exec(ctx, `DELETE FROM api_tokens WHERE id = $1 AND subject = $2`, tokenID, subject)
+	}
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func skillSortClause(sort string) string {
+	switch sort {
+	case "downloads":
+		return "p.downloads DESC, p.updated_at DESC"
+	case "stars":
+		return "p.stars + p.upstream_stars DESC, p.updated_at DESC"
+	case "installsCurrent":
+		return "p.installs_current DESC, p.updated_at DESC"
+	case "installsAllTime":
 		return "p.installs_all_time DESC, p.updated_at DESC"
 	case "trending":
-		return "p.trending_score DESC, p.updated_at DESC"
+		return "p.trending_score DESC, p.downloads DESC, p.updated_at DESC"
 	default:
 		return "p.updated_at DESC"
 	}
@@ -1226,6 +1813,19 @@ func (s *Store) UpdateRepositoryEnabled(ctx context.Context, id uuid.UUID, enabl
 	return nil
 }
 
+// UpdateRepositoryUpstream sets the upstream URL of a repository. The name
+// and type of a repository cannot change.
+func (s *Store) UpdateRepositoryUpstream(ctx context.Context, id uuid.UUID, upstreamURL *string) (Repository, error) {
+	var repo Repository
+	err := s.db.QueryRow(ctx, `
+		UPDATE repositories
+		SET upstream_url = $2, updated_at = now()
+		WHERE id = $1
+		RETURNING id, name, type::text, upstream_url, enabled
+	`, id, upstreamURL).Scan(&repo.ID, &repo.Name, &repo.Type, &repo.UpstreamURL, &repo.Enabled)
+	return repo, err
+}
+
 func ValidateRepoType(repoType string) error {
 	switch repoType {
 	case RepoTypeHosted, RepoTypeProxy, RepoTypeGroup:
@@ -1254,6 +1854,7 @@ type User struct {
 	Email        string    `json:"email"`
 	IsAdmin      bool      `json:"is_admin"`
 	Disabled     bool      `json:"disabled"`
+	TOTPEnabled  bool      `json:"totp_enabled"`
 	CreatedAt    time.Time `json:"created_at"`
 }
 
@@ -1262,9 +1863,9 @@ func (s *Store) CreateUser(ctx context.Context, username, passwordHash, displayN
 	err := s.db.QueryRow(ctx, `
 		INSERT INTO users (username, password_hash, display_name, email, is_admin)
 		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, username, password_hash, display_name, email, is_admin, disabled, created_at
+		RETURNING id, username, password_hash, display_name, email, is_admin, disabled, totp_enabled, created_at
 	`, username, passwordHash, displayName, email, isAdmin).Scan(
-		&u.ID, &u.Username, &u.PasswordHash, &u.DisplayName, &u.Email, &u.IsAdmin, &u.Disabled, &u.CreatedAt,
+		&u.ID, &u.Username, &u.PasswordHash, &u.DisplayName, &u.Email, &u.IsAdmin, &u.Disabled, &u.TOTPEnabled, &u.CreatedAt,
 	)
 	if err != nil {
 		if isUniqueViolation(err) {
@@ -1278,10 +1879,10 @@ func (s *Store) CreateUser(ctx context.Context, username, passwordHash, displayN
 func (s *Store) GetUserByUsername(ctx context.Context, username string) (User, error) {
 	var u User
 	err := s.db.QueryRow(ctx, `
-		SELECT id, username, password_hash, display_name, email, is_admin, disabled, created_at
+		SELECT id, username, password_hash, display_name, email, is_admin, disabled, totp_enabled, created_at
 		FROM users WHERE username = $1
 	`, username).Scan(
-		&u.ID, &u.Username, &u.PasswordHash, &u.DisplayName, &u.Email, &u.IsAdmin, &u.Disabled, &u.CreatedAt,
+		&u.ID, &u.Username, &u.PasswordHash, &u.DisplayName, &u.Email, &u.IsAdmin, &u.Disabled, &u.TOTPEnabled, &u.CreatedAt,
 	)
 	if err != nil {
 		return User{}, err
@@ -1291,7 +1892,7 @@ func (s *Store) GetUserByUsername(ctx context.Context, username string) (User, e
 
 func (s *Store) ListUsers(ctx context.Context) ([]User, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT id, username, password_hash, display_name, email, is_admin, disabled, created_at
+		SELECT id, username, password_hash, display_name, email, is_admin, disabled, totp_enabled, created_at
 		FROM users ORDER BY created_at
 	`)
 	if err != nil {
@@ -1301,7 +1902,7 @@ func (s *Store) ListUsers(ctx context.Context) ([]User, error) {
 	var users []User
 	for rows.Next() {
 		var u User
-		if err := rows.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.DisplayName, &u.Email, &u.IsAdmin, &u.Disabled, &u.CreatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.DisplayName, &u.Email, &u.IsAdmin, &u.Disabled, &u.TOTPEnabled, &u.CreatedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, u)
@@ -1312,10 +1913,10 @@ func (s *Store) ListUsers(ctx context.Context) ([]User, error) {
 func (s *Store) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 	var u User
 	err := s.db.QueryRow(ctx, `
-		SELECT id, username, password_hash, display_name, email, is_admin, disabled, created_at
+		SELECT id, username, password_hash, display_name, email, is_admin, disabled, totp_enabled, created_at
 		FROM users WHERE id = $1
 	`, id).Scan(
-		&u.ID, &u.Username, &u.PasswordHash, &u.DisplayName, &u.Email, &u.IsAdmin, &u.Disabled, &u.CreatedAt,
+		&u.ID, &u.Username, &u.PasswordHash, &u.DisplayName, &u.Email, &u.IsAdmin, &u.Disabled, &u.TOTPEnabled, &u.CreatedAt,
 	)
 	if err != nil {
 		return User{}, err
@@ -1328,9 +1929,9 @@ func (s *Store) UpdateUser(ctx context.Context, id uuid.UUID, displayName, email
 	err := s.db.QueryRow(ctx, `
 		UPDATE users SET display_name = $2, email = $3, is_admin = $4, disabled = $5
 		WHERE id = $1
-		RETURNING id, username, password_hash, display_name, email, is_admin, disabled, created_at
+		RETURNING id, username, password_hash, display_name, email, is_admin, disabled, totp_enabled, created_at
 	`, id, displayName, email, isAdmin, disabled).Scan(
-		&u.ID, &u.Username, &u.PasswordHash, &u.DisplayName, &u.Email, &u.IsAdmin, &u.Disabled, &u.CreatedAt,
+		&u.ID, &u.Username, &u.PasswordHash, &u.DisplayName, &u.Email, &u.IsAdmin, &u.Disabled, &u.TOTPEnabled, &u.CreatedAt,
 	)
 	if err != nil {
 		return User{}, err
@@ -1360,72 +1961,1430 @@ func (s *Store) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-// ---- Auth Config (LDAP) ----
+// ---- Two-Factor Authentication ----
 
-type AuthConfig struct {
-	ProviderType string          `json:"provider_type"`
-	Enabled      bool            `json:"enabled"`
-	Config       json.RawMessage `json:"config"`
-	UpdatedAt    time.Time       `json:"updated_at"`
+// UserTOTP is the TOTP state of a local user. Secret is set from the start
+// of enrollment; it only guards logins once Enabled.
+type UserTOTP struct {
+	Secret   string
+	Enabled  bool
+	LastStep int64
 }
 
-func (s *Store) GetAuthConfig(ctx context.Context, providerType string) (AuthConfig, error) {
-	var ac AuthConfig
-	err := s.db.QueryRow(ctx,
-		`SELECT provider_type, enabled, config, updated_at FROM auth_configs WHERE provider_type = $1`,
-		providerType,
-	).Scan(&ac.ProviderType, &ac.Enabled, &ac.Config, &ac.UpdatedAt)
-	return ac, err
+func (s *Store) GetUserTOTP(ctx context.Context, userID uuid.UUID) (UserTOTP, error) {
+	var t UserTOTP
+	var secret *string
+	err := s.db.QueryRow(ctx, `
+		SELECT totp_secret, totp_enabled, totp_last_step FROM users WHERE id = $1
+	`, userID).Scan(&secret, &t.Enabled, &t.LastStep)
+	if err != nil {
+		return UserTOTP{}, err
+	}
+	if secret != nil {
+		t.Secret = *secret
+	}
+	return t, nil
 }
 
-func (s *Store) ListAuthConfigs(ctx context.Context) ([]AuthConfig, error) {
-	rows, err := s.db.Query(ctx, `SELECT provider_type, enabled, config, updated_at FROM auth_configs ORDER BY provider_type`)
+// SetPendingTOTPSecret stores the secret of an enrollment in progress. It
+// returns ErrConflict when 2FA is already enabled.
+func (s *Store) SetPendingTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	ct, err := s.db.Exec(ctx, `
+		UPDATE users SET totp_secret = $2, totp_last_step = 0
+		WHERE id = $1 AND NOT totp_enabled
+	`, userID, secret)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
-	var configs []AuthConfig
-	for rows.Next() {
-		var ac AuthConfig
-		if err := rows.Scan(&ac.ProviderType, &ac.Enabled, &ac.Config, &ac.UpdatedAt); err != nil {
-			return nil, err
+	if ct.RowsAffected() == 0 {
+		if _, err := s.GetUserByID(ctx, userID); err != nil {
+			return err
 		}
-		configs = append(configs, ac)
+		return ErrConflict
 	}
-	return configs, rows.Err()
+	return nil
 }
 
-func (s *Store) UpsertAuthConfig(ctx context.Context, providerType string, enabled bool, config json.RawMessage) error {
+// EnableTOTP turns on 2FA with the pending secret, records step as used
+// and replaces the recovery codes, in one transaction.
+func (s *Store) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `
+		UPDATE users SET totp_enabled = TRUE, totp_last_step = $2
+		WHERE id = $1 AND totp_secret IS NOT NULL AND NOT totp_enabled
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrConflict
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DisableTOTP turns off 2FA and deletes the secret and recovery codes.
+func (s *Store) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `
+		UPDATE users SET totp_enabled = FALSE, totp_secret = NULL, totp_last_step = 0
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// AdvanceTOTPStep records step as the last used time step. It reports
+// false when step is not newer, i.e. the code was already used.
+func (s *Store) AdvanceTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	ct, err := s.db.Exec(ctx, `
+		UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// ReplaceRecoveryCodes discards all recovery codes of a user and stores
+// new ones.
+func (s *Store) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+		`, userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It reports false
+// when there is no such unused code.
+func (s *Store) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	ct, err := s.db.Exec(ctx, `
+		UPDATE user_recovery_codes SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// CountRecoveryCodes returns the number of unused recovery codes.
+func (s *Store) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var n int
+	err := s.db.QueryRow(ctx, `
+		SELECT count(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&n)
+	return n, err
+}
+
+// PendingLoginChallenge is a local login that passed the password step
+// and waits for a second factor, or for 2FA enrollment when Setup is set.
+type PendingLoginChallenge struct {
+	TokenHash string
+	UserID    uuid.UUID
+	Username  string
+	Setup     bool
+	Attempts  int
+	ExpiresAt time.Time
+}
+
+func (s *Store) CreateLoginChallenge(ctx context.Context, ch PendingLoginChallenge) error {
 	_, err := s.db.Exec(ctx, `
-		INSERT INTO auth_configs (provider_type, enabled, config, updated_at)
-		VALUES ($1, $2, $3, now())
-		ON CONFLICT (provider_type) DO UPDATE
-		SET enabled = EXCLUDED.enabled, config = EXCLUDED.config, updated_at = now()
-	`, providerType, enabled, config)
+		INSERT INTO login_challenges (token_hash, user_id, username, setup, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, ch.TokenHash, ch.UserID, ch.Username, ch.Setup, ch.ExpiresAt)
 	return err
 }
 
-func (s *Store) DeleteAuthConfig(ctx context.Context, providerType string) error {
-	_, err := s.db.Exec(ctx, `DELETE FROM auth_configs WHERE provider_type = $1`, providerType)
+// GetLoginChallenge returns an unexpired challenge.
+func (s *Store) GetLoginChallenge(ctx context.Context, tokenHash string) (PendingLoginChallenge, error) {
+	var ch PendingLoginChallenge
+	err := s.db.QueryRow(ctx, `
+		SELECT token_hash, user_id, username, setup, attempts, expires_at
+		FROM login_challenges
+		WHERE token_hash = $1 AND expires_at > now()
+	`, tokenHash).Scan(&ch.TokenHash, &ch.UserID, &ch.Username, &ch.Setup, &ch.Attempts, &ch.ExpiresAt)
+	return ch, err
+}
+
+// FailLoginChallenge counts a wrong code and deletes the challenge once it
+// reaches maxAttempts.
+func (s *Store) FailLoginChallenge(ctx context.Context, tokenHash string, maxAttempts int) error {
+	ct, err := s.db.Exec(ctx, `
+		DELETE FROM login_challenges WHERE token_hash = $1 AND attempts + 1 >= $2
+	`, tokenHash, maxAttempts)
+	if err != nil || ct.RowsAffected() > 0 {
+		return err
+	}
+	_, err = s.db.Exec(ctx, `
+		UPDATE login_challenges SET attempts = attempts + 1 WHERE token_hash = $1
+	`, tokenHash)
 	return err
 }
 
-// ---- System Config (generic key-value) ----
+func (s *Store) DeleteLoginChallenge(ctx context.Context, tokenHash string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM login_challenges WHERE token_hash = $1`, tokenHash)
+	return err
+}
 
-func (s *Store) GetSystemConfig(ctx context.Context, key string) (json.RawMessage, error) {
-	var raw json.RawMessage
-	err := s.db.QueryRow(ctx,
-		`SELECT config FROM system_configs WHERE config_key = $1`, key,
-	).Scan(&raw)
-	return raw, err
+// PendingOIDCLogin is an OIDC login that was sent to the identity provider,
+// keyed by the hash of its OAuth state.
+type PendingOIDCLogin struct {
+	StateHash string
+	Verifier  string
+	Nonce     string
+	ReturnTo  string
+	ExpiresAt time.Time
 }
 
-func (s *Store) UpsertSystemConfig(ctx context.Context, key string, config json.RawMessage) error {
+func (s *Store) CreateOIDCLogin(ctx context.Context, l PendingOIDCLogin) error {
 	_, err := s.db.Exec(ctx, `
-		INSERT INTO system_configs (config_key, config, updated_at)
-		VALUES ($1, $2, now())
-		ON CONFLICT (config_key) DO UPDATE
-		SET config = EXCLUDED.config, updated_at = now()
-	`, key, config)
+		INSERT INTO oidc_logins (state_hash, verifier, nonce, return_to, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, l.StateHash, l.Verifier, l.Nonce, l.ReturnTo, l.ExpiresAt)
+	return err
+}
+
+// TakeOIDCLogin deletes and returns an unexpired login, so each state can
+// be used once.
+func (s *Store) TakeOIDCLogin(ctx context.Context, stateHash string) (PendingOIDCLogin, error) {
+	var l PendingOIDCLogin
+	err := s.db.QueryRow(ctx, `
+		DELETE FROM oidc_logins
+		WHERE state_hash = $1 AND expires_at > now()
+		RETURNING state_hash, verifier, nonce, return_to, expires_at
+	`, stateHash).Scan(&l.StateHash, &l.Verifier, &l.Nonce, &l.ReturnTo, &l.ExpiresAt)
+	return l, err
+}
+
+// ---- Login Throttling ----
+
+// Kinds of login throttles.
+const (
+	LoginThrottleUser = "user"
+	LoginThrottleIP   = "ip"
+)
+
+// LoginThrottle counts recent failed logins for a username or client IP.
+type LoginThrottle struct {
+	Kind         string     `json:"kind"`
+	Key          string     `json:"key"`
+	Failures     int        `json:"failures"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+	Locked       bool       `json:"locked"`
+}
+
+const loginThrottleColumns = `kind, key, failures, last_failed_at, blocked_until, locked`
+
+func scanLoginThrottle(row pgx.Row) (LoginThrottle, error) {
+	var t LoginThrottle
+	err := row.Scan(&t.Kind, &t.Key, &t.Failures, &t.LastFailedAt, &t.BlockedUntil, &t.Locked)
+	return t, err
+}
+
+// LockLoginThrottleTx returns the counter of kind/key, creating an empty
+// one, and locks it until tx ends.
+func (s *Store) LockLoginThrottleTx(ctx context.Context, tx pgx.Tx, kind, key string) (LoginThrottle, error) {
+	return scanLoginThrottle(tx.QueryRow(ctx, `
+		INSERT INTO login_throttles (kind, key, failures, last_failed_at)
+		VALUES ($1, $2, 0, now())
+		ON CONFLICT (kind, key) DO UPDATE SET kind = EXCLUDED.kind
+		RETURNING `+loginThrottleColumns,
+		kind, key,
+	))
+}
+
+// UpdateLoginThrottleTx stores the failures and block of a counter locked
+// by LockLoginThrottleTx.
+func (s *Store) UpdateLoginThrottleTx(ctx context.Context, tx pgx.Tx, t LoginThrottle) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE login_throttles
+		SET failures = $3, last_failed_at = $4, blocked_until = $5, locked = $6
+		WHERE kind = $1 AND key = $2
+	`, t.Kind, t.Key, t.Failures, t.LastFailedAt, t.BlockedUntil, t.Locked)
+	return err
+}
+
+// ReleaseLoginFailure takes back one failure of kind/key. blockedUntil is
+// the block that failure set, which is lifted unless another failure has
+// replaced it since.
+func (s *Store) ReleaseLoginFailure(ctx context.Context, kind, key string, blockedUntil *time.Time) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE login_throttles SET
+			failures = GREATEST(failures - 1, 0),
+			locked = CASE WHEN blocked_until = $3 THEN FALSE ELSE locked END,
+			blocked_until = CASE WHEN blocked_until = $3 THEN NULL ELSE blocked_until END
+		WHERE kind = $1 AND key = $2
+	`, kind, key, blockedUntil)
 	return err
 }
+
+// ClearLoginThrottle forgets the failures of kind/key and lifts any block.
+// It reports whether there was anything to clear.
+func (s *Store) ClearLoginThrottle(ctx context.Context, kind, key string) (bool, error) {
+	ct, err := s.db.Exec(ctx, `DELETE FROM login_throttles WHERE kind = $1 AND key = $2`, kind, key)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// ListLoginLockouts returns the lockouts still in effect, newest first.
+func (s *Store) ListLoginLockouts(ctx context.Context) ([]LoginThrottle, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+loginThrottleColumns+` FROM login_throttles
+		WHERE locked AND blocked_until > now()
+		ORDER BY last_failed_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []LoginThrottle
+	for rows.Next() {
+		t, err := scanLoginThrottle(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// Outcomes of login attempts.
+const (
+	LoginOutcomeSuccess = "success"
+	// LoginOutcomeSecondFactor is a correct password on an account that
+	// continues with a second factor.
+	LoginOutcomeSecondFactor = "second_factor"
+	LoginOutcomeFailed       = "failed"
+	LoginOutcomeBlocked      = "blocked"
+)
+
+// LoginAttempt is one recorded local or LDAP login.
+type LoginAttempt struct {
+	ID        uuid.UUID `json:"id"`
+	Provider  string    `json:"provider"`
+	Username  string    `json:"username"`
+	ClientIP  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *Store) InsertLoginAttempt(ctx context.Context, a LoginAttempt) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO login_attempts (provider, username, client_ip, user_agent, outcome, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, a.Provider, a.Username, a.ClientIP, a.UserAgent, a.Outcome, a.Reason)
+	return err
+}
+
+// LoginAttemptFilter narrows ListLoginAttempts; empty fields match all.
+type LoginAttemptFilter struct {
+	Username string
+	ClientIP string
+	Outcome  string
+}
+
+// ListLoginAttempts returns recorded login attempts, newest first.
+func (s *Store) ListLoginAttempts(ctx context.Context, filter LoginAttemptFilter, limit int, offset int) ([]LoginAttempt, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, provider, username, client_ip, user_agent, outcome, reason, created_at
+		FROM login_attempts
+		WHERE ($1 = '' OR lower(username) = lower($1))
+			AND ($2 = '' OR client_ip = $2)
+			AND ($3 = '' OR outcome = $3)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4 OFFSET $5
+	`, filter.Username, filter.ClientIP, filter.Outcome, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []LoginAttempt
+	for rows.Next() {
+		var a LoginAttempt
+		if err := rows.Scan(&a.ID, &a.Provider, &a.Username, &a.ClientIP, &a.UserAgent, &a.Outcome, &a.Reason, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// PruneLoginRecords deletes login attempts older than before, counters
+// whose last failure is older than before and that no longer block, and
+// expired login challenges and OIDC logins.
+func (s *Store) PruneLoginRecords(ctx context.Context, before time.Time) (int64, error) {
+	ct, err := s.db.Exec(ctx, `DELETE FROM login_attempts WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.db.Exec(ctx, `
+		DELETE FROM login_throttles
+		WHERE last_failed_at < $1 AND (blocked_until IS NULL OR blocked_until <= now())
+	`, before); err != nil {
+		return 0, err
+	}
+	if _, err := s.db.Exec(ctx, `DELETE FROM login_challenges WHERE expires_at <= now()`); err != nil {
+		return 0, err
+	}
+	if _, err := s.db.Exec(ctx, `DELETE FROM oidc_logins WHERE expires_at <= now()`); err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// ---- Auth Config (LDAP) ----
+
+type AuthConfig struct {
+	ProviderType string          `json:"provider_type"`
+	Enabled      bool            `json:"enabled"`
+	Config       json.RawMessage `json:"config"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+func (s *Store) GetAuthConfig(ctx context.Context, providerType string) (AuthConfig, error) {
+	var ac AuthConfig
+	err := s.db.QueryRow(ctx,
+		`SELECT provider_type, enabled, config, updated_at FROM auth_configs WHERE provider_type = $1`,
+		providerType,
+	).Scan(&ac.ProviderType, &ac.Enabled, &ac.Config, &ac.UpdatedAt)
+	return ac, err
+}
+
+func (s *Store) ListAuthConfigs(ctx context.Context) ([]AuthConfig, error) {
+	rows, err := s.db.Query(ctx, `SELECT provider_type, enabled, config, updated_at FROM auth_configs ORDER BY provider_type`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var configs []AuthConfig
+	for rows.Next() {
+		var ac AuthConfig
+		if err := rows.Scan(&ac.ProviderType, &ac.Enabled, &ac.Config, &ac.UpdatedAt); err != nil {
+			return nil, err
+		}
+		configs = append(configs, ac)
+	}
+	return configs, rows.Err()
+}
+
+func (s *Store) UpsertAuthConfig(ctx context.Context, providerType string, enabled bool, config json.RawMessage) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO auth_configs (provider_type, enabled, config, updated_at)
+		VALUES ($1, $2, $3, now())
+		ON CONFLICT (provider_type) DO UPDATE
+		SET enabled = EXCLUDED.enabled, config = EXCLUDED.config, updated_at = now()
+	`, providerType, enabled, config)
+	return err
+}
+
+func (s *Store) DeleteAuthConfig(ctx context.Context, providerType string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM auth_configs WHERE provider_type = $1`, providerType)
+	return err
+}
+
+// ---- System Config (generic key-value) ----
+
+func (s *Store) GetSystemConfig(ctx context.Context, key string) (json.RawMessage, error) {
+	var raw json.RawMessage
+	err := s.db.QueryRow(ctx,
+		`SELECT config FROM system_configs WHERE config_key = $1`, key,
+	).Scan(&raw)
+	return raw, err
+}
+
+func (s *Store) UpsertSystemConfig(ctx context.Context, key string, config json.RawMessage) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO system_configs (config_key, config, updated_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (config_key) DO UPDATE
+		SET config = EXCLUDED.config, updated_at = now()
+	`, key, config)
+	return err
+}
+
+// SetSystemConfigEntry sets one field of the JSON object stored under key
+// in a single statement, so concurrent writers of other fields are not
+// lost.
+func (s *Store) SetSystemConfigEntry(ctx context.Context, key, field string, value json.RawMessage) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO system_configs (config_key, config, updated_at)
+		VALUES ($1, jsonb_build_object($2::text, $3::jsonb), now())
+		ON CONFLICT (config_key) DO UPDATE
+		SET config = jsonb_set(system_configs.config, ARRAY[$2::text], $3::jsonb), updated_at = now()
+	`, key, field, value)
+	return err
+}
+
+// DeleteSystemConfigEntry removes one field of the JSON object stored
+// under key.
+func (s *Store) DeleteSystemConfigEntry(ctx context.Context, key, field string) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE system_configs SET config = config - $2::text, updated_at = now()
+		WHERE config_key = $1
+	`, key, field)
+	return err
+}
+
+// ---- Sync Runs ----
+
+// proxySyncLockClass is the first key of the two-key advisory locks that
+// keep a proxy repository from being synced by several replicas at once.
+// The second key is a hash of the repository ID.
+const proxySyncLockClass int32 = 0x68726d74
+
+// TryLockProxySync takes the sync lock of a proxy repository. It returns
+// ok=false when another replica holds it; otherwise release must be called
+// when the sync ends.
+func (s *Store) TryLockProxySync(ctx context.Context, repoID uuid.UUID) (release func(), ok bool, err error) {
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	key := repoID.String()
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1, hashtext($2))`, proxySyncLockClass, key).Scan(&ok); err != nil || !ok {
+		conn.Release()
+		return nil, false, err
+	}
+	return func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1, hashtext($2))`, proxySyncLockClass, key)
+		conn.Release()
+	}, true, nil
+}
+
+const (
+	SyncRunRunning     = "running"
+	SyncRunCompleted   = "completed"
+	SyncRunFailed      = "failed"
+	SyncRunInterrupted = "interrupted"
+)
+
+type SyncRun struct {
+	ID           uuid.UUID
+	Trigger      string
+	Status       string
+	Repositories int
+	Skills       int
+	Versions     int
+	Cached       int
+	Failed       int
+	Skipped      int
+	ByRepository json.RawMessage
+	Error        *string
+	StartedAt    time.Time
+	FinishedAt   *time.Time
+}
+
+type SyncRunItem struct {
+	ID         int64
+	RunID      uuid.UUID
+	Repository string
+	Slug       string
+	Outcome    string
+	Versions   int
+	Cached     int
+	Failed     int
+	Error      *string
+}
+
+const syncRunColumns = `id, trigger, status, repositories, skills, versions, cached, failed, skipped,
+		by_repository, error, started_at, finished_at`
+
+func scanSyncRun(row pgx.Row) (SyncRun, error) {
+	var r SyncRun
+	err := row.Scan(
+		&r.ID, &r.Trigger, &r.Status, &r.Repositories, &r.Skills, &r.Versions, &r.Cached, &r.Failed, &r.Skipped,
+		&r.ByRepository, &r.Error, &r.StartedAt, &r.FinishedAt,
+	)
+	return r, err
+}
+
+func (s *Store) CreateSyncRun(ctx context.Context, trigger string) (SyncRun, error) {
+	return scanSyncRun(s.db.QueryRow(ctx, `
+		INSERT INTO sync_runs (trigger, status)
+		VALUES ($1, 'running')
+		RETURNING `+syncRunColumns,
+		trigger,
+	))
+}
+
+// FinishSyncRun records the final counters of a run together with its
+// per-skill items in a single transaction.
+func (s *Store) FinishSyncRun(ctx context.Context, run SyncRun, items []SyncRunItem) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `
+		UPDATE sync_runs
+		SET status = $2, repositories = $3, skills = $4, versions = $5, cached = $6,
+			failed = $7, skipped = $8, by_repository = $9, error = $10, finished_at = now()
+		WHERE id = $1
+	`, run.ID, run.Status, run.Repositories, run.Skills, run.Versions, run.Cached,
+		run.Failed, run.Skipped, run.ByRepository, run.Error)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if len(items) > 0 {
+		rows := make([][]any, 0, len(items))
+		for _, item := range items {
+			rows = append(rows, []any{
+				run.ID, item.Repository, item.Slug, item.Outcome, item.Versions, item.Cached, item.Failed, item.Error,
+			})
+		}
+		if _, err := tx.CopyFrom(
+			ctx,
+			pgx.Identifier{"sync_run_items"},
+			[]string{"run_id", "repository", "slug", "outcome", "versions", "cached", "failed", "error"},
+			pgx.CopyFromRows(rows),
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *Store) GetSyncRun(ctx context.Context, id uuid.UUID) (SyncRun, error) {
+	return scanSyncRun(s.db.QueryRow(ctx, `SELECT `+syncRunColumns+` FROM sync_runs WHERE id = $1`, id))
+}
+
+func (s *Store) ListSyncRuns(ctx context.Context, limit int, offset int) ([]SyncRun, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+syncRunColumns+`
+		FROM sync_runs
+		ORDER BY started_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []SyncRun
+	for rows.Next() {
+		r, err := scanSyncRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// ListSyncRunItems returns the items of a run, optionally filtered by
+// outcome when outcome is non-empty.
+func (s *Store) ListSyncRunItems(ctx context.Context, runID uuid.UUID, outcome string, limit int, offset int) ([]SyncRunItem, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, run_id, repository, slug, outcome, versions, cached, failed, error
+		FROM sync_run_items
+		WHERE run_id = $1 AND ($2::text = '' OR outcome = $2)
+		ORDER BY id
+		LIMIT $3 OFFSET $4
+	`, runID, outcome, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []SyncRunItem
+	for rows.Next() {
+		var item SyncRunItem
+		if err := rows.Scan(
+			&item.ID, &item.RunID, &item.Repository, &item.Slug, &item.Outcome,
+			&item.Versions, &item.Cached, &item.Failed, &item.Error,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// TouchSyncRun refreshes the heartbeat of a running sync.
+func (s *Store) TouchSyncRun(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE sync_runs SET heartbeat_at = now() WHERE id = $1 AND status = 'running'
+	`, id)
+	return err
+}
+
+// MarkInterruptedSyncRuns flags runs left in the running state whose
+// heartbeat is older than staleBefore, e.g. after a server restart, as
+// interrupted. Runs of live replicas keep their heartbeat fresh.
+func (s *Store) MarkInterruptedSyncRuns(ctx context.Context, staleBefore time.Time) (int64, error) {
+	ct, err := s.db.Exec(ctx, `
+		UPDATE sync_runs SET status = 'interrupted', finished_at = now()
+		WHERE status = 'running' AND heartbeat_at < $1
+	`, staleBefore)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// PruneSyncRuns deletes all but the newest keep runs.
+func (s *Store) PruneSyncRuns(ctx context.Context, keep int) error {
+	_, err := s.db.Exec(ctx, `
+		DELETE FROM sync_runs
+		WHERE id IN (
+			SELECT id FROM sync_runs
+			ORDER BY started_at DESC, id DESC
+			OFFSET $1
+		)
+	`, keep)
+	return err
+}
+
+// ---- Skill Stars ----
+
+// StarredSkill is a skill starred by a subject.
+type StarredSkill struct {
+	SkillListItem
+	RepoID    uuid.UUID
+	StarredAt time.Time
+}
+
+// SetSkillStar stars (or unstars) a package for subject and keeps
+// packages.stars in step within one transaction. It returns the resulting
+// local star count.
+func (s *Store) SetSkillStar(ctx context.Context, packageID uuid.UUID, subject string, starred bool) (int64, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var ct pgconn.CommandTag
+	if starred {
+		ct, err = tx.Exec(ctx, `
+			INSERT INTO skill_stars (package_id, subject)
+			VALUES ($1, $2)
+			ON CONFLICT (package_id, subject) DO NOTHING
+		`, packageID, subject)
+	} else {
+		ct, err = tx.Exec(ctx, `
+			DELETE FROM skill_stars WHERE package_id = $1 AND subject = $2
+		`, packageID, subject)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	delta := 0
+	if ct.RowsAffected() > 0 {
+		delta = 1
+		if !starred {
+			delta = -1
+		}
+	}
+	var stars int64
+	if err := tx.QueryRow(ctx, `
+		UPDATE packages SET stars = GREATEST(stars + $2, 0)
+		WHERE id = $1
+		RETURNING stars
+	`, packageID, delta).Scan(&stars); err != nil {
+		return 0, err
+	}
+	return stars, tx.Commit(ctx)
+}
+
+func (s *Store) IsSkillStarred(ctx context.Context, packageID uuid.UUID, subject string) (bool, error) {
+	var starred bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM skill_stars WHERE package_id = $1 AND subject = $2)
+	`, packageID, subject).Scan(&starred)
+	return starred, err
+}
+
+// ListStarredSkills returns the skills in repoIDs that subject has starred,
+// most recently starred first.
+func (s *Store) ListStarredSkills(ctx context.Context, subject string, repoIDs []uuid.UUID, limit int, offset int) ([]StarredSkill, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT
+			p.id,
+			p.name,
+			p.display_name,
+			p.summary,
+			p.tags,
+			p.downloads,
+			p.stars,
+			p.upstream_stars,
+			p.trending_score,
+			p.installs_current,
+			p.installs_all_time,
+			p.deprecated,
+			p.created_at,
+			p.updated_at,
+			p.repo_id,
+			ss.created_at,
+			lv.version,
+			lv.created_at,
+			lv.changelog,
+			lv.deprecated
+		FROM skill_stars ss
+		JOIN packages p ON p.id = ss.package_id
+		LEFT JOIN LATERAL (
+			SELECT v.version, v.created_at, v.changelog, v.deprecated
+			FROM versions v
+			WHERE v.package_id = p.id
+			  AND v.yanked_at IS NULL
+			ORDER BY `+taggedLatestOrder+`
+			LIMIT 1
+		) lv ON true
+		WHERE ss.subject = $1
+		  AND p.repo_id = ANY($2)
+		  AND p.deleted_at IS NULL
+		ORDER BY ss.created_at DESC, p.name ASC
+		LIMIT $3 OFFSET $4
+	`, subject, repoIDs, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []StarredSkill
+	for rows.Next() {
+		var item StarredSkill
+		var latestVersion *string
+		var latestCreated *time.Time
+		var latestChangelog *string
+		var latestDeprecation *string
+		if err := rows.Scan(
+			&item.ID,
+			&item.Slug,
+			&item.DisplayName,
+			&item.Summary,
+			&item.Tags,
+			&item.Downloads,
+			&item.Stars,
+			&item.UpstreamStars,
+			&item.TrendingScore,
+			&item.InstallsCurrent,
+			&item.InstallsAllTime,
+			&item.Deprecation,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+			&item.RepoID,
+			&item.StarredAt,
+			&latestVersion,
+			&latestCreated,
+			&latestChangelog,
+			&latestDeprecation,
+		); err != nil {
+			return nil, err
+		}
+		if latestVersion != nil && latestCreated != nil {
+			changelog := ""
+			if latestChangelog != nil {
+				changelog = *latestChangelog
+			}
+			item.LatestVersion = &SkillVersionSummary{
+				Version:     *latestVersion,
+				CreatedAt:   *latestCreated,
+				Changelog:   changelog,
+				Deprecation: latestDeprecation,
+			}
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// SetUpstreamStars records the star count a proxy's upstream reports.
+func (s *Store) SetUpstreamStars(ctx context.Context, repoID uuid.UUID, slug string, stars int64) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE packages SET upstream_stars = $3
+		WHERE repo_id = $1 AND name = $2
+	`, repoID, slug, stars)
+	return err
+}
+
+// ---- Skill Tags ----
+
+// SetSkillTag points tag at version. It reports pgx.ErrNoRows when the
+// skill does not exist.
+func (s *Store) SetSkillTag(ctx context.Context, repoID uuid.UUID, slug, tag, version string) error {
+	ct, err := s.db.Exec(ctx, `
+		UPDATE packages
+		SET tags = jsonb_set(COALESCE(tags, '{}'::jsonb), ARRAY[$3::text], to_jsonb($4::text)),
+			updated_at = now()
+		WHERE repo_id = $1
+		  AND name = $2
+		  AND deleted_at IS NULL
+	`, repoID, slug, tag, version)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// PinLatestTag points the latest tag at version and pins it there, so
+// publishes no longer move it. It reports pgx.ErrNoRows when the skill does
+// not exist.
+func (s *Store) PinLatestTag(ctx context.Context, repoID uuid.UUID, slug, version string) error {
+	ct, err := s.db.Exec(ctx, `
+		UPDATE packages
+		SET tags = jsonb_set(COALESCE(tags, '{}'::jsonb), '{latest}', to_jsonb($3::text)),
+			latest_pinned = TRUE,
+			updated_at = now()
+		WHERE repo_id = $1
+		  AND name = $2
+		  AND deleted_at IS NULL
+	`, repoID, slug, version)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// UnpinLatestTagTx releases a pinned latest tag and returns the package. It
+// reports pgx.ErrNoRows when the skill does not exist.
+func (s *Store) UnpinLatestTagTx(ctx context.Context, tx pgx.Tx, repoID uuid.UUID, slug string) (uuid.UUID, error) {
+	var packageID uuid.UUID
+	err := tx.QueryRow(ctx, `
+		UPDATE packages
+		SET latest_pinned = FALSE
+		WHERE repo_id = $1
+		  AND name = $2
+		  AND deleted_at IS NULL
+		RETURNING id
+	`, repoID, slug).Scan(&packageID)
+	return packageID, err
+}
+
+// DeleteSkillTag removes tag. It reports pgx.ErrNoRows when the skill or
+// the tag does not exist.
+func (s *Store) DeleteSkillTag(ctx context.Context, repoID uuid.UUID, slug, tag string) error {
+	ct, err := s.db.Exec(ctx, `
+		UPDATE packages
+		SET tags = tags - $3::text,
+			updated_at = now()
+		WHERE repo_id = $1
+		  AND name = $2
+		  AND deleted_at IS NULL
+		  AND tags ? $3::text
+	`, repoID, slug, tag)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ---- Yank & Deprecation ----
+
+// SetVersionYankedTx yanks or restores a version and returns its package.
+// Re-yanking keeps the original yank time but updates the reason. It reports
+// pgx.ErrNoRows when the version does not exist.
+func (s *Store) SetVersionYankedTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	repoID uuid.UUID,
+	slug string,
+	version string,
+	yanked bool,
+	reason *string,
+) (uuid.UUID, error) {
+	var packageID uuid.UUID
+	err := tx.QueryRow(ctx, `
+		UPDATE versions v
+		SET yanked_at = CASE WHEN $4 THEN COALESCE(v.yanked_at, now()) ELSE NULL END,
+			yank_reason = CASE WHEN $4 THEN $5 ELSE NULL END
+		FROM packages p
+		WHERE p.id = v.package_id
+		  AND p.repo_id = $1
+		  AND p.name = $2
+		  AND p.deleted_at IS NULL
+		  AND v.version = $3
+		RETURNING p.id
+	`, repoID, slug, version, yanked, reason).Scan(&packageID)
+	return packageID, err
+}
+
+// RepointLatestTagTx points the latest tag at the highest version that is
+// not yanked, or removes it when every version is yanked. A pinned tag is
+// left alone. With onlyFrom set, the tag is only moved if it currently
+// points at onlyFrom or is missing, pinned or not, and moving it releases
+// the pin.
+func (s *Store) RepointLatestTagTx(ctx context.Context, tx pgx.Tx, packageID uuid.UUID, onlyFrom *string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE packages p
+		SET tags = CASE
+				WHEN lv.version IS NULL THEN COALESCE(p.tags, '{}'::jsonb) - 'latest'
+				ELSE jsonb_set(COALESCE(p.tags, '{}'::jsonb), '{latest}', to_jsonb(lv.version))
+			END,
+			latest_pinned = FALSE,
+			updated_at = now()
+		FROM (
+			SELECT (
+				SELECT v.version
+				FROM versions v
+				WHERE v.package_id = $1
+				  AND v.yanked_at IS NULL
+				ORDER BY `+latestVersionOrder+`
+				LIMIT 1
+			) AS version
+		) lv
+		WHERE p.id = $1
+		  AND CASE
+				WHEN $2::text IS NULL THEN NOT p.latest_pinned
+				ELSE p.tags ->> 'latest' IS NULL OR p.tags ->> 'latest' = $2::text
+			END
+	`, packageID, onlyFrom)
+	return err
+}
+
+// RepointLatestTagOnRestoreTx updates the latest tag after restored was
+// unyanked. The tag moves to restored only when restored is now the highest
+// version and the tag is not pinned. A tag that is missing or points at a
+// yanked or missing version is recomputed either way.
+func (s *Store) RepointLatestTagOnRestoreTx(ctx context.Context, tx pgx.Tx, packageID uuid.UUID, restored string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE packages p
+		SET tags = jsonb_set(COALESCE(p.tags, '{}'::jsonb), '{latest}', to_jsonb(lv.version)),
+			latest_pinned = FALSE,
+			updated_at = now()
+		FROM (
+			SELECT (
+				SELECT v.version
+				FROM versions v
+				WHERE v.package_id = $1
+				  AND v.yanked_at IS NULL
+				ORDER BY `+latestVersionOrder+`
+				LIMIT 1
+			) AS version
+		) lv
+		WHERE p.id = $1
+		  AND lv.version IS NOT NULL
+		  AND (
+			(NOT p.latest_pinned AND lv.version = $2)
+			OR NOT EXISTS (
+				SELECT 1
+				FROM versions cur
+				WHERE cur.package_id = p.id
+				  AND cur.version = p.tags ->> 'latest'
+				  AND cur.yanked_at IS NULL
+			)
+		  )
+	`, packageID, restored)
+	return err
+}
+
+// SetSkillDeprecation sets or, with a nil message, clears the deprecation
+// message of a skill.
+func (s *Store) SetSkillDeprecation(ctx context.Context, repoID uuid.UUID, slug string, message *string) error {
+	ct, err := s.db.Exec(ctx, `
+		UPDATE packages
+		SET deprecated = $3, updated_at = now()
+		WHERE repo_id = $1
+		  AND name = $2
+		  AND deleted_at IS NULL
+	`, repoID, slug, message)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// SetVersionDeprecation sets or, with a nil message, clears the deprecation
+// message of one version.
+func (s *Store) SetVersionDeprecation(ctx context.Context, repoID uuid.UUID, slug, version string, message *string) error {
+	ct, err := s.db.Exec(ctx, `
+		UPDATE versions v
+		SET deprecated = $4
+		FROM packages p
+		WHERE p.id = v.package_id
+		  AND p.repo_id = $1
+		  AND p.name = $2
+		  AND p.deleted_at IS NULL
+		  AND v.version = $3
+	`, repoID, slug, version, message)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ---- Purge & Blob GC ----
+
+// PurgeSkill hard-deletes a skill with its versions, assets, stars and
+// counters, plus its proxy cache entries. Blobs are left for GC. It
+// reports pgx.ErrNoRows when the skill does not exist.
+func (s *Store) PurgeSkill(ctx context.Context, repoID uuid.UUID, slug string) error {
+	var purged int
+	err := s.db.QueryRow(ctx, `
+		WITH pkg AS (
+			DELETE FROM packages
+			WHERE repo_id = $1
+			  AND name = $2
+			RETURNING id
+		), cache AS (
+			DELETE FROM proxy_cache
+			WHERE repo_id = $1
+			  AND package_name = $2
+		)
+		SELECT count(*) FROM pkg
+	`, repoID, slug).Scan(&purged)
+	if err != nil {
+		return err
+	}
+	if purged == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// PurgeVersionTx hard-deletes one version with its assets and proxy cache
+// entry, and returns its package. It reports pgx.ErrNoRows when the
+// version does not exist.
+func (s *Store) PurgeVersionTx(ctx context.Context, tx pgx.Tx, repoID uuid.UUID, slug, version string) (uuid.UUID, error) {
+	var packageID uuid.UUID
+	err := tx.QueryRow(ctx, `
+		DELETE FROM versions v
+		USING packages p
+		WHERE p.id = v.package_id
+		  AND p.repo_id = $1
+		  AND p.name = $2
+		  AND v.version = $3
+		RETURNING p.id
+	`, repoID, slug, version).Scan(&packageID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		DELETE FROM proxy_cache
+		WHERE repo_id = $1
+		  AND package_name = $2
+		  AND version = $3
+	`, repoID, slug, version)
+	return packageID, err
+}
+
+// ListReferencedBlobs returns every blob key an asset points at.
+func (s *Store) ListReferencedBlobs(ctx context.Context) (map[string]struct{}, error) {
+	rows, err := s.db.Query(ctx, `SELECT DISTINCT blob_path FROM assets`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := map[string]struct{}{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys[key] = struct{}{}
+	}
+	return keys, rows.Err()
+}
+
+// IsBlobReferencedTx reports whether any asset points at key.
+func (s *Store) IsBlobReferencedTx(ctx context.Context, tx pgx.Tx, key string) (bool, error) {
+	var referenced bool
+	err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM assets WHERE blob_path = $1)`, key).Scan(&referenced)
+	return referenced, err
+}
+
+// Advisory lock keys for blob garbage collection, shared by all replicas.
+const (
+	// blobGCRunLockKey keeps GC runs from overlapping.
+	blobGCRunLockKey int64 = 0x6865726d69740001
+	// blobWriteLockKey is held shared by a writer from checking its blob
+	// until its asset row is committed, and exclusively by GC from its last
+	// check of a blob until the blob is deleted.
+	blobWriteLockKey int64 = 0x6865726d69740002
+)
+
+// TryLockBlobGC takes the lock that serializes blob GC runs across
+// replicas. It returns ok=false when another run holds it; otherwise
+// release must be called when the run ends.
+func (s *Store) TryLockBlobGC(ctx context.Context) (release func(), ok bool, err error) {
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, blobGCRunLockKey).Scan(&ok); err != nil || !ok {
+		conn.Release()
+		return nil, false, err
+	}
+	return func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, blobGCRunLockKey)
+		conn.Release()
+	}, true, nil
+}
+
+// LockBlobWritesTx keeps writers from committing asset rows until tx ends.
+func (s *Store) LockBlobWritesTx(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, blobWriteLockKey)
+	return err
+}
+
+// LockBlobWriteSharedTx keeps blob GC from deleting blobs until tx ends.
+func (s *Store) LockBlobWriteSharedTx(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock_shared($1)`, blobWriteLockKey)
+	return err
+}
+
+// ---- Blob Scrub ----
+
+// ScrubAsset is a stored archive together with what was recorded about it.
+type ScrubAsset struct {
+	ID          uuid.UUID
+	Repo        Repository
+	PackageName string
+	Version     string
+	BlobPath    string
+	SizeBytes   int64
+	Digest      string
+}
+
+// ListScrubAssets returns up to limit assets ordered by id, starting after
+// the given id (uuid.Nil for the first page). A non-nil repoID restricts
+// the listing to one repository.
+func (s *Store) ListScrubAssets(ctx context.Context, repoID *uuid.UUID, after uuid.UUID, limit int) ([]ScrubAsset, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT a.id, r.id, r.name, r.type::text, r.upstream_url, r.enabled,
+		       p.name, v.version, a.blob_path, a.size_bytes, a.digest
+		FROM assets a
+		JOIN versions v ON v.id = a.version_id
+		JOIN packages p ON p.id = v.package_id
+		JOIN repositories r ON r.id = p.repo_id
+		WHERE a.id > $1
+		  AND ($2::uuid IS NULL OR r.id = $2)
+		ORDER BY a.id
+		LIMIT $3
+	`, after, repoID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assets []ScrubAsset
+	for rows.Next() {
+		var a ScrubAsset
+		if err := rows.Scan(
+			&a.ID, &a.Repo.ID, &a.Repo.Name, &a.Repo.Type, &a.Repo.UpstreamURL, &a.Repo.Enabled,
+			&a.PackageName, &a.Version, &a.BlobPath, &a.SizeBytes, &a.Digest,
+		); err != nil {
+			return nil, err
+		}
+		assets = append(assets, a)
+	}
+	return assets, rows.Err()
+}
+
+// ---- Trending ----
+
+// RecomputeTrendingScores sets every package's trending score to the sum of
+// its daily downloads within windowDays, each weighted by
+// 0.5^(age/halfLifeDays), and drops buckets that fell out of the window.
+// It returns the number of packages whose score was rewritten.
+func (s *Store) RecomputeTrendingScores(ctx context.Context, halfLifeDays float64, windowDays int) (int64, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `
+		WITH today AS (
+			SELECT (now() AT TIME ZONE 'UTC')::date AS day
+		),
+		scores AS (
+			SELECT d.package_id,
+			       SUM(d.downloads * power(0.5, (today.day - d.day)::float8 / $1)) AS score
+			FROM skill_download_daily d, today
+			WHERE d.day > today.day - $2::int
+			GROUP BY d.package_id
+		)
+		UPDATE packages p
+		SET trending_score = COALESCE(scores.score, 0)
+		FROM packages p2
+		LEFT JOIN scores ON scores.package_id = p2.id
+		WHERE p.id = p2.id
+		  AND (p.trending_score <> 0 OR scores.score IS NOT NULL)
+	`, halfLifeDays, windowDays)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM skill_download_daily
+		WHERE day <= (now() AT TIME ZONE 'UTC')::date - $1::int
+	`, windowDays); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// ---- Skill Installs ----
+
+type InstallCounts struct {
+	Current int64
+	AllTime int64
+}
+
+// RecordSkillInstall registers an install of a package by clientID. The
+// first report from a client counts towards both install counters; a report
+// after an uninstall only counts towards the current installs, and a repeat
+// report while installed changes neither.
+func (s *Store) RecordSkillInstall(ctx context.Context, packageID uuid.UUID, clientID string, version *string) (InstallCounts, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return InstallCounts{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var currentDelta, allTimeDelta int64
+	ct, err := tx.Exec(ctx, `
+		INSERT INTO skill_installs (package_id, client_id, version)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (package_id, client_id) DO NOTHING
+	`, packageID, clientID, version)
+	if err != nil {
+		return InstallCounts{}, err
+	}
+	if ct.RowsAffected() == 1 {
+		currentDelta, allTimeDelta = 1, 1
+	} else {
+		var wasUninstalled bool
+		if err := tx.QueryRow(ctx, `
+			SELECT uninstalled_at IS NOT NULL
+			FROM skill_installs
+			WHERE package_id = $1 AND client_id = $2
+			FOR UPDATE
+		`, packageID, clientID).Scan(&wasUninstalled); err != nil {
+			return InstallCounts{}, err
+		}
+		if wasUninstalled {
+			currentDelta = 1
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE skill_installs
+			SET version = COALESCE($3, version), last_seen_at = now(), uninstalled_at = NULL
+			WHERE package_id = $1 AND client_id = $2
+		`, packageID, clientID, version); err != nil {
+			return InstallCounts{}, err
+		}
+	}
+
+	counts, err := adjustInstallCountsTx(ctx, tx, packageID, currentDelta, allTimeDelta)
+	if err != nil {
+		return InstallCounts{}, err
+	}
+	return counts, tx.Commit(ctx)
+}
+
+// RecordSkillUninstall marks clientID's install of a package as removed.
+// Uninstalling a package the client never installed, or already
+// uninstalled, leaves the counters untouched.
+func (s *Store) RecordSkillUninstall(ctx context.Context, packageID uuid.UUID, clientID string) (InstallCounts, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return InstallCounts{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `
+		UPDATE skill_installs
+		SET uninstalled_at = now(), last_seen_at = now()
+		WHERE package_id = $1 AND client_id = $2 AND uninstalled_at IS NULL
+	`, packageID, clientID)
+	if err != nil {
+		return InstallCounts{}, err
+	}
+	var currentDelta int64
+	if ct.RowsAffected() == 1 {
+		currentDelta = -1
+	}
+
+	counts, err := adjustInstallCountsTx(ctx, tx, packageID, currentDelta, 0)
+	if err != nil {
+		return InstallCounts{}, err
+	}
+	return counts, tx.Commit(ctx)
+}
+
+func adjustInstallCountsTx(ctx context.Context, tx pgx.Tx, packageID uuid.UUID, currentDelta, allTimeDelta int64) (InstallCounts, error) {
+	var counts InstallCounts
+	err := tx.QueryRow(ctx, `
+		UPDATE packages
+		SET
+			installs_current = GREATEST(installs_current + $2, 0),
+			installs_all_time = installs_all_time + $3
+		WHERE id = $1
+		RETURNING installs_current, installs_all_time
+	`, packageID, currentDelta, allTimeDelta).Scan(&counts.Current, &counts.AllTime)
+	return counts, err
+}
@@ -0,0 +1,326 @@
+// Package textdiff computes line diffs (Myers' O(ND) algorithm) and renders
+// them in unified diff format.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OpKind is the kind of a diff operation.
+type OpKind int
+
+const (
+	Equal OpKind = iota
+	Delete
+	Insert
+)
+
+// Op is one line of a diff. A and B are line indexes into the old and new
+// text. For an Insert, A is the old line the insertion precedes; for a
+// Delete, B is the new line the deletion precedes.
+type Op struct {
+	Kind OpKind
+	A, B int
+	Line string
+}
+
+// Stats counts inserted and deleted lines.
+type Stats struct {
+	Additions int
+	Deletions int
+}
+
+// maxEditDistance bounds the Myers search, whose time grows with the
+// number of edits. Texts that differ by more lines are diffed as a full
+// replacement.
+const maxEditDistance = 2000
+
+// Lines returns the operations that turn a into b.
+func Lines(a, b []string) []Op {
+	// Common prefix and suffix need no search.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]Op, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, Op{Kind: Equal, A: i, B: i, Line: a[i]})
+	}
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	mid, ok := myers(midA, midB, maxEditDistance)
+	if !ok {
+		mid = replaceAll(midA, midB)
+	}
+	for _, op := range mid {
+		op.A += prefix
+		op.B += prefix
+		ops = append(ops, op)
+	}
+	for i := 0; i < suffix; i++ {
+		ai, bi := len(a)-suffix+i, len(b)-suffix+i
+		ops = append(ops, Op{Kind: Equal, A: ai, B: bi, Line: a[ai]})
+	}
+	return ops
+}
+
+func replaceAll(a, b []string) []Op {
+	ops := make([]Op, 0, len(a)+len(b))
+	for i, line := range a {
+		ops = append(ops, Op{Kind: Delete, A: i, B: 0, Line: line})
+	}
+	for i, line := range b {
+		ops = append(ops, Op{Kind: Insert, A: len(a), B: i, Line: line})
+	}
+	return ops
+}
+
+// myers finds a shortest edit script with at most maxD edits. It reports
+// false when the texts differ by more. It uses the linear space variant:
+// the middle snake of an optimal path splits the texts, and both halves
+// are diffed recursively, so memory stays O(len(a)+len(b)).
+func myers(a, b []string, maxD int) ([]Op, bool) {
+	bound := min((len(a)+len(b)+1)/2, (maxD+1)/2)
+	d := &differ{
+		a:      a,
+		b:      b,
+		maxD:   maxD,
+		offset: bound + 1,
+		fwd:    make([]int, 2*bound+3),
+		bwd:    make([]int, 2*bound+3),
+	}
+	if !d.compare(0, len(a), 0, len(b)) {
+		return nil, false
+	}
+	return d.ops, true
+}
+
+// differ holds the state of one myers call. fwd and bwd are the furthest
+// reaching x per diagonal of the forward and backward searches, reused by
+// every recursive step.
+type differ struct {
+	a, b     []string
+	maxD     int
+	offset   int
+	fwd, bwd []int
+	ops      []Op
+}
+
+// compare appends the ops turning a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) bool {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.ops = append(d.ops, Op{Kind: Equal, A: aLo, B: bLo, Line: d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-1-suffix] == d.b[bHi-1-suffix] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	switch {
+	case aLo == aHi:
+		for y := bLo; y < bHi; y++ {
+			d.ops = append(d.ops, Op{Kind: Insert, A: aLo, B: y, Line: d.b[y]})
+		}
+	case bLo == bHi:
+		for x := aLo; x < aHi; x++ {
+			d.ops = append(d.ops, Op{Kind: Delete, A: x, B: bLo, Line: d.a[x]})
+		}
+	default:
+		x, y, u, v, ok := d.middleSnake(aLo, aHi, bLo, bHi)
+		if !ok || !d.compare(aLo, x, bLo, y) {
+			return false
+		}
+		for ; x < u; x, y = x+1, y+1 {
+			d.ops = append(d.ops, Op{Kind: Equal, A: x, B: y, Line: d.a[x]})
+		}
+		if !d.compare(u, aHi, v, bHi) {
+			return false
+		}
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.ops = append(d.ops, Op{Kind: Equal, A: aHi + i, B: bHi + i, Line: d.a[aHi+i]})
+	}
+	return true
+}
+
+// middleSnake runs the forward and backward searches over a[aLo:aHi] and
+// b[bLo:bHi] until they meet, and returns the snake (x, y)-(u, v) where
+// they do. It reports false when the texts differ by more than maxD.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int, ok bool) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	bound := min((n+m+1)/2, (d.maxD+1)/2)
+	off := d.offset
+	d.fwd[off+1] = 0
+	d.bwd[off+1] = 0
+
+	for D := 0; D <= bound; D++ {
+		for k := -D; k <= D; k += 2 {
+			var px int
+			if k == -D || (k != D && d.fwd[off+k-1] < d.fwd[off+k+1]) {
+				px = d.fwd[off+k+1]
+			} else {
+				px = d.fwd[off+k-1] + 1
+			}
+			py := px - k
+			sx, sy := px, py
+			for px < n && py < m && d.a[aLo+px] == d.b[bLo+py] {
+				px++
+				py++
+			}
+			d.fwd[off+k] = px
+			// The backward search has run D-1 rounds; its diagonal
+			// delta-k is in reach when D-1 >= |delta-k|.
+			if odd && k >= delta-(D-1) && k <= delta+(D-1) && px+d.bwd[off+delta-k] >= n {
+				if 2*D-1 > d.maxD {
+					return 0, 0, 0, 0, false
+				}
+				return aLo + sx, bLo + sy, aLo + px, bLo + py, true
+			}
+		}
+		for k := -D; k <= D; k += 2 {
+			var px int
+			if k == -D || (k != D && d.bwd[off+k-1] < d.bwd[off+k+1]) {
+				px = d.bwd[off+k+1]
+			} else {
+				px = d.bwd[off+k-1] + 1
+			}
+			py := px - k
+			sx, sy := px, py
+			for px < n && py < m && d.a[aHi-1-px] == d.b[bHi-1-py] {
+				px++
+				py++
+			}
+			d.bwd[off+k] = px
+			if !odd && k >= delta-D && k <= delta+D && px+d.fwd[off+delta-k] >= n {
+				if 2*D > d.maxD {
+					return 0, 0, 0, 0, false
+				}
+				return aHi - px, bHi - py, aHi - sx, bHi - sy, true
+			}
+		}
+	}
+	return 0, 0, 0, 0, false
+}
+
+// SplitLines splits text into lines without their terminators. A trailing
+// newline does not produce an empty last line.
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Unified renders the diff of oldText and newText as a unified diff with
+// the given number of context lines. It returns "" when the texts are equal.
+func Unified(oldName, newName, oldText, newText string, context int) (string, Stats) {
+	ops := Lines(SplitLines(oldText), SplitLines(newText))
+
+	var stats Stats
+	for _, op := range ops {
+		switch op.Kind {
+		case Insert:
+			stats.Additions++
+		case Delete:
+			stats.Deletions++
+		}
+	}
+	if stats.Additions == 0 && stats.Deletions == 0 {
+		return "", stats
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks(ops, context) {
+		writeHunk(&b, ops[h[0]:h[1]])
+	}
+	return b.String(), stats
+}
+
+// hunks groups changes that are within 2*context lines of each other and
+// returns the [start, end) op ranges of each group including context.
+func hunks(ops []Op, context int) [][2]int {
+	var out [][2]int
+	i := 0
+	for i < len(ops) {
+		if ops[i].Kind == Equal {
+			i++
+			continue
+		}
+		start := max(i-context, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == Equal {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+			end = run
+		}
+		out = append(out, [2]int{start, end})
+		i = end
+	}
+	return out
+}
+
+func writeHunk(b *strings.Builder, ops []Op) {
+	oldCount, newCount := 0, 0
+	for _, op := range ops {
+		if op.Kind != Insert {
+			oldCount++
+		}
+		if op.Kind != Delete {
+			newCount++
+		}
+	}
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(ops[0].A, oldCount), hunkRange(ops[0].B, newCount))
+	for _, op := range ops {
+		switch op.Kind {
+		case Equal:
+			b.WriteByte(' ')
+		case Delete:
+			b.WriteByte('-')
+		case Insert:
+			b.WriteByte('+')
+		}
+		b.WriteString(op.Line)
+		b.WriteByte('\n')
+	}
+}
+
+// hunkRange formats a 0-based start and a count as diff(1) does: 1-based,
+// with an empty range named by the line before it.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
@@ -0,0 +1,164 @@
+package textdiff
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// apply rebuilds the new text from ops, checking the old side on the way.
+func apply(t *testing.T, a []string, ops []Op) []string {
+	t.Helper()
+	var out []string
+	ai := 0
+	for _, op := range ops {
+		switch op.Kind {
+		case Equal, Delete:
+			if ai >= len(a) || a[ai] != op.Line || op.A != ai {
+				t.Fatalf("op %+v does not match old line %d", op, ai)
+			}
+			ai++
+			if op.Kind == Equal {
+				out = append(out, op.Line)
+			}
+		case Insert:
+			out = append(out, op.Line)
+		}
+	}
+	if ai != len(a) {
+		t.Fatalf("ops consumed %d of %d old lines", ai, len(a))
+	}
+	return out
+}
+
+func TestLines(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		a, b      string
+		wantEdits int
+	}{
+		{name: "equal", a: "a b c", b: "a b c", wantEdits: 0},
+		{name: "empty to text", a: "", b: "a b", wantEdits: 2},
+		{name: "text to empty", a: "a b", b: "", wantEdits: 2},
+		{name: "middle change", a: "a b c d", b: "a x c d", wantEdits: 2},
+		{name: "insert and delete", a: "a b c a b b a", b: "c b a b a c", wantEdits: 5},
+		{name: "append", a: "a b", b: "a b c", wantEdits: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+			ops := Lines(a, b)
+			if got := apply(t, a, ops); strings.Join(got, " ") != strings.Join(b, " ") {
+				t.Fatalf("applied ops = %v, want %v", got, b)
+			}
+			edits := 0
+			for _, op := range ops {
+				if op.Kind != Equal {
+					edits++
+				}
+			}
+			if edits != tt.wantEdits {
+				t.Fatalf("edits = %d, want %d", edits, tt.wantEdits)
+			}
+		})
+	}
+}
+
+// TestLines_Minimal checks the edit scripts against an LCS computed by
+// dynamic programming on random texts over a small alphabet.
+func TestLines_Minimal(t *testing.T) {
+	t.Parallel()
+	rng := rand.New(rand.NewPCG(1, 2))
+	randomText := func() []string {
+		lines := make([]string, rng.IntN(40))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.IntN(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := randomText(), randomText()
+		ops := Lines(a, b)
+		if got := apply(t, a, ops); strings.Join(got, "") != strings.Join(b, "") {
+			t.Fatalf("Lines(%v, %v) applied = %v", a, b, got)
+		}
+		edits := 0
+		for _, op := range ops {
+			if op.Kind != Equal {
+				edits++
+			}
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("Lines(%v, %v) edits = %d, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestLines_FallsBackBeyondMaxEditDistance(t *testing.T) {
+	t.Parallel()
+	var a, b []string
+	for i := 0; i < maxEditDistance; i++ {
+		a = append(a, fmt.Sprintf("old %d", i))
+		b = append(b, fmt.Sprintf("new %d", i))
+	}
+	ops := Lines(a, b)
+	if got := apply(t, a, ops); len(got) != len(b) || got[0] != b[0] {
+		t.Fatalf("applied ops produced %d lines, want %d", len(got), len(b))
+	}
+}
+
+func TestUnified(t *testing.T) {
+	t.Parallel()
+	oldText := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	newText := "one\nTWO\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+
+	got, stats := Unified("a/SKILL.md", "b/SKILL.md", oldText, newText, 1)
+	want := `--- a/SKILL.md
++++ b/SKILL.md
+@@ -1,3 +1,3 @@
+ one
+-two
++TWO
+ three
+@@ -10 +10,2 @@
+ ten
++eleven
+`
+	if got != want {
+		t.Fatalf("Unified() =\n%s\nwant\n%s", got, want)
+	}
+	if stats.Additions != 2 || stats.Deletions != 1 {
+		t.Fatalf("stats = %+v, want 2 additions, 1 deletion", stats)
+	}
+}
+
+func TestUnified_EmptySides(t *testing.T) {
+	t.Parallel()
+	got, _ := Unified("a/x", "b/x", "", "hello\n", 3)
+	if want := "--- a/x\n+++ b/x\n@@ -0,0 +1 @@\n+hello\n"; got != want {
+		t.Fatalf("Unified() = %q, want %q", got, want)
+	}
+	got, _ = Unified("a/x", "b/x", "same\n", "same", 3)
+	if got != "" {
+		t.Fatalf("Unified() = %q, want empty for equal lines", got)
+	}
+}
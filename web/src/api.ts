@@ -471,7 +471,7 @@ export const authConfigApi = {
 export interface AuthProvider {
   id: string
   name: string
-  type: 'standard' | 'ldap'
+  type: 'standard' | 'ldap' | 'oidc'
 }
 
 export interface LoginResult {
@@ -480,6 +480,21 @@ export interface LoginResult {
   display_name: string
   email: string
   is_admin: boolean
+  recovery_codes?: string[]
+}
+
+// Returned by a local login when the account continues with two-factor
+// authentication instead of receiving a token.
+export interface LoginChallenge {
+  mfa_required: true
+  mfa_token: string
+  mfa_setup_required: boolean
+  expires_at: string
+}
+
+export interface TOTPSetup {
+  secret: string
+  otpauth_uri: string
 }
 
 export const authApi = {
@@ -489,12 +504,33 @@ export const authApi = {
     }),
 
   login: (username: string, password: string) =>
-    request<LoginResult>(`${API_BASE}/auth/login`, {
+    request<LoginResult | LoginChallenge>(`${API_BASE}/auth/login`, {
       method: 'POST',
       body: JSON.stringify({ username, password }),
       suppressUnauthorizedHandler: true,
     }),
 
+  twoFactorLogin: (mfaToken: string, code: string) =>
+    request<LoginResult>(`${API_BASE}/auth/login/2fa`, {
+      method: 'POST',
+      body: JSON.stringify({ mfa_token: mfaToken, code }),
+      suppressUnauthorizedHandler: true,
+    }),
+
+  twoFactorLoginSetup: (mfaToken: string) =>
+    request<TOTPSetup>(`${API_BASE}/auth/login/2fa/setup`, {
+      method: 'POST',
+      body: JSON.stringify({ mfa_token: mfaToken }),
+      suppressUnauthorizedHandler: true,
+    }),
+
+  twoFactorLoginEnable: (mfaToken: string, code: string) =>
+    request<LoginResult>(`${API_BASE}/auth/login/2fa/enable`, {
+      method: 'POST',
+      body: JSON.stringify({ mfa_token: mfaToken, code }),
+      suppressUnauthorizedHandler: true,
+    }),
+
   ldapLogin: (username: string, password: string) =>
     request<LoginResult>(`${API_BASE}/auth/ldap`, {
       method: 'POST',
@@ -512,6 +548,7 @@ export interface LocalUser {
   email: string
   is_admin: boolean
   disabled: boolean
+  totp_enabled: boolean
   created_at: string
 }
 
@@ -2,10 +2,11 @@ import { useEffect, useState } from 'react'
 import { createFileRoute } from '@tanstack/react-router'
 import { KeyRound } from 'lucide-react'
 import { authApi, getToken, setToken } from '#/api'
-import type { AuthProvider } from '#/api'
+import type { AuthProvider, LoginChallenge, TOTPSetup } from '#/api'
 
 type LoginSearch = {
   redirect?: string
+  error?: string
 }
 
 function normalizeRedirect(rawRedirect?: string): string {
@@ -19,23 +20,38 @@ export const Route = createFileRoute('/login')({
   validateSearch: (search: Record<string, unknown>): LoginSearch => ({
     redirect:
       typeof search.redirect === 'string' ? search.redirect : undefined,
+    error: typeof search.error === 'string' ? search.error : undefined,
   }),
   component: LoginPage,
 })
 
 function LoginPage() {
-  const { redirect } = Route.useSearch()
+  const { redirect, error: loginError } = Route.useSearch()
   const redirectTarget = normalizeRedirect(redirect)
   const [tab, setTab] = useState<'standard' | 'ldap'>('standard')
   const [username, setUsername] = useState('')
   const [password, setPassword] = useState('')
   const [tokenValue, setTokenValue] = useState('')
   const [loading, setLoading] = useState(false)
-  const [error, setError] = useState('')
+  const [error, setError] = useState(loginError ?? '')
   const [providers, setProviders] = useState<AuthProvider[]>([])
   const [showTokenInput, setShowTokenInput] = useState(false)
+  const [challenge, setChallenge] = useState<LoginChallenge | null>(null)
+  const [totpSetup, setTotpSetup] = useState<TOTPSetup | null>(null)
+  const [code, setCode] = useState('')
+  const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null)
+  const [pendingToken, setPendingToken] = useState('')
 
   useEffect(() => {
+    // SSO callback hands the session token over in the URL fragment.
+    const fragment = new URLSearchParams(window.location.hash.slice(1))
+    const ssoToken = fragment.get('token')
+    if (ssoToken) {
+      setToken(ssoToken)
+      window.location.replace(redirectTarget)
+      return
+    }
+
     if (getToken()) {
       window.location.replace(redirectTarget)
       return
@@ -48,6 +64,7 @@ function LoginPage() {
   }, [redirectTarget])
 
   const hasLDAP = providers.some((p) => p.type === 'ldap')
+  const oidcProvider = providers.find((p) => p.type === 'oidc')
 
   const handlePasswordLogin = async () => {
     setLoading(true)
@@ -55,8 +72,14 @@ function LoginPage() {
     try {
       const loginFn = tab === 'ldap' ? authApi.ldapLogin : authApi.login
       const result = await loginFn(username, password)
-      setToken(result.token)
-      window.location.replace(redirectTarget)
+      if ('mfa_required' in result) {
+        setChallenge(result)
+        if (result.mfa_setup_required) {
+          setTotpSetup(await authApi.twoFactorLoginSetup(result.mfa_token))
+        }
+        return
+      }
+      finishLogin(result.token)
     } catch (e: any) {
       setError(e.message || 'Invalid username or password')
     } finally {
@@ -64,6 +87,40 @@ function LoginPage() {
     }
   }
 
+  const handleCodeSubmit = async () => {
+    if (!challenge) return
+    setLoading(true)
+    setError('')
+    try {
+      if (challenge.mfa_setup_required) {
+        const result = await authApi.twoFactorLoginEnable(challenge.mfa_token, code.trim())
+        // Recovery codes are shown once; the login finishes after they
+        // have been seen.
+        setRecoveryCodes(result.recovery_codes ?? [])
+        setPendingToken(result.token)
+        return
+      }
+      const result = await authApi.twoFactorLogin(challenge.mfa_token, code.trim())
+      finishLogin(result.token)
+    } catch (e: any) {
+      setError(e.message || 'Invalid code')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  const finishLogin = (token: string) => {
+    setToken(token)
+    window.location.replace(redirectTarget)
+  }
+
+  const cancelChallenge = () => {
+    setChallenge(null)
+    setTotpSetup(null)
+    setCode('')
+    setError('')
+  }
+
   const handleTokenLogin = () => {
     const trimmed = tokenValue.trim()
     if (!trimmed) return
@@ -86,7 +143,79 @@ function LoginPage() {
             <h1 className="font-bold text-xl">Sign in to Hermit</h1>
           </div>
 
-          {!showTokenInput && (
+          {challenge && recoveryCodes && (
+            <div className="flex flex-col gap-3">
+              <p className="text-sm">
+                Two-factor authentication is on. Save these recovery codes somewhere safe. Each one
+                signs you in once if you lose your authenticator, and they are not shown again.
+              </p>
+              <pre className="bg-base-300 rounded p-3 text-sm font-mono">
+                {recoveryCodes.join('\n')}
+              </pre>
+              <button className="btn btn-primary w-full" onClick={() => finishLogin(pendingToken)}>
+                Continue
+              </button>
+            </div>
+          )}
+
+          {challenge && !recoveryCodes && (
+            <div className="flex flex-col gap-3">
+              {error && <div className="alert alert-error mb-1 text-sm py-2">{error}</div>}
+              {challenge.mfa_setup_required ? (
+                <>
+                  <p className="text-sm">
+                    Your account requires two-factor authentication. Add this key to your
+                    authenticator app, then enter the code it shows.
+                  </p>
+                  {totpSetup && (
+                    <>
+                      <code className="bg-base-300 rounded p-2 text-sm break-all">
+                        {totpSetup.secret}
+                      </code>
+                      <a className="link text-xs break-all" href={totpSetup.otpauth_uri}>
+                        {totpSetup.otpauth_uri}
+                      </a>
+                    </>
+                  )}
+                </>
+              ) : (
+                <p className="text-sm">
+                  Enter the code from your authenticator app, or one of your recovery codes.
+                </p>
+              )}
+              <form
+                className="flex flex-col gap-3"
+                onSubmit={(e) => {
+                  e.preventDefault()
+                  handleCodeSubmit()
+                }}
+              >
+                <input
+                  type="text"
+                  inputMode={challenge.mfa_setup_required ? 'numeric' : 'text'}
+                  placeholder="Authentication code"
+                  className="input input-bordered w-full font-mono"
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  autoFocus
+                  autoComplete="one-time-code"
+                />
+                <button
+                  type="submit"
+                  className="btn btn-primary w-full"
+                  disabled={loading || !code.trim()}
+                >
+                  {loading && <span className="loading loading-spinner loading-sm" />}
+                  Verify
+                </button>
+              </form>
+              <button className="link link-hover text-xs text-base-content/50" onClick={cancelChallenge}>
+                Back to sign in
+              </button>
+            </div>
+          )}
+
+          {!challenge && !showTokenInput && (
             <>
               {hasLDAP && (
                 <div role="tablist" className="tabs tabs-bordered mb-3">
@@ -150,10 +279,22 @@ function LoginPage() {
                   Sign in
                 </button>
               </form>
+
+              {oidcProvider && (
+                <>
+                  <div className="divider text-xs text-base-content/50">or</div>
+                  <a
+                    className="btn btn-outline w-full"
+                    href={`/api/v1/auth/oidc/login?redirect=${encodeURIComponent(redirectTarget)}`}
+                  >
+                    Sign in with {oidcProvider.name}
+                  </a>
+                </>
+              )}
             </>
           )}
 
-          {showTokenInput && (
+          {!challenge && showTokenInput && (
             <div className="flex flex-col gap-3">
               {error && (
                 <div className="alert alert-error mb-1 text-sm py-2">{error}</div>
@@ -180,17 +321,19 @@ function LoginPage() {
             </div>
           )}
 
-          <div className="mt-4 text-center">
-            <button
-              className="link link-hover text-xs text-base-content/50"
-              onClick={() => {
-                setShowTokenInput(!showTokenInput)
-                setError('')
-              }}
-            >
-              {showTokenInput ? 'Back to sign in' : 'Use API token instead'}
-            </button>
-          </div>
+          {!challenge && (
+            <div className="mt-4 text-center">
+              <button
+                className="link link-hover text-xs text-base-content/50"
+                onClick={() => {
+                  setShowTokenInput(!showTokenInput)
+                  setError('')
+                }}
+              >
+                {showTokenInput ? 'Back to sign in' : 'Use API token instead'}
+              </button>
+            </div>
+          )}
         </div>
       </div>
     </div>